@@ -0,0 +1,68 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ChangeFreezeSpec defines which VerticalWorkloadAutoscalers are frozen and for how long
+type ChangeFreezeSpec struct {
+	// Reason describes why changes are frozen, e.g. an incident ID
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// NamespaceSelector selects the namespaces of the frozen VWAs by namespace labels.
+	// If not set, VWAs in all namespaces are frozen.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Selector selects the frozen VWAs by their labels.
+	// If not set, all VWAs in the selected namespaces are frozen.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// ExpiresAt is the time the freeze ends automatically.
+	// If not set, the freeze stays active until the ChangeFreeze is deleted.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// ChangeFreeze is the Schema for the cluster-scoped ChangeFreezes API.
+// While a ChangeFreeze is active, the selected VWAs record recommendations but don't apply them.
+type ChangeFreeze struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ChangeFreezeSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ChangeFreezeList contains a list of ChangeFreeze
+type ChangeFreezeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ChangeFreeze `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ChangeFreeze{}, &ChangeFreezeList{})
+}
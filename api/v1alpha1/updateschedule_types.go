@@ -0,0 +1,105 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// UpdateScheduleKind is the kind of the namespaced update schedule
+	UpdateScheduleKind = "UpdateSchedule"
+	// ClusterUpdateScheduleKind is the kind of the cluster-scoped update schedule
+	ClusterUpdateScheduleKind = "ClusterUpdateSchedule"
+)
+
+// UpdateScheduleSpec defines update windows and blackouts shared by many VerticalWorkloadAutoscalers
+type UpdateScheduleSpec struct {
+	// AllowedUpdateWindows defines specific time windows during which updates to resource requests
+	// are permitted. They are merged with the allowed update windows of the referencing VWA.
+	// +optional
+	AllowedUpdateWindows []UpdateWindow `json:"allowedUpdateWindows,omitempty"`
+
+	// BlackoutWindows defines absolute time ranges during which updates to resource requests
+	// are not permitted. They are merged with the blackout windows of the referencing VWA.
+	// +optional
+	BlackoutWindows []BlackoutWindow `json:"blackoutWindows,omitempty"`
+
+	// BlackoutCalendars references iCalendar (.ics) files stored in ConfigMaps.
+	// An UpdateSchedule reads ConfigMaps from its own namespace; a ClusterUpdateSchedule
+	// must set the namespace of every calendar reference.
+	// +optional
+	BlackoutCalendars []CalendarReference `json:"blackoutCalendars,omitempty"`
+}
+
+// UpdateScheduleReference defines a reference to an UpdateSchedule or ClusterUpdateSchedule
+type UpdateScheduleReference struct {
+	// Kind of the referenced schedule: UpdateSchedule (in the VWA namespace) or ClusterUpdateSchedule
+	// +kubebuilder:validation:Enum=UpdateSchedule;ClusterUpdateSchedule
+	// +kubebuilder:default=UpdateSchedule
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the referenced schedule
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=us
+
+// UpdateSchedule is the Schema for the namespaced UpdateSchedules API
+type UpdateSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec UpdateScheduleSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// UpdateScheduleList contains a list of UpdateSchedule
+type UpdateScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []UpdateSchedule `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=cus
+
+// ClusterUpdateSchedule is the Schema for the cluster-scoped ClusterUpdateSchedules API
+type ClusterUpdateSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="!has(self.blackoutCalendars) || self.blackoutCalendars.all(c, has(c.__namespace__) && c.__namespace__ != '')",message="blackoutCalendars of a ClusterUpdateSchedule must set the namespace"
+	Spec UpdateScheduleSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterUpdateScheduleList contains a list of ClusterUpdateSchedule
+type ClusterUpdateScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterUpdateSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&UpdateSchedule{}, &UpdateScheduleList{}, &ClusterUpdateSchedule{}, &ClusterUpdateScheduleList{})
+}
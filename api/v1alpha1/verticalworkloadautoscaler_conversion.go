@@ -0,0 +1,435 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/alexei-led/vertical-workload-autoscaler/api/v1beta1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// TargetRefAnnotation keeps spec.targetRef of v1beta1 objects, which has no v1alpha1 field
+const TargetRefAnnotation = "autoscaling.workload.io/target-ref"
+
+// TargetRef returns the workload pinned with spec.targetRef of the v1beta1 API, if any
+func (in *VerticalWorkloadAutoscaler) TargetRef() (*autoscalingv2.CrossVersionObjectReference, error) {
+	value, ok := in.Annotations[TargetRefAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var ref autoscalingv2.CrossVersionObjectReference
+	if err := json.Unmarshal([]byte(value), &ref); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", TargetRefAnnotation, err)
+	}
+	return &ref, nil
+}
+
+// ConvertTo converts this VerticalWorkloadAutoscaler to the Hub version (v1beta1).
+func (src *VerticalWorkloadAutoscaler) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.VerticalWorkloadAutoscaler)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	targetRef, err := src.TargetRef()
+	if err != nil {
+		return err
+	}
+	if targetRef != nil {
+		dst.Spec.TargetRef = targetRef
+		delete(dst.Annotations, TargetRefAnnotation)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+
+	// Spec
+	dst.Spec.VPARef = v1beta1.VPAReference{Name: src.Spec.VPAReference.Name}
+	dst.Spec.UpdatePolicy = v1beta1.UpdatePolicy{
+		Frequency: src.Spec.UpdateFrequency.DeepCopy(),
+	}
+	for _, window := range src.Spec.AllowedUpdateWindows {
+		dst.Spec.UpdatePolicy.AllowedWindows = append(dst.Spec.UpdatePolicy.AllowedWindows, v1beta1.UpdateWindow{
+			DayOfWeek: window.DayOfWeek,
+			StartTime: window.StartTime,
+			EndTime:   window.EndTime,
+			TimeZone:  window.TimeZone,
+			Direction: v1beta1.UpdateDirection(window.Direction),
+		})
+	}
+	for _, window := range src.Spec.BlackoutWindows {
+		dst.Spec.UpdatePolicy.BlackoutWindows = append(dst.Spec.UpdatePolicy.BlackoutWindows, v1beta1.BlackoutWindow{
+			Name:  window.Name,
+			Start: window.Start,
+			End:   window.End,
+		})
+	}
+	for _, calendar := range src.Spec.BlackoutCalendars {
+		dst.Spec.UpdatePolicy.BlackoutCalendars = append(dst.Spec.UpdatePolicy.BlackoutCalendars, v1beta1.CalendarReference(calendar))
+	}
+	for _, schedule := range src.Spec.UpdateSchedules {
+		dst.Spec.UpdatePolicy.Schedules = append(dst.Spec.UpdatePolicy.Schedules, v1beta1.UpdateScheduleReference(schedule))
+	}
+	if src.Spec.DriftPolicy != nil {
+		dst.Spec.UpdatePolicy.Drift = &v1beta1.DriftPolicy{
+			MaxReverts: src.Spec.DriftPolicy.MaxReverts,
+			Period:     src.Spec.DriftPolicy.Period.DeepCopy(),
+		}
+	}
+
+	avoidCPULimit := src.Spec.AvoidCPULimit
+	dst.Spec.ResourcePolicy = v1beta1.ResourcePolicy{
+		QualityOfService: v1beta1.QualityOfServiceClass(src.Spec.QualityOfService),
+		AvoidCPULimit:    &avoidCPULimit,
+	}
+	if src.Spec.IgnoreCPURecommendations || src.Spec.IgnoreMemoryRecommendations {
+		controlled := []v1beta1.ControlledResource{}
+		if !src.Spec.IgnoreCPURecommendations {
+			controlled = append(controlled, v1beta1.ControlledResourceCPU)
+		}
+		if !src.Spec.IgnoreMemoryRecommendations {
+			controlled = append(controlled, v1beta1.ControlledResourceMemory)
+		}
+		dst.Spec.ResourcePolicy.ControlledResources = &controlled
+	}
+	if src.Spec.UpdateTolerance != nil {
+		dst.Spec.ResourcePolicy.Tolerance = &v1beta1.Tolerance{
+			CPU:    toPercentage(src.Spec.UpdateTolerance.CPU),
+			Memory: toPercentage(src.Spec.UpdateTolerance.Memory),
+		}
+	}
+	dst.Spec.CustomAnnotations = src.Spec.CustomAnnotations
+	dst.Spec.DeletionPolicy = v1beta1.DeletionPolicy(src.Spec.DeletionPolicy)
+	dst.Spec.Suspend = src.Spec.Suspend
+	dst.Spec.ApplyMethod = v1beta1.ApplyMethod(src.Spec.ApplyMethod)
+	dst.Spec.UpdateMode = v1beta1.UpdateMode(src.Spec.UpdateMode)
+	dst.Spec.Approval = v1beta1.ApprovalMode(src.Spec.Approval)
+	dst.Spec.RevisionHistoryLimit = src.Spec.RevisionHistoryLimit
+	dst.Spec.RollbackTo = src.Spec.RollbackTo
+	if src.Spec.GitOps != nil {
+		dst.Spec.GitOps = &v1beta1.GitOpsIntegration{Tool: v1beta1.GitOpsTool(src.Spec.GitOps.Tool)}
+	}
+	if src.Spec.GitWriteback != nil {
+		dst.Spec.GitWriteback = &v1beta1.GitWriteback{
+			Repository: src.Spec.GitWriteback.Repository,
+			Branch:     src.Spec.GitWriteback.Branch,
+			Path:       src.Spec.GitWriteback.Path,
+			Format:     v1beta1.GitWritebackFormat(src.Spec.GitWriteback.Format),
+			ValuesKey:  src.Spec.GitWriteback.ValuesKey,
+			SecretRef:  src.Spec.GitWriteback.SecretRef,
+		}
+	}
+	if src.Spec.HPAPolicy != nil {
+		dst.Spec.HPAPolicy = &v1beta1.HPAPolicy{
+			CustomMetrics:     v1beta1.CustomMetricsPolicy(src.Spec.HPAPolicy.CustomMetrics),
+			CoordinateTargets: src.Spec.HPAPolicy.CoordinateTargets,
+		}
+		if src.Spec.HPAPolicy.SaturationScaleUp != nil {
+			scaleUp := v1beta1.SaturationScaleUp(*src.Spec.HPAPolicy.SaturationScaleUp)
+			dst.Spec.HPAPolicy.SaturationScaleUp = &scaleUp
+		}
+	}
+
+	// Status
+	if src.Status.ScaleTargetRef != (autoscalingv2.CrossVersionObjectReference{}) {
+		ref := src.Status.ScaleTargetRef
+		dst.Status.TargetRef = &ref
+	}
+	dst.Status.LastUpdated = src.Status.LastUpdated
+	dst.Status.RecommendedRequests = src.Status.RecommendedRequests
+	dst.Status.AppliedResources = src.Status.AppliedResources
+	dst.Status.OriginalResources = src.Status.OriginalResources
+	for _, revision := range src.Status.Revisions {
+		dst.Status.Revisions = append(dst.Status.Revisions, v1beta1.Revision{
+			Revision:   revision.Revision,
+			Timestamp:  revision.Timestamp,
+			Reason:     v1beta1.RevisionReason(revision.Reason),
+			Message:    revision.Message,
+			Containers: toContainerRevisions(revision.Containers),
+		})
+	}
+	dst.Status.OutdatedPods = src.Status.OutdatedPods
+	dst.Status.LastUpdateMode = v1beta1.UpdateMode(src.Status.LastUpdateMode)
+	dst.Status.ObservedTemplateHash = src.Status.ObservedTemplateHash
+	if src.Status.PendingProposal != nil {
+		dst.Status.PendingProposal = &v1beta1.Proposal{
+			Hash:       src.Status.PendingProposal.Hash,
+			ProposedAt: src.Status.PendingProposal.ProposedAt,
+			Containers: toContainerRevisions(src.Status.PendingProposal.Containers),
+		}
+	}
+	dst.Status.Reverts = src.Status.Reverts
+	dst.Status.SkippedUpdates = src.Status.SkippedUpdates
+	dst.Status.SkipReason = src.Status.SkipReason
+	dst.Status.ActiveBlackout = src.Status.ActiveBlackout
+	if src.Status.GitOpsOwner != nil {
+		owner := v1beta1.GitOpsOwner{
+			Tool:      v1beta1.GitOpsTool(src.Status.GitOpsOwner.Tool),
+			Kind:      src.Status.GitOpsOwner.Kind,
+			Name:      src.Status.GitOpsOwner.Name,
+			Namespace: src.Status.GitOpsOwner.Namespace,
+		}
+		if ignore := src.Status.GitOpsOwner.IgnoreDifferences; ignore != nil {
+			ignoreDifferences := v1beta1.ArgoCDIgnoreDifferences(*ignore)
+			owner.IgnoreDifferences = &ignoreDifferences
+		}
+		dst.Status.GitOpsOwner = &owner
+	}
+	if src.Status.GitWriteback != nil {
+		writeback := v1beta1.GitWritebackStatus(*src.Status.GitWriteback)
+		dst.Status.GitWriteback = &writeback
+	}
+	if src.Status.EffectiveIgnore != nil {
+		ignore := v1beta1.EffectiveIgnore{
+			CPU:    src.Status.EffectiveIgnore.CPU,
+			Memory: src.Status.EffectiveIgnore.Memory,
+			Source: src.Status.EffectiveIgnore.Source,
+		}
+		for _, container := range src.Status.EffectiveIgnore.Containers {
+			ignore.Containers = append(ignore.Containers, v1beta1.ContainerIgnore(container))
+		}
+		dst.Status.EffectiveIgnore = &ignore
+	}
+	if src.Status.HPACoordination != nil {
+		coordination := v1beta1.HPACoordination{HPA: src.Status.HPACoordination.HPA, UpdatedAt: src.Status.HPACoordination.UpdatedAt}
+		for _, target := range src.Status.HPACoordination.Targets {
+			coordination.Targets = append(coordination.Targets, v1beta1.HPATarget(target))
+		}
+		dst.Status.HPACoordination = &coordination
+	}
+	if src.Status.HPASaturation != nil {
+		saturation := v1beta1.HPASaturation(*src.Status.HPASaturation)
+		dst.Status.HPASaturation = &saturation
+	}
+	dst.Status.UpdateCount = src.Status.UpdateCount
+	dst.Status.Conditions = src.Status.Conditions
+	for _, conflict := range src.Status.Conflicts {
+		dst.Status.Conflicts = append(dst.Status.Conflicts, v1beta1.Conflict(conflict))
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *VerticalWorkloadAutoscaler) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.VerticalWorkloadAutoscaler)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if src.Spec.TargetRef != nil {
+		value, err := json.Marshal(src.Spec.TargetRef)
+		if err != nil {
+			return err
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = make(map[string]string)
+		}
+		dst.Annotations[TargetRefAnnotation] = string(value)
+	}
+
+	// Spec
+	dst.Spec.VPAReference = VPAReference{Name: src.Spec.VPARef.Name}
+	dst.Spec.UpdateFrequency = src.Spec.UpdatePolicy.Frequency.DeepCopy()
+	for _, window := range src.Spec.UpdatePolicy.AllowedWindows {
+		dst.Spec.AllowedUpdateWindows = append(dst.Spec.AllowedUpdateWindows, UpdateWindow{
+			DayOfWeek: window.DayOfWeek,
+			StartTime: window.StartTime,
+			EndTime:   window.EndTime,
+			TimeZone:  window.TimeZone,
+			Direction: UpdateDirection(window.Direction),
+		})
+	}
+	for _, window := range src.Spec.UpdatePolicy.BlackoutWindows {
+		dst.Spec.BlackoutWindows = append(dst.Spec.BlackoutWindows, BlackoutWindow{
+			Name:  window.Name,
+			Start: window.Start,
+			End:   window.End,
+		})
+	}
+	for _, calendar := range src.Spec.UpdatePolicy.BlackoutCalendars {
+		dst.Spec.BlackoutCalendars = append(dst.Spec.BlackoutCalendars, CalendarReference(calendar))
+	}
+	for _, schedule := range src.Spec.UpdatePolicy.Schedules {
+		dst.Spec.UpdateSchedules = append(dst.Spec.UpdateSchedules, UpdateScheduleReference(schedule))
+	}
+	if src.Spec.UpdatePolicy.Drift != nil {
+		dst.Spec.DriftPolicy = &DriftPolicy{
+			MaxReverts: src.Spec.UpdatePolicy.Drift.MaxReverts,
+			Period:     src.Spec.UpdatePolicy.Drift.Period.DeepCopy(),
+		}
+	}
+
+	policy := src.Spec.ResourcePolicy
+	dst.Spec.QualityOfService = QualityOfServiceClass(policy.QualityOfService)
+	dst.Spec.AvoidCPULimit = policy.AvoidCPULimit == nil || *policy.AvoidCPULimit
+	if policy.ControlledResources != nil {
+		dst.Spec.IgnoreCPURecommendations = !slices.Contains(*policy.ControlledResources, v1beta1.ControlledResourceCPU)
+		dst.Spec.IgnoreMemoryRecommendations = !slices.Contains(*policy.ControlledResources, v1beta1.ControlledResourceMemory)
+	}
+	if policy.Tolerance != nil {
+		dst.Spec.UpdateTolerance = &UpdateTolerance{
+			CPU:    fromPercentage(policy.Tolerance.CPU),
+			Memory: fromPercentage(policy.Tolerance.Memory),
+		}
+	}
+	dst.Spec.CustomAnnotations = src.Spec.CustomAnnotations
+	dst.Spec.DeletionPolicy = DeletionPolicy(src.Spec.DeletionPolicy)
+	dst.Spec.Suspend = src.Spec.Suspend
+	dst.Spec.ApplyMethod = ApplyMethod(src.Spec.ApplyMethod)
+	dst.Spec.UpdateMode = UpdateMode(src.Spec.UpdateMode)
+	dst.Spec.Approval = ApprovalMode(src.Spec.Approval)
+	dst.Spec.RevisionHistoryLimit = src.Spec.RevisionHistoryLimit
+	dst.Spec.RollbackTo = src.Spec.RollbackTo
+	if src.Spec.GitOps != nil {
+		dst.Spec.GitOps = &GitOpsIntegration{Tool: GitOpsTool(src.Spec.GitOps.Tool)}
+	}
+	if src.Spec.GitWriteback != nil {
+		dst.Spec.GitWriteback = &GitWriteback{
+			Repository: src.Spec.GitWriteback.Repository,
+			Branch:     src.Spec.GitWriteback.Branch,
+			Path:       src.Spec.GitWriteback.Path,
+			Format:     GitWritebackFormat(src.Spec.GitWriteback.Format),
+			ValuesKey:  src.Spec.GitWriteback.ValuesKey,
+			SecretRef:  src.Spec.GitWriteback.SecretRef,
+		}
+	}
+	if src.Spec.HPAPolicy != nil {
+		dst.Spec.HPAPolicy = &HPAPolicy{
+			CustomMetrics:     CustomMetricsPolicy(src.Spec.HPAPolicy.CustomMetrics),
+			CoordinateTargets: src.Spec.HPAPolicy.CoordinateTargets,
+		}
+		if src.Spec.HPAPolicy.SaturationScaleUp != nil {
+			scaleUp := SaturationScaleUp(*src.Spec.HPAPolicy.SaturationScaleUp)
+			dst.Spec.HPAPolicy.SaturationScaleUp = &scaleUp
+		}
+	}
+
+	// Status
+	if src.Status.TargetRef != nil {
+		dst.Status.ScaleTargetRef = *src.Status.TargetRef
+	}
+	dst.Status.LastUpdated = src.Status.LastUpdated
+	dst.Status.RecommendedRequests = src.Status.RecommendedRequests
+	dst.Status.AppliedResources = src.Status.AppliedResources
+	dst.Status.OriginalResources = src.Status.OriginalResources
+	for _, revision := range src.Status.Revisions {
+		dst.Status.Revisions = append(dst.Status.Revisions, Revision{
+			Revision:   revision.Revision,
+			Timestamp:  revision.Timestamp,
+			Reason:     RevisionReason(revision.Reason),
+			Message:    revision.Message,
+			Containers: fromContainerRevisions(revision.Containers),
+		})
+	}
+	dst.Status.OutdatedPods = src.Status.OutdatedPods
+	dst.Status.LastUpdateMode = UpdateMode(src.Status.LastUpdateMode)
+	dst.Status.ObservedTemplateHash = src.Status.ObservedTemplateHash
+	if src.Status.PendingProposal != nil {
+		dst.Status.PendingProposal = &Proposal{
+			Hash:       src.Status.PendingProposal.Hash,
+			ProposedAt: src.Status.PendingProposal.ProposedAt,
+			Containers: fromContainerRevisions(src.Status.PendingProposal.Containers),
+		}
+	}
+	dst.Status.Reverts = src.Status.Reverts
+	dst.Status.SkippedUpdates = src.Status.SkippedUpdates
+	dst.Status.SkipReason = src.Status.SkipReason
+	dst.Status.ActiveBlackout = src.Status.ActiveBlackout
+	if src.Status.GitOpsOwner != nil {
+		owner := GitOpsOwner{
+			Tool:      GitOpsTool(src.Status.GitOpsOwner.Tool),
+			Kind:      src.Status.GitOpsOwner.Kind,
+			Name:      src.Status.GitOpsOwner.Name,
+			Namespace: src.Status.GitOpsOwner.Namespace,
+		}
+		if ignore := src.Status.GitOpsOwner.IgnoreDifferences; ignore != nil {
+			ignoreDifferences := ArgoCDIgnoreDifferences(*ignore)
+			owner.IgnoreDifferences = &ignoreDifferences
+		}
+		dst.Status.GitOpsOwner = &owner
+	}
+	if src.Status.GitWriteback != nil {
+		writeback := GitWritebackStatus(*src.Status.GitWriteback)
+		dst.Status.GitWriteback = &writeback
+	}
+	if src.Status.EffectiveIgnore != nil {
+		ignore := EffectiveIgnore{
+			CPU:    src.Status.EffectiveIgnore.CPU,
+			Memory: src.Status.EffectiveIgnore.Memory,
+			Source: src.Status.EffectiveIgnore.Source,
+		}
+		for _, container := range src.Status.EffectiveIgnore.Containers {
+			ignore.Containers = append(ignore.Containers, ContainerIgnore(container))
+		}
+		dst.Status.EffectiveIgnore = &ignore
+	}
+	if src.Status.HPACoordination != nil {
+		coordination := HPACoordination{HPA: src.Status.HPACoordination.HPA, UpdatedAt: src.Status.HPACoordination.UpdatedAt}
+		for _, target := range src.Status.HPACoordination.Targets {
+			coordination.Targets = append(coordination.Targets, HPATarget(target))
+		}
+		dst.Status.HPACoordination = &coordination
+	}
+	if src.Status.HPASaturation != nil {
+		saturation := HPASaturation(*src.Status.HPASaturation)
+		dst.Status.HPASaturation = &saturation
+	}
+	dst.Status.UpdateCount = src.Status.UpdateCount
+	dst.Status.Conditions = src.Status.Conditions
+	for _, conflict := range src.Status.Conflicts {
+		dst.Status.Conflicts = append(dst.Status.Conflicts, Conflict(conflict))
+	}
+	return nil
+}
+
+func toPercentage(value *int) *v1beta1.Percentage {
+	if value == nil {
+		return nil
+	}
+	percentage := v1beta1.Percentage(*value)
+	return &percentage
+}
+
+func fromPercentage(percentage *v1beta1.Percentage) *int {
+	if percentage == nil {
+		return nil
+	}
+	value := int(*percentage)
+	return &value
+}
+
+func toContainerRevisions(containers map[string]ContainerRevision) map[string]v1beta1.ContainerRevision {
+	if containers == nil {
+		return nil
+	}
+	result := make(map[string]v1beta1.ContainerRevision, len(containers))
+	for name, container := range containers {
+		result[name] = v1beta1.ContainerRevision(container)
+	}
+	return result
+}
+
+func fromContainerRevisions(containers map[string]v1beta1.ContainerRevision) map[string]ContainerRevision {
+	if containers == nil {
+		return nil
+	}
+	result := make(map[string]ContainerRevision, len(containers))
+	for name, container := range containers {
+		result[name] = ContainerRevision(container)
+	}
+	return result
+}
@@ -0,0 +1,243 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alexei-led/vertical-workload-autoscaler/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestConvertRoundTrip(t *testing.T) {
+	now := metav1.NewTime(time.Date(2024, 9, 21, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name string
+		vwa  VerticalWorkloadAutoscaler
+	}{
+		{
+			name: "Minimal",
+			vwa: VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Spec:       VerticalWorkloadAutoscalerSpec{VPAReference: VPAReference{Name: "vpa1"}},
+			},
+		},
+		{
+			name: "All fields",
+			vwa: VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "vwa1",
+					Namespace:   "default",
+					Labels:      map[string]string{"team": "checkout"},
+					Annotations: map[string]string{TargetRefAnnotation: `{"kind":"Deployment","name":"web","apiVersion":"apps/v1"}`},
+				},
+				Spec: VerticalWorkloadAutoscalerSpec{
+					VPAReference:    VPAReference{Name: "vpa1"},
+					UpdateFrequency: &metav1.Duration{Duration: 10 * time.Minute},
+					AllowedUpdateWindows: []UpdateWindow{
+						{DayOfWeek: "Monday", StartTime: "01:00", EndTime: "05:00", TimeZone: "UTC", Direction: UpdateDirectionDecrease},
+					},
+					BlackoutWindows:      []BlackoutWindow{{Name: "Black Friday", Start: now, End: metav1.NewTime(now.Add(96 * time.Hour))}},
+					BlackoutCalendars:    []CalendarReference{{Name: "holidays", Key: DefaultCalendarKey}},
+					UpdateSchedules:      []UpdateScheduleReference{{Kind: ClusterUpdateScheduleKind, Name: "fleet"}},
+					QualityOfService:     BurstableQualityOfService,
+					AvoidCPULimit:        true,
+					UpdateTolerance:      &UpdateTolerance{CPU: ptr.To(0), Memory: ptr.To(25)},
+					CustomAnnotations:    map[string]string{"argocd.argoproj.io/compare-options": "IgnoreExtraneous"},
+					GitOps:               &GitOpsIntegration{Tool: GitOpsToolArgoCD},
+					DeletionPolicy:       DeletionPolicyRestore,
+					Suspend:              true,
+					ApplyMethod:          ApplyMethodAdmission,
+					UpdateMode:           UpdateModeOnRollout,
+					Approval:             ApprovalManual,
+					RevisionHistoryLimit: ptr.To(int32(5)),
+					RollbackTo:           ptr.To(int64(2)),
+					GitWriteback: &GitWriteback{
+						Repository: "https://github.com/org/deploy.git",
+						Branch:     "main",
+						Path:       "apps/{{ .Name }}/resources.yaml",
+						Format:     GitWritebackFormatHelmValues,
+						ValuesKey:  "{{ .Container }}.resources",
+						SecretRef:  &corev1.LocalObjectReference{Name: "git"},
+					},
+					DriftPolicy: &DriftPolicy{MaxReverts: ptr.To(int32(5)), Period: &metav1.Duration{Duration: 2 * time.Hour}},
+					HPAPolicy: &HPAPolicy{
+						CustomMetrics:     CustomMetricsIgnoreCPU,
+						CoordinateTargets: true,
+						SaturationScaleUp: &SaturationScaleUp{After: &metav1.Duration{Duration: 10 * time.Minute}, MaxCPU: ptr.To(resource.MustParse("2"))},
+					},
+				},
+				Status: VerticalWorkloadAutoscalerStatus{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web", APIVersion: "apps/v1"},
+					LastUpdated:    &now,
+					RecommendedRequests: map[string]corev1.ResourceRequirements{
+						"web": {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+					},
+					AppliedResources: map[string]corev1.ResourceRequirements{
+						"web": {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("400m")}},
+					},
+					OriginalResources: map[string]corev1.ResourceRequirements{
+						"web": {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+					},
+					Revisions: []Revision{
+						{
+							Revision:  2,
+							Timestamp: now,
+							Reason:    RevisionReasonRollback,
+							Message:   "rolled back to revision 1",
+							Containers: map[string]ContainerRevision{
+								"web": {
+									Before:         corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+									After:          corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("400m")}},
+									Recommendation: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+								},
+							},
+						},
+					},
+					OutdatedPods:         2,
+					LastUpdateMode:       UpdateModeOnRollout,
+					ObservedTemplateHash: "fedcba9876543210",
+					PendingProposal: &Proposal{
+						Hash:       "0123456789abcdef",
+						ProposedAt: now,
+						Containers: map[string]ContainerRevision{
+							"web": {
+								Before:         corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("400m")}},
+								After:          corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("600m")}},
+								Recommendation: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("600m")},
+							},
+						},
+					},
+					Reverts:        []metav1.Time{now},
+					SkippedUpdates: true,
+					SkipReason:     "blackout",
+					ActiveBlackout: "Black Friday",
+					GitWriteback: &GitWritebackStatus{
+						CommitSHA:   "0123abcd",
+						Path:        "apps/web/resources.yaml",
+						CommittedAt: now,
+						Resources: map[string]corev1.ResourceRequirements{
+							"web": {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+						},
+						Observed: true,
+					},
+					GitOpsOwner: &GitOpsOwner{Tool: GitOpsToolArgoCD, Kind: "Application", Name: "web", Namespace: "argocd",
+						IgnoreDifferences: &ArgoCDIgnoreDifferences{Group: "apps", Kind: "Deployment", Name: "web", Namespace: "default",
+							ManagedFieldsManagers: []string{"vertical-workload-autoscaler"}}},
+					EffectiveIgnore: &EffectiveIgnore{
+						CPU:        true,
+						Containers: []ContainerIgnore{{Name: "sidecar", Memory: true}},
+						Source:     "HorizontalPodAutoscaler/web",
+					},
+					HPACoordination: &HPACoordination{
+						HPA:       "web",
+						Targets:   []HPATarget{{Metric: "cpu", Threshold: resource.MustParse("400m"), AverageUtilization: 80}},
+						UpdatedAt: &now,
+					},
+					HPASaturation: &HPASaturation{HPA: "web", Metrics: []string{"cpu"}, Since: now, ScaleUp: true},
+					UpdateCount:   3,
+					Conditions:    []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "TargetObjectFound", LastTransitionTime: now}},
+					Conflicts:     []Conflict{{Resource: "cpu", ConflictWith: "HPA/web", Reason: "HPA scales on CPU"}},
+				},
+			},
+		},
+		{
+			name: "CPU recommendations ignored",
+			vwa: VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Spec:       VerticalWorkloadAutoscalerSpec{VPAReference: VPAReference{Name: "vpa1"}, IgnoreCPURecommendations: true},
+			},
+		},
+		{
+			name: "All recommendations ignored",
+			vwa: VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Spec: VerticalWorkloadAutoscalerSpec{
+					VPAReference:                VPAReference{Name: "vpa1"},
+					IgnoreCPURecommendations:    true,
+					IgnoreMemoryRecommendations: true,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := &v1beta1.VerticalWorkloadAutoscaler{}
+			assert.NoError(t, tt.vwa.DeepCopy().ConvertTo(hub))
+
+			converted := &VerticalWorkloadAutoscaler{}
+			assert.NoError(t, converted.ConvertFrom(hub))
+			assert.Equal(t, tt.vwa, *converted)
+		})
+	}
+}
+
+func TestConvertTo(t *testing.T) {
+	vwa := &VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "vwa1",
+			Namespace:   "default",
+			Annotations: map[string]string{TargetRefAnnotation: `{"kind":"Deployment","name":"web","apiVersion":"apps/v1"}`},
+		},
+		Spec: VerticalWorkloadAutoscalerSpec{
+			VPAReference:             VPAReference{Name: "vpa1"},
+			IgnoreCPURecommendations: true,
+			UpdateTolerance:          &UpdateTolerance{CPU: ptr.To(5)},
+		},
+	}
+
+	hub := &v1beta1.VerticalWorkloadAutoscaler{}
+	assert.NoError(t, vwa.ConvertTo(hub))
+	assert.Nil(t, hub.Annotations)
+	assert.Equal(t, &autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web", APIVersion: "apps/v1"}, hub.Spec.TargetRef)
+	assert.Equal(t, v1beta1.VPAReference{Name: "vpa1"}, hub.Spec.VPARef)
+	assert.Equal(t, &[]v1beta1.ControlledResource{v1beta1.ControlledResourceMemory}, hub.Spec.ResourcePolicy.ControlledResources)
+	assert.Equal(t, &v1beta1.Tolerance{CPU: ptr.To(v1beta1.Percentage(5))}, hub.Spec.ResourcePolicy.Tolerance)
+	assert.Equal(t, ptr.To(false), hub.Spec.ResourcePolicy.AvoidCPULimit)
+	assert.Nil(t, hub.Status.TargetRef)
+}
+
+func TestConvertToInvalidTargetRef(t *testing.T) {
+	vwa := &VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Annotations: map[string]string{TargetRefAnnotation: "web"}},
+	}
+	assert.Error(t, vwa.ConvertTo(&v1beta1.VerticalWorkloadAutoscaler{}))
+}
+
+func TestConvertFromRoundTrip(t *testing.T) {
+	hub := &v1beta1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: v1beta1.VerticalWorkloadAutoscalerSpec{
+			VPARef:    v1beta1.VPAReference{Name: "vpa1"},
+			TargetRef: &autoscalingv2.CrossVersionObjectReference{Kind: "StatefulSet", Name: "db", APIVersion: "apps/v1"},
+			UpdatePolicy: v1beta1.UpdatePolicy{
+				Frequency: &metav1.Duration{Duration: time.Hour},
+				Schedules: []v1beta1.UpdateScheduleReference{{Kind: "UpdateSchedule", Name: "nightly"}},
+			},
+			ResourcePolicy: v1beta1.ResourcePolicy{
+				QualityOfService:    v1beta1.GuaranteedQualityOfService,
+				AvoidCPULimit:       ptr.To(true),
+				ControlledResources: &[]v1beta1.ControlledResource{v1beta1.ControlledResourceCPU},
+				Tolerance:           &v1beta1.Tolerance{Memory: ptr.To(v1beta1.Percentage(0))},
+			},
+		},
+	}
+
+	spoke := &VerticalWorkloadAutoscaler{}
+	assert.NoError(t, spoke.ConvertFrom(hub))
+	assert.True(t, spoke.Spec.IgnoreMemoryRecommendations)
+	assert.False(t, spoke.Spec.IgnoreCPURecommendations)
+	targetRef, err := spoke.TargetRef()
+	assert.NoError(t, err)
+	assert.Equal(t, hub.Spec.TargetRef, targetRef)
+
+	converted := &v1beta1.VerticalWorkloadAutoscaler{}
+	assert.NoError(t, spoke.ConvertTo(converted))
+	assert.Equal(t, hub, converted)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultUpdateFrequency is the built-in default of spec.updateFrequency
+	DefaultUpdateFrequency = 5 * time.Minute
+	// DefaultQualityOfService is the built-in default of spec.qualityOfService
+	DefaultQualityOfService = GuaranteedQualityOfService
+	// DefaultTolerance is the built-in default of spec.updateTolerance.cpu and spec.updateTolerance.memory (in percent)
+	DefaultTolerance = 10
+	// DefaultMaxReverts is the built-in default of spec.driftPolicy.maxReverts
+	DefaultMaxReverts = 3
+	// DefaultDriftPeriod is the built-in default of spec.driftPolicy.period
+	DefaultDriftPeriod = time.Hour
+	// DefaultRevisionHistoryLimit is the built-in default of spec.revisionHistoryLimit
+	DefaultRevisionHistoryLimit = 10
+	// DefaultSaturationScaleUpAfter is the built-in default of spec.hpaPolicy.saturationScaleUp.after
+	DefaultSaturationScaleUpAfter = 5 * time.Minute
+	// DefaultGitWritebackBranch is the built-in default of spec.gitWriteback.branch
+	DefaultGitWritebackBranch = "main"
+)
+
+// SpecDefaults holds the values set for unset VerticalWorkloadAutoscaler spec fields.
+// The controller defaults can be overridden with manager flags.
+// +kubebuilder:object:generate=false
+type SpecDefaults struct {
+	UpdateFrequency  time.Duration
+	QualityOfService QualityOfServiceClass
+	CPUTolerance     int
+	MemoryTolerance  int
+}
+
+// NewSpecDefaults returns the built-in spec defaults
+func NewSpecDefaults() SpecDefaults {
+	return SpecDefaults{
+		UpdateFrequency:  DefaultUpdateFrequency,
+		QualityOfService: DefaultQualityOfService,
+		CPUTolerance:     DefaultTolerance,
+		MemoryTolerance:  DefaultTolerance,
+	}
+}
+
+// Validate checks that the defaults are valid spec values
+func (d SpecDefaults) Validate() error {
+	if d.UpdateFrequency <= 0 {
+		return fmt.Errorf("default update frequency must be positive, got %s", d.UpdateFrequency)
+	}
+	if d.QualityOfService != GuaranteedQualityOfService && d.QualityOfService != BurstableQualityOfService {
+		return fmt.Errorf("default quality of service must be %s or %s, got '%s'", GuaranteedQualityOfService, BurstableQualityOfService, d.QualityOfService)
+	}
+	if d.CPUTolerance < 0 || d.CPUTolerance > 100 {
+		return fmt.Errorf("default CPU tolerance must be between 0 and 100, got %d", d.CPUTolerance)
+	}
+	if d.MemoryTolerance < 0 || d.MemoryTolerance > 100 {
+		return fmt.Errorf("default memory tolerance must be between 0 and 100, got %d", d.MemoryTolerance)
+	}
+	return nil
+}
+
+// Apply sets every unset field of the spec to its effective default, so the stored object
+// shows exactly what the controller does
+func (d SpecDefaults) Apply(spec *VerticalWorkloadAutoscalerSpec) {
+	if spec.UpdateFrequency == nil {
+		spec.UpdateFrequency = &metav1.Duration{Duration: d.UpdateFrequency}
+	}
+	if spec.QualityOfService == "" {
+		spec.QualityOfService = d.QualityOfService
+	}
+	if spec.UpdateTolerance == nil {
+		spec.UpdateTolerance = &UpdateTolerance{}
+	}
+	if spec.UpdateTolerance.CPU == nil {
+		cpu := d.CPUTolerance
+		spec.UpdateTolerance.CPU = &cpu
+	}
+	if spec.UpdateTolerance.Memory == nil {
+		memory := d.MemoryTolerance
+		spec.UpdateTolerance.Memory = &memory
+	}
+	if spec.DriftPolicy == nil {
+		spec.DriftPolicy = &DriftPolicy{}
+	}
+	if spec.DriftPolicy.MaxReverts == nil {
+		maxReverts := int32(DefaultMaxReverts)
+		spec.DriftPolicy.MaxReverts = &maxReverts
+	}
+	if spec.DriftPolicy.Period == nil {
+		spec.DriftPolicy.Period = &metav1.Duration{Duration: DefaultDriftPeriod}
+	}
+	if spec.DeletionPolicy == "" {
+		spec.DeletionPolicy = DeletionPolicyRetain
+	}
+	if spec.ApplyMethod == "" {
+		spec.ApplyMethod = ApplyMethodTemplate
+	}
+	if spec.UpdateMode == "" {
+		spec.UpdateMode = UpdateModeAuto
+	}
+	if spec.Approval == "" {
+		spec.Approval = ApprovalAutomatic
+	}
+	if spec.RevisionHistoryLimit == nil {
+		limit := int32(DefaultRevisionHistoryLimit)
+		spec.RevisionHistoryLimit = &limit
+	}
+	if spec.HPAPolicy != nil {
+		if spec.HPAPolicy.CustomMetrics == "" {
+			spec.HPAPolicy.CustomMetrics = CustomMetricsAllow
+		}
+		if spec.HPAPolicy.SaturationScaleUp != nil && spec.HPAPolicy.SaturationScaleUp.After == nil {
+			spec.HPAPolicy.SaturationScaleUp.After = &metav1.Duration{Duration: DefaultSaturationScaleUpAfter}
+		}
+	}
+	if spec.GitWriteback != nil {
+		if spec.GitWriteback.Branch == "" {
+			spec.GitWriteback.Branch = DefaultGitWritebackBranch
+		}
+		if spec.GitWriteback.Format == "" {
+			spec.GitWriteback.Format = GitWritebackFormatKustomizePatch
+		}
+	}
+	for i := range spec.AllowedUpdateWindows {
+		if spec.AllowedUpdateWindows[i].Direction == "" {
+			spec.AllowedUpdateWindows[i].Direction = UpdateDirectionBoth
+		}
+	}
+	for i := range spec.BlackoutCalendars {
+		if spec.BlackoutCalendars[i].Key == "" {
+			spec.BlackoutCalendars[i].Key = DefaultCalendarKey
+		}
+	}
+	for i := range spec.UpdateSchedules {
+		if spec.UpdateSchedules[i].Kind == "" {
+			spec.UpdateSchedules[i].Kind = UpdateScheduleKind
+		}
+	}
+}
@@ -19,14 +19,13 @@ package v1alpha1
 import (
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // QualityOfServiceClass defines the quality of service class
 // Only Burstable and Guaranteed are supported
-// if not set, the default is Guaranteed
 // +kubebuilder:validation:Enum=Burstable;Guaranteed
-// +kubebuilder:default=Guaranteed
 type QualityOfServiceClass string
 
 const (
@@ -36,6 +35,26 @@ const (
 	GuaranteedQualityOfService QualityOfServiceClass = "Guaranteed"
 )
 
+const (
+	// UpdatedByAnnotation is set on the target workload to the name of the VWA that last updated its resources
+	UpdatedByAnnotation = "verticalworkloadautoscaler.kubernetes.io/updatedBy"
+	// LastUpdatedAnnotation is set on the target workload to the time of the last resources update
+	LastUpdatedAnnotation = "verticalworkloadautoscaler.kubernetes.io/lastUpdated"
+	// AllowManualResourcesAnnotation set to "true" on a workload allows manual changes of the
+	// VWA managed container resources when the workload protection runs in strict mode
+	AllowManualResourcesAnnotation = "verticalworkloadautoscaler.kubernetes.io/allowManualResources"
+	// ApproveAnnotation set on a VWA to the hash of its pending proposal approves the proposed resource change
+	// with the Manual approval mode
+	ApproveAnnotation = "verticalworkloadautoscaler.kubernetes.io/approve"
+	// AppliedResourcesAnnotation records on a pod the container resources set by the pod mutating webhook
+	AppliedResourcesAnnotation = "verticalworkloadautoscaler.kubernetes.io/appliedResources"
+	// SuspendAnnotation set to "true" on a VWA stops all updates of its target workload, like spec.suspend
+	SuspendAnnotation = "verticalworkloadautoscaler.kubernetes.io/suspend"
+	// ForceApplyAnnotation set to "true" on a VWA makes the next reconcile bypass the update windows and
+	// frequency; the annotation is removed once used
+	ForceApplyAnnotation = "verticalworkloadautoscaler.kubernetes.io/forceApply"
+)
+
 // VerticalWorkloadAutoscalerSpec defines the desired state of VerticalWorkloadAutoscaler
 type VerticalWorkloadAutoscalerSpec struct {
 	// VPAReference defines the reference to the VerticalPodAutoscaler that this VWA is managing.
@@ -44,10 +63,10 @@ type VerticalWorkloadAutoscalerSpec struct {
 	VPAReference VPAReference `json:"vpaReference"`
 
 	// UpdateFrequency specifies how often the VWA should check and apply updates to resource requests.
-	// It is defined as a duration (e.g., "30s", "1m"). The default value is set to 5 minutes if not specified.
-	// +kubebuilder:default="5m"
+	// It is defined as a duration (e.g., "30s", "1m"). If not specified, the defaulting webhook
+	// sets the controller default (5 minutes unless overridden with --default-update-frequency).
 	// +optional
-	UpdateFrequency *metav1.Duration `json:"updateFrequency"`
+	UpdateFrequency *metav1.Duration `json:"updateFrequency,omitempty"`
 
 	// AllowedUpdateWindows defines specific time windows during which updates to resource requests
 	// are permitted. This can help minimize disruptions during peak usage times.
@@ -55,16 +74,32 @@ type VerticalWorkloadAutoscalerSpec struct {
 	// +optional
 	AllowedUpdateWindows []UpdateWindow `json:"allowedUpdateWindows"`
 
+	// BlackoutWindows defines absolute time ranges during which updates to resource requests
+	// are not permitted, e.g. Black Friday, quarter-end close or release freezes.
+	// Blackout windows take precedence over AllowedUpdateWindows.
+	// +optional
+	BlackoutWindows []BlackoutWindow `json:"blackoutWindows,omitempty"`
+
+	// BlackoutCalendars references iCalendar (.ics) files stored in ConfigMaps in the VWA namespace.
+	// Every event of a referenced calendar is treated as a blackout window.
+	// +optional
+	BlackoutCalendars []CalendarReference `json:"blackoutCalendars,omitempty"`
+
+	// UpdateSchedules references shared UpdateSchedule or ClusterUpdateSchedule objects.
+	// Their update windows and blackouts are merged with the ones defined inline.
+	// +optional
+	UpdateSchedules []UpdateScheduleReference `json:"updateSchedules,omitempty"`
+
 	// QualityOfService defines the quality of service class to be applied to the managed resource.
 	// This can help Kubernetes make scheduling decisions based on the resource guarantees.
 	// Possible values are:
 	// - "Guaranteed": CPU and Memory requests are equal to limits for all containers.
 	// - "Burstable": Requests are lower than limits, allowing bursts of usage.
-	// If not set, the default is "Guaranteed".
+	// If not set, the defaulting webhook sets the controller default
+	// ("Guaranteed" unless overridden with --default-quality-of-service).
 	// +kubebuilder:validation:Enum=Guaranteed;Burstable
-	// +kubebuilder:default=Guaranteed
 	// +optional
-	QualityOfService QualityOfServiceClass `json:"qualityOfService"`
+	QualityOfService QualityOfServiceClass `json:"qualityOfService,omitempty"`
 
 	// AvoidCPULimit indicates whether the VWA should avoid setting CPU limits on the managed resource.
 	// If set to true, only resource requests will be set, which may be beneficial in scenarios
@@ -86,14 +121,78 @@ type VerticalWorkloadAutoscalerSpec struct {
 	IgnoreMemoryRecommendations bool `json:"ignoreMemoryRecommendations,omitempty"`
 
 	// UpdateTolerance defines the tolerance for updates to resource requests.
-	// It accepts two optional subfields: cpu and memory. Both subfields should accept float values between 0 and 1.
-	// The default value for both cpu and memory is 0.10 (10%).
+	// It accepts two optional subfields: cpu and memory, both percentages between 0 and 100.
+	// Unset subfields are set by the defaulting webhook to the controller defaults (10% unless
+	// overridden with --default-cpu-tolerance and --default-memory-tolerance); 0 applies every change.
 	// +optional
 	UpdateTolerance *UpdateTolerance `json:"updateTolerance,omitempty"`
 
 	// CustomAnnotations holds a map of annotations that will be applied to the target object.
 	// +optional
 	CustomAnnotations map[string]string `json:"customAnnotations,omitempty"`
+
+	// GitOps configures the integration with the GitOps tool managing the target workload.
+	// The VWA reports the tool object managing the workload in status.gitOpsOwner; for Argo CD it includes the
+	// ignoreDifferences entry scoped to the fields owned by the VWA. The VWA doesn't change how the tool syncs.
+	// +optional
+	GitOps *GitOpsIntegration `json:"gitOps,omitempty"`
+
+	// DeletionPolicy decides what happens to the target workload resources when the VWA is deleted:
+	// Retain keeps the last applied resources, Restore rolls them back to the original ones (default: Retain).
+	// The VWA annotations are removed from the target workload in both cases.
+	// +kubebuilder:default=Retain
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Suspend stops all updates of the target workload while the VWA keeps tracking the recommendations.
+	// Setting the suspend annotation of the VWA to "true" has the same effect.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// ApplyMethod decides how the resources are applied: by updating the pod template of the target workload
+	// (Template) or by setting them on new pods with the pod mutating webhook, leaving the workload unchanged (Admission).
+	// +kubebuilder:default=Template
+	// +optional
+	ApplyMethod ApplyMethod `json:"applyMethod,omitempty"`
+
+	// UpdateMode decides when the target workload is updated: on every recommendation change (Auto), with the
+	// first recommendation only (Once) or when the pod template of the workload is changed by someone else (OnRollout).
+	// +kubebuilder:default=Auto
+	// +optional
+	UpdateMode UpdateMode `json:"updateMode,omitempty"`
+
+	// Approval decides whether resource changes are applied automatically or proposed in status.pendingProposal
+	// and applied once approved with the approve annotation set to the proposal hash (default: Automatic).
+	// +kubebuilder:default=Automatic
+	// +optional
+	Approval ApprovalMode `json:"approval,omitempty"`
+
+	// RevisionHistoryLimit is the number of resource changes kept in status.revisions (default: 10).
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackTo is a revision of status.revisions to roll the target workload resources back to.
+	// While set, the VWA keeps the resources of the revision and pauses automatic updates;
+	// unset it to resume them.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RollbackTo *int64 `json:"rollbackTo,omitempty"`
+
+	// GitWriteback makes the VWA commit the recommended resources to a Git repository instead of
+	// updating the target workload; the GitOps tool managing the workload then syncs them to the cluster.
+	// +optional
+	GitWriteback *GitWriteback `json:"gitWriteback,omitempty"`
+
+	// DriftPolicy defines how the VWA reacts when another field manager, like a GitOps tool,
+	// reverts the resources it applied. Unset subfields are set by the defaulting webhook.
+	// +optional
+	DriftPolicy *DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// HPAPolicy defines how the VWA coordinates with the HorizontalPodAutoscaler scaling the target workload.
+	// +optional
+	HPAPolicy *HPAPolicy `json:"hpaPolicy,omitempty"`
 }
 
 // VPAReference defines the reference to the VerticalPodAutoscaler
@@ -106,6 +205,19 @@ type HPAReference struct {
 	Name string `json:"name"`
 }
 
+// UpdateDirection defines which resource changes an update window applies to
+// +kubebuilder:validation:Enum=Both;Increase;Decrease
+type UpdateDirection string
+
+const (
+	// UpdateDirectionBoth applies the update window to resource increases and decreases
+	UpdateDirectionBoth UpdateDirection = "Both"
+	// UpdateDirectionIncrease applies the update window to resource increases only
+	UpdateDirectionIncrease UpdateDirection = "Increase"
+	// UpdateDirectionDecrease applies the update window to resource decreases only
+	UpdateDirectionDecrease UpdateDirection = "Decrease"
+)
+
 // UpdateWindow defines a time window for allowed updates
 type UpdateWindow struct {
 	// DayOfWeek represents the day of the week for the update window.
@@ -122,9 +234,418 @@ type UpdateWindow struct {
 	// +kubebuilder:validation:required
 	EndTime string `json:"endTime"`
 
-	// TimeZone represents the time zone in IANA format, like "UTC" or "America/New_York"
-	// +kubebuilder:validation:Pattern="^[A-Za-z]+/[A-Za-z_]+$"
+	// TimeZone represents the time zone in IANA format, like "UTC", "America/New_York" or
+	// "America/Argentina/Buenos_Aires"; unknown zones are rejected by the validating webhook
+	// +kubebuilder:validation:Pattern="^[A-Za-z][A-Za-z0-9_+-]*(/[A-Za-z0-9_+-]+)*$"
 	TimeZone string `json:"timeZone"`
+
+	// Direction limits the update window to resource increases or decreases.
+	// Changes in a direction without any matching update window are applied immediately,
+	// e.g. with only "Decrease" windows, increases are never delayed.
+	// The default is "Both".
+	// +kubebuilder:default=Both
+	// +optional
+	Direction UpdateDirection `json:"direction,omitempty"`
+}
+
+// BlackoutWindow defines an absolute time range during which updates are not allowed
+type BlackoutWindow struct {
+	// Name identifies the blackout window, e.g. "Black Friday"
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Start represents the beginning of the blackout window (RFC 3339)
+	// +kubebuilder:validation:required
+	Start metav1.Time `json:"start"`
+
+	// End represents the end of the blackout window (RFC 3339)
+	// +kubebuilder:validation:required
+	End metav1.Time `json:"end"`
+}
+
+// DefaultCalendarKey is the ConfigMap key used when a CalendarReference doesn't specify one
+const DefaultCalendarKey = "calendar.ics"
+
+// CalendarReference defines a reference to an iCalendar file stored in a ConfigMap
+type CalendarReference struct {
+	// Name of the ConfigMap holding the iCalendar data
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap; only used by ClusterUpdateSchedule calendars,
+	// other calendars are always read from the namespace of the referencing object
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key of the ConfigMap entry holding the iCalendar data (default: calendar.ics)
+	// +kubebuilder:default="calendar.ics"
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// DriftPolicy defines how the VWA reacts to reverts of the resources it applied
+type DriftPolicy struct {
+	// MaxReverts is the number of reverts within the period after which the VWA stops re-applying
+	// its resources and reports a GitOpsConflict (default: 3).
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxReverts *int32 `json:"maxReverts,omitempty"`
+
+	// Period is the time window reverts are counted in (default: 1h).
+	// The VWA resumes re-applying once fewer than MaxReverts reverts happened within the period.
+	// +optional
+	Period *metav1.Duration `json:"period,omitempty"`
+}
+
+// GitOpsTool is a GitOps tool the VWA integrates with
+// +kubebuilder:validation:Enum=ArgoCD;Flux
+type GitOpsTool string
+
+const (
+	// GitOpsToolArgoCD is Argo CD
+	GitOpsToolArgoCD GitOpsTool = "ArgoCD"
+	// GitOpsToolFlux is Flux
+	GitOpsToolFlux GitOpsTool = "Flux"
+)
+
+// GitOpsIntegration configures the integration with the GitOps tool managing the target workload
+type GitOpsIntegration struct {
+	// Tool is the GitOps tool managing the target workload.
+	// +kubebuilder:validation:required
+	Tool GitOpsTool `json:"tool"`
+}
+
+// GitOpsOwner references the GitOps object managing the target workload
+type GitOpsOwner struct {
+	// Tool is the GitOps tool of the object.
+	Tool GitOpsTool `json:"tool"`
+
+	// Kind of the object: Application, Kustomization or HelmRelease.
+	Kind string `json:"kind"`
+
+	// Name of the object.
+	Name string `json:"name"`
+
+	// Namespace of the object, if known.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// IgnoreDifferences is the entry to add to the ignoreDifferences of the Argo CD Application, together with the
+	// RespectIgnoreDifferences=true sync option, so Argo CD ignores and keeps only the fields owned by the VWA.
+	// +optional
+	IgnoreDifferences *ArgoCDIgnoreDifferences `json:"ignoreDifferences,omitempty"`
+}
+
+// ArgoCDIgnoreDifferences is an ignoreDifferences entry of an Argo CD Application
+type ArgoCDIgnoreDifferences struct {
+	// Group of the ignored resource.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind of the ignored resource.
+	Kind string `json:"kind"`
+
+	// Name of the ignored resource.
+	Name string `json:"name"`
+
+	// Namespace of the ignored resource.
+	Namespace string `json:"namespace"`
+
+	// ManagedFieldsManagers are the field managers whose fields are ignored.
+	ManagedFieldsManagers []string `json:"managedFieldsManagers"`
+}
+
+// HPAPolicy defines how the VWA coordinates with the HorizontalPodAutoscaler scaling the target workload
+type HPAPolicy struct {
+	// CustomMetrics decides which recommendations are ignored while the HPA scales on Pods, Object or External
+	// metrics, since vertical changes change the throughput per pod: none (Allow), the CPU ones (IgnoreCPU)
+	// or all of them (IgnoreAll) (default: Allow).
+	// +kubebuilder:default=Allow
+	// +optional
+	CustomMetrics CustomMetricsPolicy `json:"customMetrics,omitempty"`
+
+	// CoordinateTargets makes the VWA apply the recommendations of the resources the HPA scales on, and scale
+	// the averageUtilization targets of the HPA with the requests, so the usage per pod the HPA scales at stays
+	// the same. The HPAs of KEDA ScaledObjects are left unchanged.
+	// +optional
+	CoordinateTargets bool `json:"coordinateTargets,omitempty"`
+
+	// SaturationScaleUp makes the VWA apply the CPU increases it otherwise ignores for the HPA while the HPA is
+	// at its maxReplicas with its metrics over their targets, until the HPA scales below maxReplicas.
+	// +optional
+	SaturationScaleUp *SaturationScaleUp `json:"saturationScaleUp,omitempty"`
+}
+
+// SaturationScaleUp defines the vertical scale-up of a workload whose HPA can't add more replicas
+type SaturationScaleUp struct {
+	// After is how long the HPA must stay saturated before the CPU increases are applied (default: 5m).
+	// +optional
+	After *metav1.Duration `json:"after,omitempty"`
+
+	// MaxCPU caps the CPU requests and limits of each container raised while the HPA is saturated; the
+	// recommendations are already bounded by the VPA resource policy.
+	// +optional
+	MaxCPU *resource.Quantity `json:"maxCPU,omitempty"`
+}
+
+// CustomMetricsPolicy is the handling of the recommendations while the HPA scales on custom metrics
+// +kubebuilder:validation:Enum=Allow;IgnoreCPU;IgnoreAll
+type CustomMetricsPolicy string
+
+const (
+	// CustomMetricsAllow keeps applying all the recommendations
+	CustomMetricsAllow CustomMetricsPolicy = "Allow"
+	// CustomMetricsIgnoreCPU ignores the CPU recommendations
+	CustomMetricsIgnoreCPU CustomMetricsPolicy = "IgnoreCPU"
+	// CustomMetricsIgnoreAll ignores the CPU and memory recommendations
+	CustomMetricsIgnoreAll CustomMetricsPolicy = "IgnoreAll"
+)
+
+// HPACoordination describes the HPA utilization targets scaled by the VWA
+type HPACoordination struct {
+	// HPA is the name of the HorizontalPodAutoscaler.
+	HPA string `json:"hpa"`
+
+	// Targets lists the coordinated utilization targets.
+	// +optional
+	Targets []HPATarget `json:"targets,omitempty"`
+
+	// UpdatedAt is the time the VWA last changed the HPA.
+	// +optional
+	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
+}
+
+// HPASaturation describes an HPA at its maxReplicas with its metrics over their targets
+type HPASaturation struct {
+	// HPA is the name of the HorizontalPodAutoscaler.
+	HPA string `json:"hpa"`
+
+	// Metrics lists the metrics over their targets, e.g. cpu or app/cpu.
+	// +optional
+	Metrics []string `json:"metrics,omitempty"`
+
+	// Since is the time the HPA was first observed saturated.
+	Since metav1.Time `json:"since"`
+
+	// ScaleUp is set once the HPA has been saturated for spec.hpaPolicy.saturationScaleUp.after, while the
+	// VWA applies the CPU increases.
+	// +optional
+	ScaleUp bool `json:"scaleUp,omitempty"`
+}
+
+// HPATarget describes a utilization target of an HPA resource metric scaled by the VWA
+type HPATarget struct {
+	// Metric is the resource of the metric, prefixed with the container name for ContainerResource metrics,
+	// e.g. cpu or app/cpu.
+	Metric string `json:"metric"`
+
+	// Threshold is the usage per pod, or per container, the HPA scales at.
+	Threshold resource.Quantity `json:"threshold"`
+
+	// AverageUtilization is the utilization target set by the VWA, in percent of the requests.
+	AverageUtilization int32 `json:"averageUtilization"`
+}
+
+// EffectiveIgnore describes the resource recommendations the VWA ignores
+type EffectiveIgnore struct {
+	// CPU indicates whether the CPU recommendations are ignored.
+	// +optional
+	CPU bool `json:"cpu,omitempty"`
+
+	// Memory indicates whether the memory recommendations are ignored.
+	// +optional
+	Memory bool `json:"memory,omitempty"`
+
+	// Containers lists the containers with recommendations ignored for HPA ContainerResource metrics;
+	// the recommendations of the other containers are applied.
+	// +optional
+	Containers []ContainerIgnore `json:"containers,omitempty"`
+
+	// Source lists the HorizontalPodAutoscaler and KEDA ScaledObjects or ScaledJob scaling the target workload
+	// on the ignored resources, separated by commas; it's empty when the recommendations are ignored by the VWA
+	// spec only.
+	// +optional
+	Source string `json:"source,omitempty"`
+}
+
+// ContainerIgnore describes the resource recommendations ignored for a container
+type ContainerIgnore struct {
+	// Name of the container.
+	Name string `json:"name"`
+
+	// CPU indicates whether the CPU recommendations of the container are ignored.
+	// +optional
+	CPU bool `json:"cpu,omitempty"`
+
+	// Memory indicates whether the memory recommendations of the container are ignored.
+	// +optional
+	Memory bool `json:"memory,omitempty"`
+}
+
+// DeletionPolicy is the handling of the target workload resources on VWA deletion
+// +kubebuilder:validation:Enum=Retain;Restore
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyRetain keeps the resources last applied by the VWA
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyRestore restores the resources the target workload had before the VWA modified it
+	DeletionPolicyRestore DeletionPolicy = "Restore"
+)
+
+// GitWritebackFormat is the format of the file the VWA writes to Git
+// +kubebuilder:validation:Enum=KustomizePatch;HelmValues
+type GitWritebackFormat string
+
+const (
+	// GitWritebackFormatKustomizePatch is a kustomize strategic merge patch of the target workload
+	GitWritebackFormatKustomizePatch GitWritebackFormat = "KustomizePatch"
+	// GitWritebackFormatHelmValues is a Helm values snippet
+	GitWritebackFormatHelmValues GitWritebackFormat = "HelmValues"
+)
+
+// GitWriteback defines the Git repository file the recommended resources are written to
+type GitWriteback struct {
+	// Repository is the URL of the Git repository, e.g. https://github.com/org/deploy.git or git@github.com:org/deploy.git.
+	// Only https://, ssh:// and scp-like user@host:path URLs are allowed.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern="^(https://|ssh://|[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:).+$"
+	Repository string `json:"repository"`
+
+	// Branch is the existing branch to commit to (default: main).
+	// +kubebuilder:default=main
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// Path is a Go template of the file path in the repository. It may use the target workload
+	// {{ .Namespace }}, {{ .Kind }} and {{ .Name }} and the VWA name {{ .VWA }},
+	// e.g. "apps/{{ .Namespace }}/{{ .Name }}/resources-patch.yaml".
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Format of the file: a kustomize strategic merge patch or a Helm values snippet (default: KustomizePatch).
+	// +kubebuilder:default=KustomizePatch
+	// +optional
+	Format GitWritebackFormat `json:"format,omitempty"`
+
+	// ValuesKey is a Go template of the dot separated Helm values key holding the resources of a container,
+	// e.g. "{{ .Container }}.resources" (default: resources). Only used by the HelmValues format.
+	// +optional
+	ValuesKey string `json:"valuesKey,omitempty"`
+
+	// SecretRef references a Secret in the VWA namespace with the repository credentials:
+	// username and password for HTTPS, or identity (a private key) and known_hosts for SSH.
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// GitWritebackStatus describes the last commit of the recommended resources to Git
+type GitWritebackStatus struct {
+	// CommitSHA is the commit holding the recommended resources.
+	CommitSHA string `json:"commitSHA"`
+
+	// Path is the file written in the repository.
+	Path string `json:"path"`
+
+	// CommittedAt is the time the resources were committed.
+	CommittedAt metav1.Time `json:"committedAt"`
+
+	// Resources maps container names to the committed resource requirements.
+	// +optional
+	Resources map[string]corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Observed indicates whether the committed resources have since been observed on the target workload.
+	Observed bool `json:"observed"`
+}
+
+// ApplyMethod decides how the resources are applied to the target workload
+// +kubebuilder:validation:Enum=Template;Admission
+type ApplyMethod string
+
+const (
+	// ApplyMethodTemplate updates the container resources in the pod template of the target workload
+	ApplyMethodTemplate ApplyMethod = "Template"
+	// ApplyMethodAdmission sets the container resources on new pods of the target workload with the pod mutating webhook
+	ApplyMethodAdmission ApplyMethod = "Admission"
+)
+
+// UpdateMode decides when the target workload is updated
+// +kubebuilder:validation:Enum=Auto;Once;OnRollout
+type UpdateMode string
+
+const (
+	// UpdateModeAuto updates the target workload whenever the recommendations change
+	UpdateModeAuto UpdateMode = "Auto"
+	// UpdateModeOnce applies the first recommendation and then stops updating the target workload
+	UpdateModeOnce UpdateMode = "Once"
+	// UpdateModeOnRollout applies the latest recommendation only when the pod template is changed by someone else
+	UpdateModeOnRollout UpdateMode = "OnRollout"
+)
+
+// ApprovalMode decides how resource changes are approved
+// +kubebuilder:validation:Enum=Automatic;Manual
+type ApprovalMode string
+
+const (
+	// ApprovalAutomatic applies resource changes without approval
+	ApprovalAutomatic ApprovalMode = "Automatic"
+	// ApprovalManual applies resource changes once approved with the approve annotation
+	ApprovalManual ApprovalMode = "Manual"
+)
+
+// Proposal is a resource change waiting for approval
+type Proposal struct {
+	// Hash identifies the proposed resources; set the approve annotation of the VWA to it to approve the change.
+	Hash string `json:"hash"`
+
+	// ProposedAt is the time the change was proposed.
+	ProposedAt metav1.Time `json:"proposedAt"`
+
+	// Containers maps the names of the containers to change to their current and proposed resources.
+	Containers map[string]ContainerRevision `json:"containers"`
+}
+
+// RevisionReason is the cause of a resource change
+// +kubebuilder:validation:Enum=Recommendation;Rollback
+type RevisionReason string
+
+const (
+	// RevisionReasonRecommendation is a change applying the VPA recommendations
+	RevisionReasonRecommendation RevisionReason = "Recommendation"
+	// RevisionReasonRollback is a change rolling back to an earlier revision
+	RevisionReasonRollback RevisionReason = "Rollback"
+)
+
+// Revision records a change of the target workload resources made by the VWA
+type Revision struct {
+	// Revision is the sequence number of the change, starting at 1.
+	Revision int64 `json:"revision"`
+
+	// Timestamp is the time the change was made.
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// Reason is the cause of the change.
+	Reason RevisionReason `json:"reason"`
+
+	// Message describes the change, e.g. the revision rolled back to.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Containers maps the names of the changed containers to their resources before and after the change.
+	Containers map[string]ContainerRevision `json:"containers"`
+}
+
+// ContainerRevision holds the resources of a container before and after a change
+type ContainerRevision struct {
+	// Before are the resource requirements before the change.
+	Before corev1.ResourceRequirements `json:"before"`
+
+	// After are the resource requirements after the change.
+	After corev1.ResourceRequirements `json:"after"`
+
+	// Recommendation is the VPA target recommendation of the container at the time of the change.
+	// +optional
+	Recommendation corev1.ResourceList `json:"recommendation,omitempty"`
 }
 
 // VerticalWorkloadAutoscalerStatus defines the observed state of VerticalWorkloadAutoscaler
@@ -143,6 +664,15 @@ type VerticalWorkloadAutoscalerStatus struct {
 	// +optional
 	RecommendedRequests map[string]corev1.ResourceRequirements `json:"recommendedRequests,omitempty"`
 
+	// AppliedResources maps container names to the resource requirements last applied by the VWA,
+	// or last observed after a revert. Resources differing from them have drifted.
+	// +optional
+	AppliedResources map[string]corev1.ResourceRequirements `json:"appliedResources,omitempty"`
+
+	// Reverts lists the times the applied resources were reverted by another field manager within the drift period.
+	// +optional
+	Reverts []metav1.Time `json:"reverts,omitempty"`
+
 	// SkippedUpdates indicates whether updates were skipped during the last reconciliation.
 	// +optional
 	SkippedUpdates bool `json:"skippedUpdates,omitempty"`
@@ -151,6 +681,61 @@ type VerticalWorkloadAutoscalerStatus struct {
 	// +optional
 	SkipReason string `json:"skipReason,omitempty"`
 
+	// ActiveBlackout names the blackout window or calendar event that currently blocks updates.
+	// +optional
+	ActiveBlackout string `json:"activeBlackout,omitempty"`
+
+	// OriginalResources maps container names to their resource requirements before the VWA first modified them.
+	// They are restored on deletion with the Restore deletion policy.
+	// +optional
+	OriginalResources map[string]corev1.ResourceRequirements `json:"originalResources,omitempty"`
+
+	// Revisions lists the last resource changes of the VWA, oldest first, bounded by spec.revisionHistoryLimit.
+	// +optional
+	Revisions []Revision `json:"revisions,omitempty"`
+
+	// OutdatedPods is the number of live pods of the target workload not running the applied resources
+	// with the Admission apply method.
+	// +optional
+	OutdatedPods int32 `json:"outdatedPods,omitempty"`
+
+	// LastUpdateMode is the update mode that applied the last resource change.
+	// +optional
+	LastUpdateMode UpdateMode `json:"lastUpdateMode,omitempty"`
+
+	// ObservedTemplateHash is the hash of the target pod template, without container resources, last handled
+	// with the OnRollout update mode.
+	// +optional
+	ObservedTemplateHash string `json:"observedTemplateHash,omitempty"`
+
+	// PendingProposal is the resource change waiting for approval with the Manual approval mode.
+	// +optional
+	PendingProposal *Proposal `json:"pendingProposal,omitempty"`
+
+	// GitWriteback describes the last commit of the recommended resources to Git.
+	// +optional
+	GitWriteback *GitWritebackStatus `json:"gitWriteback,omitempty"`
+
+	// GitOpsOwner references the Argo CD Application or Flux Kustomization or HelmRelease managing the target
+	// workload, detected with the tool tracking labels and annotations.
+	// +optional
+	GitOpsOwner *GitOpsOwner `json:"gitOpsOwner,omitempty"`
+
+	// EffectiveIgnore is the resource recommendations ignored by the VWA, set by the VWA spec or by the
+	// HorizontalPodAutoscaler scaling the target workload on the resource utilization.
+	// +optional
+	EffectiveIgnore *EffectiveIgnore `json:"effectiveIgnore,omitempty"`
+
+	// HPACoordination describes the HPA utilization targets last scaled by the VWA with
+	// spec.hpaPolicy.coordinateTargets.
+	// +optional
+	HPACoordination *HPACoordination `json:"hpaCoordination,omitempty"`
+
+	// HPASaturation describes the HPA scaling the target workload at its maxReplicas, tracked with
+	// spec.hpaPolicy.saturationScaleUp.
+	// +optional
+	HPASaturation *HPASaturation `json:"hpaSaturation,omitempty"`
+
 	// UpdateCount represents the number of updates applied by the VWA.
 	// +optional
 	UpdateCount int32 `json:"updateCount,omitempty"`
@@ -181,18 +766,16 @@ type ResourceRequests struct {
 // UpdateTolerance defines the tolerance for updates to resource requests
 type UpdateTolerance struct {
 	// CPU tolerance for updates (as a percentage, default: 10%)
-	// +kubebuilder:default=10
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Maximum=100
 	// +optional
-	CPU int `json:"cpu,omitempty"`
+	CPU *int `json:"cpu,omitempty"`
 
 	// Memory tolerance for updates (as a percentage, default: 10%)
-	// +kubebuilder:default=10
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Maximum=100
 	// +optional
-	Memory int `json:"memory,omitempty"`
+	Memory *int `json:"memory,omitempty"`
 }
 
 // +kubebuilder:object:root=true
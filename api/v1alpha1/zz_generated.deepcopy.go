@@ -26,6 +26,203 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDIgnoreDifferences) DeepCopyInto(out *ArgoCDIgnoreDifferences) {
+	*out = *in
+	if in.ManagedFieldsManagers != nil {
+		in, out := &in.ManagedFieldsManagers, &out.ManagedFieldsManagers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDIgnoreDifferences.
+func (in *ArgoCDIgnoreDifferences) DeepCopy() *ArgoCDIgnoreDifferences {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDIgnoreDifferences)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlackoutWindow) DeepCopyInto(out *BlackoutWindow) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlackoutWindow.
+func (in *BlackoutWindow) DeepCopy() *BlackoutWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(BlackoutWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalendarReference) DeepCopyInto(out *CalendarReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalendarReference.
+func (in *CalendarReference) DeepCopy() *CalendarReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CalendarReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeFreeze) DeepCopyInto(out *ChangeFreeze) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeFreeze.
+func (in *ChangeFreeze) DeepCopy() *ChangeFreeze {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeFreeze)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ChangeFreeze) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeFreezeList) DeepCopyInto(out *ChangeFreezeList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ChangeFreeze, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeFreezeList.
+func (in *ChangeFreezeList) DeepCopy() *ChangeFreezeList {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeFreezeList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ChangeFreezeList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeFreezeSpec) DeepCopyInto(out *ChangeFreezeSpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeFreezeSpec.
+func (in *ChangeFreezeSpec) DeepCopy() *ChangeFreezeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeFreezeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterUpdateSchedule) DeepCopyInto(out *ClusterUpdateSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterUpdateSchedule.
+func (in *ClusterUpdateSchedule) DeepCopy() *ClusterUpdateSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterUpdateSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterUpdateSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterUpdateScheduleList) DeepCopyInto(out *ClusterUpdateScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterUpdateSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterUpdateScheduleList.
+func (in *ClusterUpdateScheduleList) DeepCopy() *ClusterUpdateScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterUpdateScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterUpdateScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Conflict) DeepCopyInto(out *Conflict) {
 	*out = *in
@@ -41,6 +238,214 @@ func (in *Conflict) DeepCopy() *Conflict {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerIgnore) DeepCopyInto(out *ContainerIgnore) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerIgnore.
+func (in *ContainerIgnore) DeepCopy() *ContainerIgnore {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerIgnore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerRevision) DeepCopyInto(out *ContainerRevision) {
+	*out = *in
+	in.Before.DeepCopyInto(&out.Before)
+	in.After.DeepCopyInto(&out.After)
+	if in.Recommendation != nil {
+		in, out := &in.Recommendation, &out.Recommendation
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerRevision.
+func (in *ContainerRevision) DeepCopy() *ContainerRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftPolicy) DeepCopyInto(out *DriftPolicy) {
+	*out = *in
+	if in.MaxReverts != nil {
+		in, out := &in.MaxReverts, &out.MaxReverts
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Period != nil {
+		in, out := &in.Period, &out.Period
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftPolicy.
+func (in *DriftPolicy) DeepCopy() *DriftPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EffectiveIgnore) DeepCopyInto(out *EffectiveIgnore) {
+	*out = *in
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]ContainerIgnore, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EffectiveIgnore.
+func (in *EffectiveIgnore) DeepCopy() *EffectiveIgnore {
+	if in == nil {
+		return nil
+	}
+	out := new(EffectiveIgnore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitOpsIntegration) DeepCopyInto(out *GitOpsIntegration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsIntegration.
+func (in *GitOpsIntegration) DeepCopy() *GitOpsIntegration {
+	if in == nil {
+		return nil
+	}
+	out := new(GitOpsIntegration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitOpsOwner) DeepCopyInto(out *GitOpsOwner) {
+	*out = *in
+	if in.IgnoreDifferences != nil {
+		in, out := &in.IgnoreDifferences, &out.IgnoreDifferences
+		*out = new(ArgoCDIgnoreDifferences)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsOwner.
+func (in *GitOpsOwner) DeepCopy() *GitOpsOwner {
+	if in == nil {
+		return nil
+	}
+	out := new(GitOpsOwner)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitWriteback) DeepCopyInto(out *GitWriteback) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitWriteback.
+func (in *GitWriteback) DeepCopy() *GitWriteback {
+	if in == nil {
+		return nil
+	}
+	out := new(GitWriteback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitWritebackStatus) DeepCopyInto(out *GitWritebackStatus) {
+	*out = *in
+	in.CommittedAt.DeepCopyInto(&out.CommittedAt)
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitWritebackStatus.
+func (in *GitWritebackStatus) DeepCopy() *GitWritebackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GitWritebackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HPACoordination) DeepCopyInto(out *HPACoordination) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]HPATarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UpdatedAt != nil {
+		in, out := &in.UpdatedAt, &out.UpdatedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPACoordination.
+func (in *HPACoordination) DeepCopy() *HPACoordination {
+	if in == nil {
+		return nil
+	}
+	out := new(HPACoordination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HPAPolicy) DeepCopyInto(out *HPAPolicy) {
+	*out = *in
+	if in.SaturationScaleUp != nil {
+		in, out := &in.SaturationScaleUp, &out.SaturationScaleUp
+		*out = new(SaturationScaleUp)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPAPolicy.
+func (in *HPAPolicy) DeepCopy() *HPAPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HPAPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HPAReference) DeepCopyInto(out *HPAReference) {
 	*out = *in
@@ -56,6 +461,66 @@ func (in *HPAReference) DeepCopy() *HPAReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HPASaturation) DeepCopyInto(out *HPASaturation) {
+	*out = *in
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Since.DeepCopyInto(&out.Since)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPASaturation.
+func (in *HPASaturation) DeepCopy() *HPASaturation {
+	if in == nil {
+		return nil
+	}
+	out := new(HPASaturation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HPATarget) DeepCopyInto(out *HPATarget) {
+	*out = *in
+	out.Threshold = in.Threshold.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPATarget.
+func (in *HPATarget) DeepCopy() *HPATarget {
+	if in == nil {
+		return nil
+	}
+	out := new(HPATarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Proposal) DeepCopyInto(out *Proposal) {
+	*out = *in
+	in.ProposedAt.DeepCopyInto(&out.ProposedAt)
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make(map[string]ContainerRevision, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Proposal.
+func (in *Proposal) DeepCopy() *Proposal {
+	if in == nil {
+		return nil
+	}
+	out := new(Proposal)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceRequests) DeepCopyInto(out *ResourceRequests) {
 	*out = *in
@@ -71,9 +536,172 @@ func (in *ResourceRequests) DeepCopy() *ResourceRequests {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Revision) DeepCopyInto(out *Revision) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make(map[string]ContainerRevision, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Revision.
+func (in *Revision) DeepCopy() *Revision {
+	if in == nil {
+		return nil
+	}
+	out := new(Revision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SaturationScaleUp) DeepCopyInto(out *SaturationScaleUp) {
+	*out = *in
+	if in.After != nil {
+		in, out := &in.After, &out.After
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxCPU != nil {
+		in, out := &in.MaxCPU, &out.MaxCPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SaturationScaleUp.
+func (in *SaturationScaleUp) DeepCopy() *SaturationScaleUp {
+	if in == nil {
+		return nil
+	}
+	out := new(SaturationScaleUp)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateSchedule) DeepCopyInto(out *UpdateSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateSchedule.
+func (in *UpdateSchedule) DeepCopy() *UpdateSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UpdateSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateScheduleList) DeepCopyInto(out *UpdateScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]UpdateSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateScheduleList.
+func (in *UpdateScheduleList) DeepCopy() *UpdateScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UpdateScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateScheduleReference) DeepCopyInto(out *UpdateScheduleReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateScheduleReference.
+func (in *UpdateScheduleReference) DeepCopy() *UpdateScheduleReference {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateScheduleReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateScheduleSpec) DeepCopyInto(out *UpdateScheduleSpec) {
+	*out = *in
+	if in.AllowedUpdateWindows != nil {
+		in, out := &in.AllowedUpdateWindows, &out.AllowedUpdateWindows
+		*out = make([]UpdateWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.BlackoutWindows != nil {
+		in, out := &in.BlackoutWindows, &out.BlackoutWindows
+		*out = make([]BlackoutWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BlackoutCalendars != nil {
+		in, out := &in.BlackoutCalendars, &out.BlackoutCalendars
+		*out = make([]CalendarReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateScheduleSpec.
+func (in *UpdateScheduleSpec) DeepCopy() *UpdateScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpdateTolerance) DeepCopyInto(out *UpdateTolerance) {
 	*out = *in
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		*out = new(int)
+		**out = **in
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		*out = new(int)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateTolerance.
@@ -189,10 +817,27 @@ func (in *VerticalWorkloadAutoscalerSpec) DeepCopyInto(out *VerticalWorkloadAuto
 		*out = make([]UpdateWindow, len(*in))
 		copy(*out, *in)
 	}
+	if in.BlackoutWindows != nil {
+		in, out := &in.BlackoutWindows, &out.BlackoutWindows
+		*out = make([]BlackoutWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BlackoutCalendars != nil {
+		in, out := &in.BlackoutCalendars, &out.BlackoutCalendars
+		*out = make([]CalendarReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.UpdateSchedules != nil {
+		in, out := &in.UpdateSchedules, &out.UpdateSchedules
+		*out = make([]UpdateScheduleReference, len(*in))
+		copy(*out, *in)
+	}
 	if in.UpdateTolerance != nil {
 		in, out := &in.UpdateTolerance, &out.UpdateTolerance
 		*out = new(UpdateTolerance)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.CustomAnnotations != nil {
 		in, out := &in.CustomAnnotations, &out.CustomAnnotations
@@ -201,6 +846,36 @@ func (in *VerticalWorkloadAutoscalerSpec) DeepCopyInto(out *VerticalWorkloadAuto
 			(*out)[key] = val
 		}
 	}
+	if in.GitOps != nil {
+		in, out := &in.GitOps, &out.GitOps
+		*out = new(GitOpsIntegration)
+		**out = **in
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RollbackTo != nil {
+		in, out := &in.RollbackTo, &out.RollbackTo
+		*out = new(int64)
+		**out = **in
+	}
+	if in.GitWriteback != nil {
+		in, out := &in.GitWriteback, &out.GitWriteback
+		*out = new(GitWriteback)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DriftPolicy != nil {
+		in, out := &in.DriftPolicy, &out.DriftPolicy
+		*out = new(DriftPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HPAPolicy != nil {
+		in, out := &in.HPAPolicy, &out.HPAPolicy
+		*out = new(HPAPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerticalWorkloadAutoscalerSpec.
@@ -228,6 +903,64 @@ func (in *VerticalWorkloadAutoscalerStatus) DeepCopyInto(out *VerticalWorkloadAu
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.AppliedResources != nil {
+		in, out := &in.AppliedResources, &out.AppliedResources
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Reverts != nil {
+		in, out := &in.Reverts, &out.Reverts
+		*out = make([]v1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.OriginalResources != nil {
+		in, out := &in.OriginalResources, &out.OriginalResources
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Revisions != nil {
+		in, out := &in.Revisions, &out.Revisions
+		*out = make([]Revision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PendingProposal != nil {
+		in, out := &in.PendingProposal, &out.PendingProposal
+		*out = new(Proposal)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GitWriteback != nil {
+		in, out := &in.GitWriteback, &out.GitWriteback
+		*out = new(GitWritebackStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GitOpsOwner != nil {
+		in, out := &in.GitOpsOwner, &out.GitOpsOwner
+		*out = new(GitOpsOwner)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EffectiveIgnore != nil {
+		in, out := &in.EffectiveIgnore, &out.EffectiveIgnore
+		*out = new(EffectiveIgnore)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HPACoordination != nil {
+		in, out := &in.HPACoordination, &out.HPACoordination
+		*out = new(HPACoordination)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HPASaturation != nil {
+		in, out := &in.HPASaturation, &out.HPASaturation
+		*out = new(HPASaturation)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
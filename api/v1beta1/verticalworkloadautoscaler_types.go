@@ -0,0 +1,786 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// QualityOfServiceClass defines the quality of service class
+// Only Burstable and Guaranteed are supported
+// +kubebuilder:validation:Enum=Burstable;Guaranteed
+type QualityOfServiceClass string
+
+const (
+	// BurstableQualityOfService is the burstable quality of service class
+	BurstableQualityOfService QualityOfServiceClass = "Burstable"
+	// GuaranteedQualityOfService is the guaranteed quality of service class
+	GuaranteedQualityOfService QualityOfServiceClass = "Guaranteed"
+)
+
+// VerticalWorkloadAutoscalerSpec defines the desired state of VerticalWorkloadAutoscaler
+type VerticalWorkloadAutoscalerSpec struct {
+	// VPARef references the VerticalPodAutoscaler providing the resource recommendations.
+	VPARef VPAReference `json:"vpaRef"`
+
+	// TargetRef pins the workload managed by the VWA. If set, the VWA only updates this workload
+	// and reports an error when the VPA targets another one. If not set, the VPA target is used.
+	// +optional
+	TargetRef *autoscalingv2.CrossVersionObjectReference `json:"targetRef,omitempty"`
+
+	// UpdatePolicy defines when resource changes are applied.
+	// +optional
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+
+	// ResourcePolicy defines which resources are managed and how they are calculated.
+	// +optional
+	ResourcePolicy ResourcePolicy `json:"resourcePolicy,omitempty"`
+
+	// CustomAnnotations holds a map of annotations that will be applied to the target object.
+	// +optional
+	CustomAnnotations map[string]string `json:"customAnnotations,omitempty"`
+
+	// GitOps configures the integration with the GitOps tool managing the target workload.
+	// The VWA reports the tool object managing the workload in status.gitOpsOwner; for Argo CD it includes the
+	// ignoreDifferences entry scoped to the fields owned by the VWA. The VWA doesn't change how the tool syncs.
+	// +optional
+	GitOps *GitOpsIntegration `json:"gitOps,omitempty"`
+
+	// DeletionPolicy decides what happens to the target workload resources when the VWA is deleted:
+	// Retain keeps the last applied resources, Restore rolls them back to the original ones (default: Retain).
+	// The VWA annotations are removed from the target workload in both cases.
+	// +kubebuilder:default=Retain
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Suspend stops all updates of the target workload while the VWA keeps tracking the recommendations.
+	// Setting the suspend annotation of the VWA to "true" has the same effect.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// ApplyMethod decides how the resources are applied: by updating the pod template of the target workload
+	// (Template) or by setting them on new pods with the pod mutating webhook, leaving the workload unchanged (Admission).
+	// +kubebuilder:default=Template
+	// +optional
+	ApplyMethod ApplyMethod `json:"applyMethod,omitempty"`
+
+	// UpdateMode decides when the target workload is updated: on every recommendation change (Auto), with the
+	// first recommendation only (Once) or when the pod template of the workload is changed by someone else (OnRollout).
+	// +kubebuilder:default=Auto
+	// +optional
+	UpdateMode UpdateMode `json:"updateMode,omitempty"`
+
+	// Approval decides whether resource changes are applied automatically or proposed in status.pendingProposal
+	// and applied once approved with the approve annotation set to the proposal hash (default: Automatic).
+	// +kubebuilder:default=Automatic
+	// +optional
+	Approval ApprovalMode `json:"approval,omitempty"`
+
+	// RevisionHistoryLimit is the number of resource changes kept in status.revisions (default: 10).
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackTo is a revision of status.revisions to roll the target workload resources back to.
+	// While set, the VWA keeps the resources of the revision and pauses automatic updates;
+	// unset it to resume them.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RollbackTo *int64 `json:"rollbackTo,omitempty"`
+
+	// GitWriteback makes the VWA commit the recommended resources to a Git repository instead of
+	// updating the target workload; the GitOps tool managing the workload then syncs them to the cluster.
+	// +optional
+	GitWriteback *GitWriteback `json:"gitWriteback,omitempty"`
+
+	// HPAPolicy defines how the VWA coordinates with the HorizontalPodAutoscaler scaling the target workload.
+	// +optional
+	HPAPolicy *HPAPolicy `json:"hpaPolicy,omitempty"`
+}
+
+// VPAReference defines the reference to a VerticalPodAutoscaler in the VWA namespace
+type VPAReference struct {
+	// Name of the VerticalPodAutoscaler
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// UpdatePolicy defines when resource changes are applied
+type UpdatePolicy struct {
+	// Frequency specifies how often the VWA applies updates to resource requests, e.g. "30m".
+	// If not set, the controller default is used.
+	// +optional
+	Frequency *metav1.Duration `json:"frequency,omitempty"`
+
+	// AllowedWindows defines the time windows during which updates are permitted.
+	// If not set, updates are permitted at any time.
+	// +optional
+	AllowedWindows []UpdateWindow `json:"allowedWindows,omitempty"`
+
+	// BlackoutWindows defines absolute time ranges during which updates are not permitted.
+	// Blackout windows take precedence over AllowedWindows.
+	// +optional
+	BlackoutWindows []BlackoutWindow `json:"blackoutWindows,omitempty"`
+
+	// BlackoutCalendars references iCalendar (.ics) files stored in ConfigMaps in the VWA namespace.
+	// Every event of a referenced calendar is treated as a blackout window.
+	// +optional
+	BlackoutCalendars []CalendarReference `json:"blackoutCalendars,omitempty"`
+
+	// Schedules references shared UpdateSchedule or ClusterUpdateSchedule objects.
+	// Their update windows and blackouts are merged with the ones defined inline.
+	// +optional
+	Schedules []UpdateScheduleReference `json:"schedules,omitempty"`
+
+	// Drift defines how the VWA reacts when another field manager, like a GitOps tool,
+	// reverts the resources it applied.
+	// +optional
+	Drift *DriftPolicy `json:"drift,omitempty"`
+}
+
+// DriftPolicy defines how the VWA reacts to reverts of the resources it applied
+type DriftPolicy struct {
+	// MaxReverts is the number of reverts within the period after which the VWA stops re-applying
+	// its resources and reports a GitOpsConflict.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxReverts *int32 `json:"maxReverts,omitempty"`
+
+	// Period is the time window reverts are counted in.
+	// +optional
+	Period *metav1.Duration `json:"period,omitempty"`
+}
+
+// ResourcePolicy defines which resources are managed and how they are calculated
+type ResourcePolicy struct {
+	// QualityOfService defines the quality of service class applied to the managed resource.
+	// If not set, the controller default is used.
+	// +optional
+	QualityOfService QualityOfServiceClass `json:"qualityOfService,omitempty"`
+
+	// AvoidCPULimit indicates whether the VWA should avoid setting CPU limits on the managed resource.
+	// +kubebuilder:default=true
+	// +optional
+	AvoidCPULimit *bool `json:"avoidCPULimit,omitempty"`
+
+	// ControlledResources lists the resources whose recommendations are applied.
+	// If not set, both cpu and memory are controlled; an empty list controls none.
+	// +optional
+	ControlledResources *[]ControlledResource `json:"controlledResources,omitempty"`
+
+	// Tolerance defines the relative change below which recommendations are not applied.
+	// If not set, the controller defaults are used.
+	// +optional
+	Tolerance *Tolerance `json:"tolerance,omitempty"`
+}
+
+// ControlledResource is a resource whose recommendations are applied by the VWA
+// +kubebuilder:validation:Enum=cpu;memory
+type ControlledResource string
+
+const (
+	// ControlledResourceCPU controls the CPU requests and limits
+	ControlledResourceCPU ControlledResource = "cpu"
+	// ControlledResourceMemory controls the memory requests and limits
+	ControlledResourceMemory ControlledResource = "memory"
+)
+
+// Percentage is a whole percentage between 0 and 100
+// +kubebuilder:validation:Minimum=0
+// +kubebuilder:validation:Maximum=100
+type Percentage int32
+
+// Tolerance defines the relative change of a resource below which recommendations are not applied
+type Tolerance struct {
+	// CPU tolerance; 0 applies every change
+	// +optional
+	CPU *Percentage `json:"cpu,omitempty"`
+
+	// Memory tolerance; 0 applies every change
+	// +optional
+	Memory *Percentage `json:"memory,omitempty"`
+}
+
+// UpdateDirection defines which resource changes an update window applies to
+// +kubebuilder:validation:Enum=Both;Increase;Decrease
+type UpdateDirection string
+
+const (
+	// UpdateDirectionBoth applies the update window to resource increases and decreases
+	UpdateDirectionBoth UpdateDirection = "Both"
+	// UpdateDirectionIncrease applies the update window to resource increases only
+	UpdateDirectionIncrease UpdateDirection = "Increase"
+	// UpdateDirectionDecrease applies the update window to resource decreases only
+	UpdateDirectionDecrease UpdateDirection = "Decrease"
+)
+
+// UpdateWindow defines a time window for allowed updates
+type UpdateWindow struct {
+	// DayOfWeek represents the day of the week for the update window.
+	// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+	DayOfWeek string `json:"dayOfWeek"`
+
+	// StartTime represents the start of the update window
+	// +kubebuilder:validation:Pattern="^([01]?[0-9]|2[0-3]):[0-5][0-9]$"
+	StartTime string `json:"startTime"`
+
+	// EndTime represents the end of the update window
+	// +kubebuilder:validation:Pattern="^([01]?[0-9]|2[0-3]):[0-5][0-9]$"
+	EndTime string `json:"endTime"`
+
+	// TimeZone represents the time zone in IANA format, like "UTC" or "America/New_York"
+	// +kubebuilder:validation:Pattern="^[A-Za-z][A-Za-z0-9_+-]*(/[A-Za-z0-9_+-]+)*$"
+	TimeZone string `json:"timeZone"`
+
+	// Direction limits the update window to resource increases or decreases.
+	// +kubebuilder:default=Both
+	// +optional
+	Direction UpdateDirection `json:"direction,omitempty"`
+}
+
+// BlackoutWindow defines an absolute time range during which updates are not allowed
+type BlackoutWindow struct {
+	// Name identifies the blackout window, e.g. "Black Friday"
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Start represents the beginning of the blackout window (RFC 3339)
+	Start metav1.Time `json:"start"`
+
+	// End represents the end of the blackout window (RFC 3339)
+	End metav1.Time `json:"end"`
+}
+
+// CalendarReference defines a reference to an iCalendar file stored in a ConfigMap in the VWA namespace
+type CalendarReference struct {
+	// Name of the ConfigMap holding the iCalendar data
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap; ignored for VWA calendars
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key of the ConfigMap entry holding the iCalendar data (default: calendar.ics)
+	// +kubebuilder:default="calendar.ics"
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// UpdateScheduleReference defines a reference to a shared update schedule
+type UpdateScheduleReference struct {
+	// Kind of the referenced schedule: UpdateSchedule (same namespace) or ClusterUpdateSchedule
+	// +kubebuilder:validation:Enum=UpdateSchedule;ClusterUpdateSchedule
+	// +kubebuilder:default=UpdateSchedule
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the referenced schedule
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// GitOpsTool is a GitOps tool the VWA integrates with
+// +kubebuilder:validation:Enum=ArgoCD;Flux
+type GitOpsTool string
+
+const (
+	// GitOpsToolArgoCD is Argo CD
+	GitOpsToolArgoCD GitOpsTool = "ArgoCD"
+	// GitOpsToolFlux is Flux
+	GitOpsToolFlux GitOpsTool = "Flux"
+)
+
+// GitOpsIntegration configures the integration with the GitOps tool managing the target workload
+type GitOpsIntegration struct {
+	// Tool is the GitOps tool managing the target workload.
+	// +kubebuilder:validation:required
+	Tool GitOpsTool `json:"tool"`
+}
+
+// GitOpsOwner references the GitOps object managing the target workload
+type GitOpsOwner struct {
+	// Tool is the GitOps tool of the object.
+	Tool GitOpsTool `json:"tool"`
+
+	// Kind of the object: Application, Kustomization or HelmRelease.
+	Kind string `json:"kind"`
+
+	// Name of the object.
+	Name string `json:"name"`
+
+	// Namespace of the object, if known.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// IgnoreDifferences is the entry to add to the ignoreDifferences of the Argo CD Application, together with the
+	// RespectIgnoreDifferences=true sync option, so Argo CD ignores and keeps only the fields owned by the VWA.
+	// +optional
+	IgnoreDifferences *ArgoCDIgnoreDifferences `json:"ignoreDifferences,omitempty"`
+}
+
+// ArgoCDIgnoreDifferences is an ignoreDifferences entry of an Argo CD Application
+type ArgoCDIgnoreDifferences struct {
+	// Group of the ignored resource.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind of the ignored resource.
+	Kind string `json:"kind"`
+
+	// Name of the ignored resource.
+	Name string `json:"name"`
+
+	// Namespace of the ignored resource.
+	Namespace string `json:"namespace"`
+
+	// ManagedFieldsManagers are the field managers whose fields are ignored.
+	ManagedFieldsManagers []string `json:"managedFieldsManagers"`
+}
+
+// HPAPolicy defines how the VWA coordinates with the HorizontalPodAutoscaler scaling the target workload
+type HPAPolicy struct {
+	// CustomMetrics decides which recommendations are ignored while the HPA scales on Pods, Object or External
+	// metrics, since vertical changes change the throughput per pod: none (Allow), the CPU ones (IgnoreCPU)
+	// or all of them (IgnoreAll) (default: Allow).
+	// +kubebuilder:default=Allow
+	// +optional
+	CustomMetrics CustomMetricsPolicy `json:"customMetrics,omitempty"`
+
+	// CoordinateTargets makes the VWA apply the recommendations of the resources the HPA scales on, and scale
+	// the averageUtilization targets of the HPA with the requests, so the usage per pod the HPA scales at stays
+	// the same. The HPAs of KEDA ScaledObjects are left unchanged.
+	// +optional
+	CoordinateTargets bool `json:"coordinateTargets,omitempty"`
+
+	// SaturationScaleUp makes the VWA apply the CPU increases it otherwise ignores for the HPA while the HPA is
+	// at its maxReplicas with its metrics over their targets, until the HPA scales below maxReplicas.
+	// +optional
+	SaturationScaleUp *SaturationScaleUp `json:"saturationScaleUp,omitempty"`
+}
+
+// SaturationScaleUp defines the vertical scale-up of a workload whose HPA can't add more replicas
+type SaturationScaleUp struct {
+	// After is how long the HPA must stay saturated before the CPU increases are applied (default: 5m).
+	// +optional
+	After *metav1.Duration `json:"after,omitempty"`
+
+	// MaxCPU caps the CPU requests and limits of each container raised while the HPA is saturated; the
+	// recommendations are already bounded by the VPA resource policy.
+	// +optional
+	MaxCPU *resource.Quantity `json:"maxCPU,omitempty"`
+}
+
+// CustomMetricsPolicy is the handling of the recommendations while the HPA scales on custom metrics
+// +kubebuilder:validation:Enum=Allow;IgnoreCPU;IgnoreAll
+type CustomMetricsPolicy string
+
+const (
+	// CustomMetricsAllow keeps applying all the recommendations
+	CustomMetricsAllow CustomMetricsPolicy = "Allow"
+	// CustomMetricsIgnoreCPU ignores the CPU recommendations
+	CustomMetricsIgnoreCPU CustomMetricsPolicy = "IgnoreCPU"
+	// CustomMetricsIgnoreAll ignores the CPU and memory recommendations
+	CustomMetricsIgnoreAll CustomMetricsPolicy = "IgnoreAll"
+)
+
+// HPACoordination describes the HPA utilization targets scaled by the VWA
+type HPACoordination struct {
+	// HPA is the name of the HorizontalPodAutoscaler.
+	HPA string `json:"hpa"`
+
+	// Targets lists the coordinated utilization targets.
+	// +optional
+	Targets []HPATarget `json:"targets,omitempty"`
+
+	// UpdatedAt is the time the VWA last changed the HPA.
+	// +optional
+	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
+}
+
+// HPASaturation describes an HPA at its maxReplicas with its metrics over their targets
+type HPASaturation struct {
+	// HPA is the name of the HorizontalPodAutoscaler.
+	HPA string `json:"hpa"`
+
+	// Metrics lists the metrics over their targets, e.g. cpu or app/cpu.
+	// +optional
+	Metrics []string `json:"metrics,omitempty"`
+
+	// Since is the time the HPA was first observed saturated.
+	Since metav1.Time `json:"since"`
+
+	// ScaleUp is set once the HPA has been saturated for spec.hpaPolicy.saturationScaleUp.after, while the
+	// VWA applies the CPU increases.
+	// +optional
+	ScaleUp bool `json:"scaleUp,omitempty"`
+}
+
+// HPATarget describes a utilization target of an HPA resource metric scaled by the VWA
+type HPATarget struct {
+	// Metric is the resource of the metric, prefixed with the container name for ContainerResource metrics,
+	// e.g. cpu or app/cpu.
+	Metric string `json:"metric"`
+
+	// Threshold is the usage per pod, or per container, the HPA scales at.
+	Threshold resource.Quantity `json:"threshold"`
+
+	// AverageUtilization is the utilization target set by the VWA, in percent of the requests.
+	AverageUtilization int32 `json:"averageUtilization"`
+}
+
+// EffectiveIgnore describes the resource recommendations the VWA ignores
+type EffectiveIgnore struct {
+	// CPU indicates whether the CPU recommendations are ignored.
+	// +optional
+	CPU bool `json:"cpu,omitempty"`
+
+	// Memory indicates whether the memory recommendations are ignored.
+	// +optional
+	Memory bool `json:"memory,omitempty"`
+
+	// Containers lists the containers with recommendations ignored for HPA ContainerResource metrics;
+	// the recommendations of the other containers are applied.
+	// +optional
+	Containers []ContainerIgnore `json:"containers,omitempty"`
+
+	// Source lists the HorizontalPodAutoscaler and KEDA ScaledObjects or ScaledJob scaling the target workload
+	// on the ignored resources, separated by commas; it's empty when the recommendations are ignored by the VWA
+	// spec only.
+	// +optional
+	Source string `json:"source,omitempty"`
+}
+
+// ContainerIgnore describes the resource recommendations ignored for a container
+type ContainerIgnore struct {
+	// Name of the container.
+	Name string `json:"name"`
+
+	// CPU indicates whether the CPU recommendations of the container are ignored.
+	// +optional
+	CPU bool `json:"cpu,omitempty"`
+
+	// Memory indicates whether the memory recommendations of the container are ignored.
+	// +optional
+	Memory bool `json:"memory,omitempty"`
+}
+
+// DeletionPolicy is the handling of the target workload resources on VWA deletion
+// +kubebuilder:validation:Enum=Retain;Restore
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyRetain keeps the resources last applied by the VWA
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyRestore restores the resources the target workload had before the VWA modified it
+	DeletionPolicyRestore DeletionPolicy = "Restore"
+)
+
+// GitWritebackFormat is the format of the file the VWA writes to Git
+// +kubebuilder:validation:Enum=KustomizePatch;HelmValues
+type GitWritebackFormat string
+
+const (
+	// GitWritebackFormatKustomizePatch is a kustomize strategic merge patch of the target workload
+	GitWritebackFormatKustomizePatch GitWritebackFormat = "KustomizePatch"
+	// GitWritebackFormatHelmValues is a Helm values snippet
+	GitWritebackFormatHelmValues GitWritebackFormat = "HelmValues"
+)
+
+// GitWriteback defines the Git repository file the recommended resources are written to
+type GitWriteback struct {
+	// Repository is the URL of the Git repository, e.g. https://github.com/org/deploy.git or git@github.com:org/deploy.git.
+	// Only https://, ssh:// and scp-like user@host:path URLs are allowed.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern="^(https://|ssh://|[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:).+$"
+	Repository string `json:"repository"`
+
+	// Branch is the existing branch to commit to (default: main).
+	// +kubebuilder:default=main
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// Path is a Go template of the file path in the repository. It may use the target workload
+	// {{ .Namespace }}, {{ .Kind }} and {{ .Name }} and the VWA name {{ .VWA }},
+	// e.g. "apps/{{ .Namespace }}/{{ .Name }}/resources-patch.yaml".
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Format of the file: a kustomize strategic merge patch or a Helm values snippet (default: KustomizePatch).
+	// +kubebuilder:default=KustomizePatch
+	// +optional
+	Format GitWritebackFormat `json:"format,omitempty"`
+
+	// ValuesKey is a Go template of the dot separated Helm values key holding the resources of a container,
+	// e.g. "{{ .Container }}.resources" (default: resources). Only used by the HelmValues format.
+	// +optional
+	ValuesKey string `json:"valuesKey,omitempty"`
+
+	// SecretRef references a Secret in the VWA namespace with the repository credentials:
+	// username and password for HTTPS, or identity (a private key) and known_hosts for SSH.
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// GitWritebackStatus describes the last commit of the recommended resources to Git
+type GitWritebackStatus struct {
+	// CommitSHA is the commit holding the recommended resources.
+	CommitSHA string `json:"commitSHA"`
+
+	// Path is the file written in the repository.
+	Path string `json:"path"`
+
+	// CommittedAt is the time the resources were committed.
+	CommittedAt metav1.Time `json:"committedAt"`
+
+	// Resources maps container names to the committed resource requirements.
+	// +optional
+	Resources map[string]corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Observed indicates whether the committed resources have since been observed on the target workload.
+	Observed bool `json:"observed"`
+}
+
+// ApplyMethod decides how the resources are applied to the target workload
+// +kubebuilder:validation:Enum=Template;Admission
+type ApplyMethod string
+
+const (
+	// ApplyMethodTemplate updates the container resources in the pod template of the target workload
+	ApplyMethodTemplate ApplyMethod = "Template"
+	// ApplyMethodAdmission sets the container resources on new pods of the target workload with the pod mutating webhook
+	ApplyMethodAdmission ApplyMethod = "Admission"
+)
+
+// UpdateMode decides when the target workload is updated
+// +kubebuilder:validation:Enum=Auto;Once;OnRollout
+type UpdateMode string
+
+const (
+	// UpdateModeAuto updates the target workload whenever the recommendations change
+	UpdateModeAuto UpdateMode = "Auto"
+	// UpdateModeOnce applies the first recommendation and then stops updating the target workload
+	UpdateModeOnce UpdateMode = "Once"
+	// UpdateModeOnRollout applies the latest recommendation only when the pod template is changed by someone else
+	UpdateModeOnRollout UpdateMode = "OnRollout"
+)
+
+// ApprovalMode decides how resource changes are approved
+// +kubebuilder:validation:Enum=Automatic;Manual
+type ApprovalMode string
+
+const (
+	// ApprovalAutomatic applies resource changes without approval
+	ApprovalAutomatic ApprovalMode = "Automatic"
+	// ApprovalManual applies resource changes once approved with the approve annotation
+	ApprovalManual ApprovalMode = "Manual"
+)
+
+// Proposal is a resource change waiting for approval
+type Proposal struct {
+	// Hash identifies the proposed resources; set the approve annotation of the VWA to it to approve the change.
+	Hash string `json:"hash"`
+
+	// ProposedAt is the time the change was proposed.
+	ProposedAt metav1.Time `json:"proposedAt"`
+
+	// Containers maps the names of the containers to change to their current and proposed resources.
+	Containers map[string]ContainerRevision `json:"containers"`
+}
+
+// RevisionReason is the cause of a resource change
+// +kubebuilder:validation:Enum=Recommendation;Rollback
+type RevisionReason string
+
+const (
+	// RevisionReasonRecommendation is a change applying the VPA recommendations
+	RevisionReasonRecommendation RevisionReason = "Recommendation"
+	// RevisionReasonRollback is a change rolling back to an earlier revision
+	RevisionReasonRollback RevisionReason = "Rollback"
+)
+
+// Revision records a change of the target workload resources made by the VWA
+type Revision struct {
+	// Revision is the sequence number of the change, starting at 1.
+	Revision int64 `json:"revision"`
+
+	// Timestamp is the time the change was made.
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// Reason is the cause of the change.
+	Reason RevisionReason `json:"reason"`
+
+	// Message describes the change, e.g. the revision rolled back to.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Containers maps the names of the changed containers to their resources before and after the change.
+	Containers map[string]ContainerRevision `json:"containers"`
+}
+
+// ContainerRevision holds the resources of a container before and after a change
+type ContainerRevision struct {
+	// Before are the resource requirements before the change.
+	Before corev1.ResourceRequirements `json:"before"`
+
+	// After are the resource requirements after the change.
+	After corev1.ResourceRequirements `json:"after"`
+
+	// Recommendation is the VPA target recommendation of the container at the time of the change.
+	// +optional
+	Recommendation corev1.ResourceList `json:"recommendation,omitempty"`
+}
+
+// VerticalWorkloadAutoscalerStatus defines the observed state of VerticalWorkloadAutoscaler
+type VerticalWorkloadAutoscalerStatus struct {
+	// TargetRef references the workload managed by the VWA.
+	// +optional
+	TargetRef *autoscalingv2.CrossVersionObjectReference `json:"targetRef,omitempty"`
+
+	// LastUpdated indicates the last time the VWA updated the workload.
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+
+	// RecommendedRequests maps container names to the recommended resource requirements.
+	// +optional
+	RecommendedRequests map[string]corev1.ResourceRequirements `json:"recommendedRequests,omitempty"`
+
+	// AppliedResources maps container names to the resource requirements last applied by the VWA.
+	// +optional
+	AppliedResources map[string]corev1.ResourceRequirements `json:"appliedResources,omitempty"`
+
+	// Reverts lists the times the applied resources were reverted by another field manager within the drift period.
+	// +optional
+	Reverts []metav1.Time `json:"reverts,omitempty"`
+
+	// SkippedUpdates indicates whether updates were skipped during the last reconciliation.
+	// +optional
+	SkippedUpdates bool `json:"skippedUpdates,omitempty"`
+
+	// SkipReason provides the reason for skipped updates, if applicable.
+	// +optional
+	SkipReason string `json:"skipReason,omitempty"`
+
+	// ActiveBlackout names the blackout window or calendar event that currently blocks updates.
+	// +optional
+	ActiveBlackout string `json:"activeBlackout,omitempty"`
+
+	// OriginalResources maps container names to their resource requirements before the VWA first modified them.
+	// They are restored on deletion with the Restore deletion policy.
+	// +optional
+	OriginalResources map[string]corev1.ResourceRequirements `json:"originalResources,omitempty"`
+
+	// Revisions lists the last resource changes of the VWA, oldest first, bounded by spec.revisionHistoryLimit.
+	// +optional
+	Revisions []Revision `json:"revisions,omitempty"`
+
+	// OutdatedPods is the number of live pods of the target workload not running the applied resources
+	// with the Admission apply method.
+	// +optional
+	OutdatedPods int32 `json:"outdatedPods,omitempty"`
+
+	// LastUpdateMode is the update mode that applied the last resource change.
+	// +optional
+	LastUpdateMode UpdateMode `json:"lastUpdateMode,omitempty"`
+
+	// ObservedTemplateHash is the hash of the target pod template, without container resources, last handled
+	// with the OnRollout update mode.
+	// +optional
+	ObservedTemplateHash string `json:"observedTemplateHash,omitempty"`
+
+	// PendingProposal is the resource change waiting for approval with the Manual approval mode.
+	// +optional
+	PendingProposal *Proposal `json:"pendingProposal,omitempty"`
+
+	// GitWriteback describes the last commit of the recommended resources to Git.
+	// +optional
+	GitWriteback *GitWritebackStatus `json:"gitWriteback,omitempty"`
+
+	// GitOpsOwner references the Argo CD Application or Flux Kustomization or HelmRelease managing the target
+	// workload, detected with the tool tracking labels and annotations.
+	// +optional
+	GitOpsOwner *GitOpsOwner `json:"gitOpsOwner,omitempty"`
+
+	// EffectiveIgnore is the resource recommendations ignored by the VWA, set by the VWA spec or by the
+	// HorizontalPodAutoscaler scaling the target workload on the resource utilization.
+	// +optional
+	EffectiveIgnore *EffectiveIgnore `json:"effectiveIgnore,omitempty"`
+
+	// HPACoordination describes the HPA utilization targets last scaled by the VWA with
+	// spec.hpaPolicy.coordinateTargets.
+	// +optional
+	HPACoordination *HPACoordination `json:"hpaCoordination,omitempty"`
+
+	// HPASaturation describes the HPA scaling the target workload at its maxReplicas, tracked with
+	// spec.hpaPolicy.saturationScaleUp.
+	// +optional
+	HPASaturation *HPASaturation `json:"hpaSaturation,omitempty"`
+
+	// UpdateCount represents the number of updates applied by the VWA.
+	// +optional
+	UpdateCount int32 `json:"updateCount,omitempty"`
+
+	// Conditions contains the current conditions of the VWA.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Conflicts contains a list of resources that conflict with the VWA's recommendations.
+	// +optional
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+}
+
+// Conflict describes a scaling controller that conflicts with the VWA on a resource
+type Conflict struct {
+	// Resource is the conflicting resource, e.g. cpu or memory
+	Resource string `json:"resource"`
+	// ConflictWith names the conflicting scaling controller
+	ConflictWith string `json:"conflictWith"`
+	// Reason describes the conflict
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:resource:shortName=vwa
+
+// VerticalWorkloadAutoscaler is the Schema for the VerticalWorkloadAutoscalers API
+type VerticalWorkloadAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VerticalWorkloadAutoscalerSpec   `json:"spec,omitempty"`
+	Status VerticalWorkloadAutoscalerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VerticalWorkloadAutoscalerList contains a list of VerticalWorkloadAutoscaler
+type VerticalWorkloadAutoscalerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VerticalWorkloadAutoscaler `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VerticalWorkloadAutoscaler{}, &VerticalWorkloadAutoscalerList{})
+}
@@ -0,0 +1,777 @@
+//go:build !ignore_autogenerated
+
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArgoCDIgnoreDifferences) DeepCopyInto(out *ArgoCDIgnoreDifferences) {
+	*out = *in
+	if in.ManagedFieldsManagers != nil {
+		in, out := &in.ManagedFieldsManagers, &out.ManagedFieldsManagers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDIgnoreDifferences.
+func (in *ArgoCDIgnoreDifferences) DeepCopy() *ArgoCDIgnoreDifferences {
+	if in == nil {
+		return nil
+	}
+	out := new(ArgoCDIgnoreDifferences)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlackoutWindow) DeepCopyInto(out *BlackoutWindow) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlackoutWindow.
+func (in *BlackoutWindow) DeepCopy() *BlackoutWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(BlackoutWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalendarReference) DeepCopyInto(out *CalendarReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalendarReference.
+func (in *CalendarReference) DeepCopy() *CalendarReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CalendarReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Conflict) DeepCopyInto(out *Conflict) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Conflict.
+func (in *Conflict) DeepCopy() *Conflict {
+	if in == nil {
+		return nil
+	}
+	out := new(Conflict)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerIgnore) DeepCopyInto(out *ContainerIgnore) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerIgnore.
+func (in *ContainerIgnore) DeepCopy() *ContainerIgnore {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerIgnore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerRevision) DeepCopyInto(out *ContainerRevision) {
+	*out = *in
+	in.Before.DeepCopyInto(&out.Before)
+	in.After.DeepCopyInto(&out.After)
+	if in.Recommendation != nil {
+		in, out := &in.Recommendation, &out.Recommendation
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerRevision.
+func (in *ContainerRevision) DeepCopy() *ContainerRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftPolicy) DeepCopyInto(out *DriftPolicy) {
+	*out = *in
+	if in.MaxReverts != nil {
+		in, out := &in.MaxReverts, &out.MaxReverts
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Period != nil {
+		in, out := &in.Period, &out.Period
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftPolicy.
+func (in *DriftPolicy) DeepCopy() *DriftPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EffectiveIgnore) DeepCopyInto(out *EffectiveIgnore) {
+	*out = *in
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]ContainerIgnore, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EffectiveIgnore.
+func (in *EffectiveIgnore) DeepCopy() *EffectiveIgnore {
+	if in == nil {
+		return nil
+	}
+	out := new(EffectiveIgnore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitOpsIntegration) DeepCopyInto(out *GitOpsIntegration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsIntegration.
+func (in *GitOpsIntegration) DeepCopy() *GitOpsIntegration {
+	if in == nil {
+		return nil
+	}
+	out := new(GitOpsIntegration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitOpsOwner) DeepCopyInto(out *GitOpsOwner) {
+	*out = *in
+	if in.IgnoreDifferences != nil {
+		in, out := &in.IgnoreDifferences, &out.IgnoreDifferences
+		*out = new(ArgoCDIgnoreDifferences)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsOwner.
+func (in *GitOpsOwner) DeepCopy() *GitOpsOwner {
+	if in == nil {
+		return nil
+	}
+	out := new(GitOpsOwner)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitWriteback) DeepCopyInto(out *GitWriteback) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitWriteback.
+func (in *GitWriteback) DeepCopy() *GitWriteback {
+	if in == nil {
+		return nil
+	}
+	out := new(GitWriteback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitWritebackStatus) DeepCopyInto(out *GitWritebackStatus) {
+	*out = *in
+	in.CommittedAt.DeepCopyInto(&out.CommittedAt)
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitWritebackStatus.
+func (in *GitWritebackStatus) DeepCopy() *GitWritebackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GitWritebackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HPACoordination) DeepCopyInto(out *HPACoordination) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]HPATarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UpdatedAt != nil {
+		in, out := &in.UpdatedAt, &out.UpdatedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPACoordination.
+func (in *HPACoordination) DeepCopy() *HPACoordination {
+	if in == nil {
+		return nil
+	}
+	out := new(HPACoordination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HPAPolicy) DeepCopyInto(out *HPAPolicy) {
+	*out = *in
+	if in.SaturationScaleUp != nil {
+		in, out := &in.SaturationScaleUp, &out.SaturationScaleUp
+		*out = new(SaturationScaleUp)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPAPolicy.
+func (in *HPAPolicy) DeepCopy() *HPAPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HPAPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HPASaturation) DeepCopyInto(out *HPASaturation) {
+	*out = *in
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Since.DeepCopyInto(&out.Since)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPASaturation.
+func (in *HPASaturation) DeepCopy() *HPASaturation {
+	if in == nil {
+		return nil
+	}
+	out := new(HPASaturation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HPATarget) DeepCopyInto(out *HPATarget) {
+	*out = *in
+	out.Threshold = in.Threshold.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPATarget.
+func (in *HPATarget) DeepCopy() *HPATarget {
+	if in == nil {
+		return nil
+	}
+	out := new(HPATarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Proposal) DeepCopyInto(out *Proposal) {
+	*out = *in
+	in.ProposedAt.DeepCopyInto(&out.ProposedAt)
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make(map[string]ContainerRevision, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Proposal.
+func (in *Proposal) DeepCopy() *Proposal {
+	if in == nil {
+		return nil
+	}
+	out := new(Proposal)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourcePolicy) DeepCopyInto(out *ResourcePolicy) {
+	*out = *in
+	if in.AvoidCPULimit != nil {
+		in, out := &in.AvoidCPULimit, &out.AvoidCPULimit
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ControlledResources != nil {
+		in, out := &in.ControlledResources, &out.ControlledResources
+		*out = new([]ControlledResource)
+		if **in != nil {
+			in, out := *in, *out
+			*out = make([]ControlledResource, len(*in))
+			copy(*out, *in)
+		}
+	}
+	if in.Tolerance != nil {
+		in, out := &in.Tolerance, &out.Tolerance
+		*out = new(Tolerance)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourcePolicy.
+func (in *ResourcePolicy) DeepCopy() *ResourcePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourcePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Revision) DeepCopyInto(out *Revision) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make(map[string]ContainerRevision, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Revision.
+func (in *Revision) DeepCopy() *Revision {
+	if in == nil {
+		return nil
+	}
+	out := new(Revision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SaturationScaleUp) DeepCopyInto(out *SaturationScaleUp) {
+	*out = *in
+	if in.After != nil {
+		in, out := &in.After, &out.After
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxCPU != nil {
+		in, out := &in.MaxCPU, &out.MaxCPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SaturationScaleUp.
+func (in *SaturationScaleUp) DeepCopy() *SaturationScaleUp {
+	if in == nil {
+		return nil
+	}
+	out := new(SaturationScaleUp)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Tolerance) DeepCopyInto(out *Tolerance) {
+	*out = *in
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		*out = new(Percentage)
+		**out = **in
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		*out = new(Percentage)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tolerance.
+func (in *Tolerance) DeepCopy() *Tolerance {
+	if in == nil {
+		return nil
+	}
+	out := new(Tolerance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdatePolicy) DeepCopyInto(out *UpdatePolicy) {
+	*out = *in
+	if in.Frequency != nil {
+		in, out := &in.Frequency, &out.Frequency
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.AllowedWindows != nil {
+		in, out := &in.AllowedWindows, &out.AllowedWindows
+		*out = make([]UpdateWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.BlackoutWindows != nil {
+		in, out := &in.BlackoutWindows, &out.BlackoutWindows
+		*out = make([]BlackoutWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BlackoutCalendars != nil {
+		in, out := &in.BlackoutCalendars, &out.BlackoutCalendars
+		*out = make([]CalendarReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]UpdateScheduleReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = new(DriftPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdatePolicy.
+func (in *UpdatePolicy) DeepCopy() *UpdatePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdatePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateScheduleReference) DeepCopyInto(out *UpdateScheduleReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateScheduleReference.
+func (in *UpdateScheduleReference) DeepCopy() *UpdateScheduleReference {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateScheduleReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateWindow) DeepCopyInto(out *UpdateWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateWindow.
+func (in *UpdateWindow) DeepCopy() *UpdateWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VPAReference) DeepCopyInto(out *VPAReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VPAReference.
+func (in *VPAReference) DeepCopy() *VPAReference {
+	if in == nil {
+		return nil
+	}
+	out := new(VPAReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerticalWorkloadAutoscaler) DeepCopyInto(out *VerticalWorkloadAutoscaler) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerticalWorkloadAutoscaler.
+func (in *VerticalWorkloadAutoscaler) DeepCopy() *VerticalWorkloadAutoscaler {
+	if in == nil {
+		return nil
+	}
+	out := new(VerticalWorkloadAutoscaler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VerticalWorkloadAutoscaler) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerticalWorkloadAutoscalerList) DeepCopyInto(out *VerticalWorkloadAutoscalerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VerticalWorkloadAutoscaler, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerticalWorkloadAutoscalerList.
+func (in *VerticalWorkloadAutoscalerList) DeepCopy() *VerticalWorkloadAutoscalerList {
+	if in == nil {
+		return nil
+	}
+	out := new(VerticalWorkloadAutoscalerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VerticalWorkloadAutoscalerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerticalWorkloadAutoscalerSpec) DeepCopyInto(out *VerticalWorkloadAutoscalerSpec) {
+	*out = *in
+	out.VPARef = in.VPARef
+	if in.TargetRef != nil {
+		in, out := &in.TargetRef, &out.TargetRef
+		*out = new(v2.CrossVersionObjectReference)
+		**out = **in
+	}
+	in.UpdatePolicy.DeepCopyInto(&out.UpdatePolicy)
+	in.ResourcePolicy.DeepCopyInto(&out.ResourcePolicy)
+	if in.CustomAnnotations != nil {
+		in, out := &in.CustomAnnotations, &out.CustomAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.GitOps != nil {
+		in, out := &in.GitOps, &out.GitOps
+		*out = new(GitOpsIntegration)
+		**out = **in
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RollbackTo != nil {
+		in, out := &in.RollbackTo, &out.RollbackTo
+		*out = new(int64)
+		**out = **in
+	}
+	if in.GitWriteback != nil {
+		in, out := &in.GitWriteback, &out.GitWriteback
+		*out = new(GitWriteback)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HPAPolicy != nil {
+		in, out := &in.HPAPolicy, &out.HPAPolicy
+		*out = new(HPAPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerticalWorkloadAutoscalerSpec.
+func (in *VerticalWorkloadAutoscalerSpec) DeepCopy() *VerticalWorkloadAutoscalerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VerticalWorkloadAutoscalerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerticalWorkloadAutoscalerStatus) DeepCopyInto(out *VerticalWorkloadAutoscalerStatus) {
+	*out = *in
+	if in.TargetRef != nil {
+		in, out := &in.TargetRef, &out.TargetRef
+		*out = new(v2.CrossVersionObjectReference)
+		**out = **in
+	}
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+	if in.RecommendedRequests != nil {
+		in, out := &in.RecommendedRequests, &out.RecommendedRequests
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.AppliedResources != nil {
+		in, out := &in.AppliedResources, &out.AppliedResources
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Reverts != nil {
+		in, out := &in.Reverts, &out.Reverts
+		*out = make([]v1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.OriginalResources != nil {
+		in, out := &in.OriginalResources, &out.OriginalResources
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Revisions != nil {
+		in, out := &in.Revisions, &out.Revisions
+		*out = make([]Revision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PendingProposal != nil {
+		in, out := &in.PendingProposal, &out.PendingProposal
+		*out = new(Proposal)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GitWriteback != nil {
+		in, out := &in.GitWriteback, &out.GitWriteback
+		*out = new(GitWritebackStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GitOpsOwner != nil {
+		in, out := &in.GitOpsOwner, &out.GitOpsOwner
+		*out = new(GitOpsOwner)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EffectiveIgnore != nil {
+		in, out := &in.EffectiveIgnore, &out.EffectiveIgnore
+		*out = new(EffectiveIgnore)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HPACoordination != nil {
+		in, out := &in.HPACoordination, &out.HPACoordination
+		*out = new(HPACoordination)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HPASaturation != nil {
+		in, out := &in.HPASaturation, &out.HPASaturation
+		*out = new(HPASaturation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]Conflict, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerticalWorkloadAutoscalerStatus.
+func (in *VerticalWorkloadAutoscalerStatus) DeepCopy() *VerticalWorkloadAutoscalerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VerticalWorkloadAutoscalerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -25,7 +25,12 @@ import (
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	vwav1beta1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1beta1"
 	"github.com/alexei-led/vertical-workload-autoscaler/internal/controller" //nolint:typecheck
+	"github.com/alexei-led/vertical-workload-autoscaler/internal/gitwriteback"
+	webhookv1alpha1 "github.com/alexei-led/vertical-workload-autoscaler/internal/webhook/v1alpha1"
+	webhookv1beta1 "github.com/alexei-led/vertical-workload-autoscaler/internal/webhook/v1beta1"
+	webhookworkload "github.com/alexei-led/vertical-workload-autoscaler/internal/webhook/workload"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -37,6 +42,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -49,6 +55,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(vwav1.AddToScheme(scheme))
+	utilruntime.Must(vwav1beta1.AddToScheme(scheme))
 	utilruntime.Must(vpav1.AddToScheme(scheme))
 	utilruntime.Must(autoscalingv2.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
@@ -63,6 +70,13 @@ func main() {
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
 	var timeoutDuration time.Duration
+	var qualityOfService string
+	var controllerUsername string
+	var strictResourceProtection bool
+	var gitAuthorName string
+	var gitAuthorEmail string
+	var enableRecommendationsExport bool
+	specDefaults := vwav1.NewSpecDefaults()
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -75,6 +89,27 @@ func main() {
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	flag.DurationVar(&timeoutDuration, "timeout", 30*time.Second, "The default reconcile timeout")
+	flag.DurationVar(&specDefaults.UpdateFrequency, "default-update-frequency", specDefaults.UpdateFrequency,
+		"The update frequency of VWAs that don't set spec.updateFrequency")
+	flag.StringVar(&qualityOfService, "default-quality-of-service", string(specDefaults.QualityOfService),
+		"The quality of service class (Guaranteed or Burstable) of VWAs that don't set spec.qualityOfService")
+	flag.IntVar(&specDefaults.CPUTolerance, "default-cpu-tolerance", specDefaults.CPUTolerance,
+		"The CPU update tolerance in percent of VWAs that don't set spec.updateTolerance.cpu")
+	flag.IntVar(&specDefaults.MemoryTolerance, "default-memory-tolerance", specDefaults.MemoryTolerance,
+		"The memory update tolerance in percent of VWAs that don't set spec.updateTolerance.memory")
+	flag.StringVar(&controllerUsername, "controller-username", "",
+		"The user the controller authenticates as; the workload webhook always allows its resource changes. "+
+			"Defaults to the service account of the controller pod from the POD_NAMESPACE and SERVICE_ACCOUNT_NAME variables")
+	flag.BoolVar(&strictResourceProtection, "strict-resource-protection", false,
+		"If set, the workload webhook denies manual changes of VWA managed container resources "+
+			"unless the workload has the "+vwav1.AllowManualResourcesAnnotation+"=true annotation")
+	flag.StringVar(&gitAuthorName, "git-author-name", "Vertical Workload Autoscaler",
+		"The author name of the commits of VWAs in Git write-back mode")
+	flag.StringVar(&gitAuthorEmail, "git-author-email", "vwa@autoscaling.workload.io",
+		"The author email of the commits of VWAs in Git write-back mode")
+	flag.BoolVar(&enableRecommendationsExport, "enable-recommendations-export", true,
+		"If set, the secure metrics server also serves the VWA recommendations as kustomize patches, "+
+			"Helm values or JSON on "+controller.RecommendationsPath)
 	opts := zap.Options{
 		Development: true,
 	}
@@ -83,6 +118,16 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if controllerUsername == "" && os.Getenv("POD_NAMESPACE") != "" && os.Getenv("SERVICE_ACCOUNT_NAME") != "" {
+		controllerUsername = webhookworkload.ServiceAccountUsername(os.Getenv("POD_NAMESPACE"), os.Getenv("SERVICE_ACCOUNT_NAME"))
+	}
+
+	specDefaults.QualityOfService = vwav1.QualityOfServiceClass(qualityOfService)
+	if err := specDefaults.Validate(); err != nil {
+		setupLog.Error(err, "invalid VWA defaults")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -98,6 +143,10 @@ func main() {
 		tlsOpts = append(tlsOpts, disableHTTP2)
 	}
 
+	webhookServer := webhook.NewServer(webhook.Options{
+		TLSOpts: tlsOpts,
+	})
+
 	// Metrics endpoint is enabled in 'config/default/kustomization.yaml'. The Metrics options configure the server.
 	// More info:
 	// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/metrics/server
@@ -119,6 +168,7 @@ func main() {
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
+		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       leaderElectionID,
@@ -140,12 +190,43 @@ func main() {
 	}
 
 	if err = (&controller.VerticalWorkloadAutoscalerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Defaults:  &specDefaults,
+		GitWriter: gitwriteback.NewCLIWriter(gitAuthorName, gitAuthorEmail),
+		APIReader: mgr.GetAPIReader(),
 	}).SetupWithManager(mgr, timeoutDuration); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VerticalWorkloadAutoscaler")
 		os.Exit(1)
 	}
+	// the export shares the authentication and authorization of the metrics endpoint, so it needs secure metrics
+	if enableRecommendationsExport && secureMetrics {
+		if err = mgr.AddMetricsServerExtraHandler(controller.RecommendationsPath, controller.NewRecommendationsHandler(mgr.GetClient())); err != nil {
+			setupLog.Error(err, "unable to add recommendations export handler")
+			os.Exit(1)
+		}
+	} else if enableRecommendationsExport {
+		setupLog.Info("recommendations export disabled, it requires --metrics-secure")
+	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookv1alpha1.SetupVerticalWorkloadAutoscalerWebhookWithManager(mgr, specDefaults); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "VerticalWorkloadAutoscaler")
+			os.Exit(1)
+		}
+		if err = webhookv1beta1.SetupVerticalWorkloadAutoscalerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create conversion webhook", "webhook", "VerticalWorkloadAutoscaler")
+			os.Exit(1)
+		}
+		if err = webhookworkload.SetupWorkloadWebhookWithManager(mgr, controllerUsername, strictResourceProtection); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Workload")
+			os.Exit(1)
+		}
+		if err = webhookworkload.SetupPodWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
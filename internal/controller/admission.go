@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// admissionTarget returns a copy of the target object with the container resources the pod mutating webhook sets on
+// its pods with the Admission apply method, so resource changes are planned against the resources the pods run with
+func admissionTarget(targetObject client.Object, applied map[string]corev1.ResourceRequirements) (client.Object, error) {
+	obj := targetObject.DeepCopyObject().(client.Object)
+	template, err := podTemplate(obj)
+	if err != nil {
+		return nil, err
+	}
+	for i, container := range template.Spec.Containers {
+		if resources, ok := applied[container.Name]; ok {
+			template.Spec.Containers[i].Resources = resources
+		}
+	}
+	return obj, nil
+}
+
+// workloadSelector returns the label selector of the pods of the target object
+func workloadSelector(targetObject client.Object) (labels.Selector, error) {
+	switch resource := targetObject.(type) {
+	case *appsv1.Deployment:
+		return metav1.LabelSelectorAsSelector(resource.Spec.Selector)
+	case *appsv1.StatefulSet:
+		return metav1.LabelSelectorAsSelector(resource.Spec.Selector)
+	case *appsv1.DaemonSet:
+		return metav1.LabelSelectorAsSelector(resource.Spec.Selector)
+	case *appsv1.ReplicaSet:
+		return metav1.LabelSelectorAsSelector(resource.Spec.Selector)
+	case *batchv1.Job:
+		return metav1.LabelSelectorAsSelector(resource.Spec.Selector)
+	case *batchv1.CronJob:
+		// the pods of a CronJob have no selector, only the labels of its job template
+		if podLabels := resource.Spec.JobTemplate.Spec.Template.Labels; len(podLabels) > 0 {
+			return labels.SelectorFromSet(podLabels), nil
+		}
+		return labels.Nothing(), nil
+	default:
+		return labels.Nothing(), nil
+	}
+}
+
+// countOutdatedPods returns the number of live pods of the target object whose container resources differ from
+// the resources applied by the pod mutating webhook
+func (r *VerticalWorkloadAutoscalerReconciler) countOutdatedPods(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, targetObject client.Object) (int32, error) {
+	selector, err := workloadSelector(targetObject)
+	if err != nil {
+		return 0, err
+	}
+	// pods are listed uncached, so the controller doesn't cache every pod of the cluster
+	var podList corev1.PodList
+	if err := r.uncachedReader().List(ctx, &podList, client.InNamespace(wa.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return 0, err
+	}
+
+	var outdated int32
+	for _, pod := range podList.Items {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			if resources, ok := wa.Status.AppliedResources[container.Name]; ok && !resourceRequirementsEqual(resources, container.Resources) {
+				outdated++
+				break
+			}
+		}
+	}
+	return outdated, nil
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWorkloadSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      client.Object
+		expected string
+	}{
+		{
+			name:     "Deployment",
+			obj:      &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}},
+			expected: "app=web",
+		},
+		{
+			name: "CronJob",
+			obj: &batchv1.CronJob{Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "report"}}},
+			}}}},
+			expected: "app=report",
+		},
+		{
+			name: "CronJob without pod labels",
+			obj:  &batchv1.CronJob{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := workloadSelector(tt.obj)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, selector.String())
+		})
+	}
+}
+
+func TestHandleVWAChangeAdmission(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	updateModeOff := vpav1.UpdateModeOff
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference: vwav1.VPAReference{Name: "vpa1"},
+			ApplyMethod:  vwav1.ApplyMethodAdmission,
+		},
+	}
+	vpa := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &updateModeOff},
+			TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+		Status: vpav1.VerticalPodAutoscalerStatus{Recommendation: &vpav1.RecommendedPodResources{ContainerRecommendations: []vpav1.RecommendedContainerResources{
+			{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+		}}},
+	}
+	podLabels := map[string]string{"app": "web"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app", Image: "web:1.0", Resources: cpuRequests("250m")},
+			}}},
+		},
+	}
+	pod := func(name string, resources corev1.ResourceRequirements, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: podLabels},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "web:1.0", Resources: resources}}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment,
+			pod("web-old", cpuRequests("250m"), corev1.PodRunning),
+			// the Guaranteed QoS class sets the limits to the requests
+			pod("web-new", corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+			}, corev1.PodRunning),
+			pod("web-done", cpuRequests("250m"), corev1.PodSucceeded),
+		).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(100)}
+
+	for range 2 {
+		now = now.Add(10 * time.Minute)
+		_, err := r.handleVWAChange(context.Background(), vwa)
+		require.NoError(t, err)
+
+		// the workload manifest is left unchanged
+		stored := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+		assert.Equal(t, cpuRequests("250m"), stored.Spec.Template.Spec.Containers[0].Resources)
+		assert.Empty(t, stored.Annotations)
+
+		assert.Equal(t, resource.MustParse("500m"), vwa.Status.AppliedResources["app"].Requests[corev1.ResourceCPU])
+		assert.Equal(t, int32(1), vwa.Status.OutdatedPods)
+		// the second reconcile plans against the applied resources and has nothing to change
+		assert.Len(t, vwa.Status.Revisions, 1)
+	}
+}
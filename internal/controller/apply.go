@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// FieldManager is the field manager of the VWA controller server-side apply requests
+const FieldManager = "vertical-workload-autoscaler"
+
+// applyTargetResources applies the container resources and the VWA annotations to the target object with
+// server-side apply, so the VWA field manager owns only these fields. A conflict with another field manager
+// is reported, and the apply is retried taking the ownership of the conflicting fields.
+func (r *VerticalWorkloadAutoscalerReconciler) applyTargetResources(ctx context.Context, targetObject client.Object, vwa *vwav1.VerticalWorkloadAutoscaler, resources map[string]corev1.ResourceRequirements) error {
+	applyObj, err := r.applyConfiguration(targetObject, vwa, resources)
+	if err != nil {
+		return err
+	}
+	return r.applyTarget(ctx, targetObject, vwa, applyObj)
+}
+
+// applyTarget applies the apply configuration to the target object and refreshes the target object with the result
+func (r *VerticalWorkloadAutoscalerReconciler) applyTarget(ctx context.Context, targetObject client.Object, vwa *vwav1.VerticalWorkloadAutoscaler, applyObj *unstructured.Unstructured) error {
+	logger := log.FromContext(ctx)
+
+	force := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj := applyObj.DeepCopy()
+		opts := []client.PatchOption{client.FieldOwner(FieldManager)}
+		if force {
+			opts = append(opts, client.ForceOwnership)
+		}
+		err := r.Patch(ctx, obj, client.Apply, opts...)
+		if err == nil {
+			applyObj = obj
+			return nil
+		}
+		if apierrors.IsConflict(err) && !force {
+			logger.Info("field manager conflict, retrying with forced ownership", "target", targetObject.GetName(), "error", err.Error())
+			r.recordEvent(vwa, "Warning", ReasonFieldManagerConflict,
+				fmt.Sprintf("conflict applying resources of '%s', taking ownership: %v", targetObject.GetName(), err))
+			force = true
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply target object resources: %w", err)
+	}
+
+	// refresh the target object with the applied state
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(applyObj.Object, targetObject)
+}
+
+// applyConfiguration returns the apply configuration holding only the fields owned by the VWA:
+// the resources of the managed containers and the VWA annotations
+func (r *VerticalWorkloadAutoscalerReconciler) applyConfiguration(targetObject client.Object, vwa *vwav1.VerticalWorkloadAutoscaler, resources map[string]corev1.ResourceRequirements) (*unstructured.Unstructured, error) {
+	applyObj, err := r.resourcesObject(targetObject, resources)
+	if err != nil {
+		return nil, err
+	}
+	r.setAnnotations(applyObj, vwa)
+	return applyObj, nil
+}
+
+// resourcesObject returns a partial target object holding only the resources of the containers
+func (r *VerticalWorkloadAutoscalerReconciler) resourcesObject(targetObject client.Object, resources map[string]corev1.ResourceRequirements) (*unstructured.Unstructured, error) {
+	gvk, err := apiutil.GVKForObject(targetObject, r.Client.Scheme())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target object kind: %w", err)
+	}
+	return resourcesPatch(gvk, targetObject.GetNamespace(), targetObject.GetName(), resources)
+}
+
+// resourcesPatch returns a partial object of the kind holding only the resources of the containers
+func resourcesPatch(gvk schema.GroupVersionKind, namespace, name string, resources map[string]corev1.ResourceRequirements) (*unstructured.Unstructured, error) {
+	path, err := containersPath(gvk.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	containerNames := make([]string, 0, len(resources))
+	for containerName := range resources {
+		containerNames = append(containerNames, containerName)
+	}
+	sort.Strings(containerNames)
+
+	containers := make([]interface{}, 0, len(containerNames))
+	for _, containerName := range containerNames {
+		containerResources := resources[containerName]
+		unstructuredResources, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&containerResources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert resources of container '%s': %w", containerName, err)
+		}
+		containers = append(containers, map[string]interface{}{"name": containerName, "resources": unstructuredResources})
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	if err := unstructured.SetNestedSlice(obj.Object, containers, path...); err != nil {
+		return nil, fmt.Errorf("failed to set containers: %w", err)
+	}
+	return obj, nil
+}
+
+// containersPath returns the path of the pod template containers in a workload of the kind
+func containersPath(kind string) ([]string, error) {
+	switch kind {
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		return []string{"spec", "template", "spec", "containers"}, nil
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec", "containers"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported target object kind: %s", kind)
+	}
+}
@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// withApplyPatches emulates server-side apply, which the fake client doesn't support,
+// with a strategic merge patch of the typed object; the ServerSide tests check the field ownership with envtest
+func withApplyPatches(builder *fake.ClientBuilder) *fake.ClientBuilder {
+	return builder.WithInterceptorFuncs(interceptor.Funcs{Patch: applyAsStrategicMerge})
+}
+
+func applyAsStrategicMerge(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Patch(ctx, obj, patch, opts...)
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	typed, err := c.Scheme().New(obj.GetObjectKind().GroupVersionKind())
+	if err != nil {
+		return err
+	}
+	typedObj := typed.(client.Object)
+	typedObj.SetName(obj.GetName())
+	typedObj.SetNamespace(obj.GetNamespace())
+	if err := c.Patch(ctx, typedObj, client.RawPatch(types.StrategicMergePatchType, data)); err != nil {
+		return err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typedObj)
+	if err != nil {
+		return err
+	}
+	obj.(*unstructured.Unstructured).SetUnstructuredContent(content)
+	return nil
+}
+
+func TestApplyConfiguration(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-vwa", Namespace: "default"},
+		Spec:       vwav1.VerticalWorkloadAutoscalerSpec{CustomAnnotations: map[string]string{"team": "web"}},
+	}
+	resources := map[string]corev1.ResourceRequirements{
+		"sidecar": {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")}},
+		"app": {
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("400Mi")},
+		},
+	}
+	expectedContainers := []interface{}{
+		map[string]interface{}{"name": "app", "resources": map[string]interface{}{
+			"requests": map[string]interface{}{"cpu": "200m"},
+			"limits":   map[string]interface{}{"memory": "400Mi"},
+		}},
+		map[string]interface{}{"name": "sidecar", "resources": map[string]interface{}{
+			"requests": map[string]interface{}{"cpu": "50m"},
+		}},
+	}
+
+	tests := []struct {
+		name          string
+		targetObject  client.Object
+		expectedKind  schema.GroupVersionKind
+		expectedPath  []string
+		expectedError bool
+	}{
+		{
+			name:         "Deployment",
+			targetObject: &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			expectedKind: appsv1.SchemeGroupVersion.WithKind("Deployment"),
+			expectedPath: []string{"spec", "template", "spec", "containers"},
+		},
+		{
+			name:         "CronJob",
+			targetObject: &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			expectedKind: batchv1.SchemeGroupVersion.WithKind("CronJob"),
+			expectedPath: []string{"spec", "jobTemplate", "spec", "template", "spec", "containers"},
+		},
+		{
+			name:          "Unsupported kind",
+			targetObject:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyObj, err := r.applyConfiguration(tt.targetObject, vwa, resources)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedKind, applyObj.GroupVersionKind())
+			assert.Equal(t, "web", applyObj.GetName())
+			assert.Equal(t, "default", applyObj.GetNamespace())
+			assert.Equal(t, "web", applyObj.GetAnnotations()["team"])
+			assert.Equal(t, "test-vwa", applyObj.GetAnnotations()[vwav1.UpdatedByAnnotation])
+			containers, found, err := unstructured.NestedSlice(applyObj.Object, tt.expectedPath...)
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.Equal(t, expectedContainers, containers)
+			// only the resources, the annotations and the object identity are applied
+			spec, _, _ := unstructured.NestedMap(applyObj.Object, "spec")
+			assert.Len(t, spec, 1)
+			assert.Len(t, applyObj.Object, 4)
+		})
+	}
+}
+
+func TestApplyTargetResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	conflict := apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, "web",
+		assert.AnError)
+
+	tests := []struct {
+		name           string
+		conflicts      int
+		expectedError  bool
+		expectedForces []bool
+		expectedEvents int
+	}{
+		{
+			name:           "No conflict",
+			expectedForces: []bool{false},
+		},
+		{
+			name:           "Conflict retried with forced ownership",
+			conflicts:      1,
+			expectedForces: []bool{false, true},
+			expectedEvents: 1,
+		},
+		{
+			name:           "Persistent conflict",
+			conflicts:      10,
+			expectedError:  true,
+			expectedForces: []bool{false, true, true, true, true},
+			expectedEvents: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "app", Image: "web:1.0", Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+					}},
+				}}}},
+			}
+			var forces []bool
+			conflicts := tt.conflicts
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(deployment).WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					patchOpts := &client.PatchOptions{}
+					patchOpts.ApplyOptions(opts)
+					assert.Equal(t, FieldManager, patchOpts.FieldManager)
+					forces = append(forces, patchOpts.Force != nil && *patchOpts.Force)
+					if conflicts > 0 {
+						conflicts--
+						return conflict
+					}
+					return applyAsStrategicMerge(ctx, c, obj, patch, opts...)
+				},
+			}).Build()
+			recorder := record.NewFakeRecorder(10)
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+			vwa := &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "test-vwa", Namespace: "default"}}
+			resources := map[string]corev1.ResourceRequirements{
+				"app": {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")}},
+			}
+
+			err := r.applyTargetResources(context.Background(), deployment, vwa, resources)
+
+			assert.Equal(t, tt.expectedForces, forces)
+			assert.Len(t, recorder.Events, tt.expectedEvents)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			// the target object is refreshed with the applied state, keeping the fields not owned by the VWA
+			container := deployment.Spec.Template.Spec.Containers[0]
+			assert.Equal(t, "web:1.0", container.Image)
+			assert.Equal(t, resources["app"], container.Resources)
+			assert.Equal(t, "test-vwa", deployment.Annotations[vwav1.UpdatedByAnnotation])
+		})
+	}
+}
+
+// managedFields returns the fields owned by the field manager, as the JSON of the managed fields entries
+func managedFields(obj client.Object, manager string) string {
+	var fields []string
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == manager && entry.FieldsV1 != nil {
+			fields = append(fields, string(entry.FieldsV1.Raw))
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func TestApplyTargetServerSide(t *testing.T) {
+	c := requireEnvtest(t)
+	ctx := context.Background()
+	cpu := func(value string) corev1.ResourceList {
+		return corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(value)}
+	}
+
+	labels := map[string]string{"app": "apply-web"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "apply-web", Namespace: "default", Annotations: map[string]string{"team": "checkout"}},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "app", Image: "web:1.0"},
+					{Name: "sidecar", Image: "proxy:1.0", Resources: corev1.ResourceRequirements{Requests: cpu("50m")}},
+				}},
+			},
+		},
+	}
+	require.NoError(t, c.Create(ctx, deployment, client.FieldOwner("kubectl")))
+	t.Cleanup(func() { _ = c.Delete(ctx, deployment) })
+
+	recorder := record.NewFakeRecorder(10)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+	vwa := &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "test-vwa", Namespace: "default"}}
+	gvk := appsv1.SchemeGroupVersion.WithKind("Deployment")
+
+	steps := []struct {
+		name              string
+		manager           string
+		resources         corev1.ResourceRequirements
+		expectedResources corev1.ResourceRequirements
+		expectedEvents    int
+	}{
+		{
+			name:              "VWA owns only the resources it applies",
+			manager:           FieldManager,
+			resources:         corev1.ResourceRequirements{Requests: cpu("200m"), Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("400Mi")}},
+			expectedResources: corev1.ResourceRequirements{Requests: cpu("200m"), Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("400Mi")}},
+		},
+		{
+			name:              "Fields the VWA stops applying are removed",
+			manager:           FieldManager,
+			resources:         corev1.ResourceRequirements{Requests: cpu("300m")},
+			expectedResources: corev1.ResourceRequirements{Requests: cpu("300m")},
+		},
+		{
+			name:              "Another field manager takes the CPU request",
+			manager:           "helm",
+			resources:         corev1.ResourceRequirements{Requests: cpu("100m")},
+			expectedResources: corev1.ResourceRequirements{Requests: cpu("100m")},
+		},
+		{
+			name:              "Conflict is forced and reported",
+			manager:           FieldManager,
+			resources:         corev1.ResourceRequirements{Requests: cpu("250m")},
+			expectedResources: corev1.ResourceRequirements{Requests: cpu("250m")},
+			expectedEvents:    1,
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			resources := map[string]corev1.ResourceRequirements{"app": step.resources}
+			if step.manager == FieldManager {
+				require.NoError(t, r.applyTargetResources(ctx, deployment, vwa, resources))
+			} else {
+				applyObj, err := resourcesPatch(gvk, deployment.Namespace, deployment.Name, resources)
+				require.NoError(t, err)
+				require.NoError(t, c.Patch(ctx, applyObj, client.Apply, client.FieldOwner(step.manager), client.ForceOwnership))
+			}
+			assert.Len(t, recorder.Events, step.expectedEvents)
+			for len(recorder.Events) > 0 {
+				assert.Contains(t, <-recorder.Events, ReasonFieldManagerConflict)
+			}
+
+			updated := &appsv1.Deployment{}
+			require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(deployment), updated))
+			containers := updated.Spec.Template.Spec.Containers
+			require.Len(t, containers, 2)
+			assert.True(t, apiequality.Semantic.DeepEqual(step.expectedResources, containers[0].Resources), "app resources: %v", containers[0].Resources)
+			assert.True(t, apiequality.Semantic.DeepEqual(corev1.ResourceRequirements{Requests: cpu("50m")}, containers[1].Resources), "sidecar resources: %v", containers[1].Resources)
+			assert.Equal(t, "web:1.0", containers[0].Image)
+			assert.Equal(t, "checkout", updated.Annotations["team"])
+			assert.Equal(t, "test-vwa", updated.Annotations[vwav1.UpdatedByAnnotation])
+
+			// the VWA field manager owns the resources of the app container and its annotations, nothing else
+			owned := managedFields(updated, FieldManager)
+			assert.Contains(t, owned, `"k:{\"name\":\"app\"}"`)
+			assert.Contains(t, owned, `"f:`+vwav1.UpdatedByAnnotation+`"`)
+			assert.NotContains(t, owned, `"k:{\"name\":\"sidecar\"}"`)
+			assert.NotContains(t, owned, `"f:image"`)
+			assert.NotContains(t, owned, `"f:team"`)
+			assert.Equal(t, step.manager == "helm", strings.Contains(managedFields(updated, "helm"), `"f:cpu"`))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// proposalHashLength is the number of hex digits of a proposal hash
+const proposalHashLength = 16
+
+// handleApproval proposes the resource change in status.pendingProposal with the Manual approval mode and
+// returns true once the approve annotation of the VWA matches the proposal hash. A newer recommendation
+// replaces the pending proposal, and approvals of any other hash are rejected.
+func (r *VerticalWorkloadAutoscalerReconciler) handleApproval(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, targetObject client.Object, newResources map[string]corev1.ResourceRequirements, vpa *vpav1.VerticalPodAutoscaler) (bool, error) {
+	currentResources, appliedResources, needsUpdate, err := plannedResources(targetObject, newResources, vpa.Spec.UpdatePolicy)
+	if err != nil {
+		return false, err
+	}
+
+	// an approval is used once, whether it matches the proposal or not
+	approval := wa.Annotations[vwav1.ApproveAnnotation]
+	if approval != "" {
+		if err := r.removeAnnotation(ctx, wa, vwav1.ApproveAnnotation); err != nil {
+			return false, err
+		}
+	}
+
+	if !needsUpdate {
+		if approval != "" {
+			r.recordEvent(wa, "Warning", ReasonApprovalRejected, fmt.Sprintf("approval of proposal %s rejected: no resource change is pending", approval))
+		}
+		if wa.Status.PendingProposal != nil {
+			wa.Status.PendingProposal = nil
+			if err := r.Status().Update(ctx, wa); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	hash, err := proposalHash(appliedResources)
+	if err != nil {
+		return false, err
+	}
+	pending := wa.Status.PendingProposal
+	if approval != "" {
+		if pending != nil && pending.Hash == hash && approval == hash {
+			r.recordEvent(wa, "Normal", ReasonProposalApproved, fmt.Sprintf("resource change %s approved", hash))
+			wa.Status.PendingProposal = nil
+			return true, nil
+		}
+		r.recordEvent(wa, "Warning", ReasonApprovalRejected, fmt.Sprintf("approval of proposal %s rejected: the pending proposal is %s", approval, hash))
+	}
+
+	if pending == nil || pending.Hash != hash {
+		wa.Status.PendingProposal = &vwav1.Proposal{
+			Hash:       hash,
+			ProposedAt: metav1.NewTime(timeNow()),
+			Containers: containerChanges(currentResources, appliedResources, vpa.Status.Recommendation),
+		}
+		r.recordEvent(wa, "Normal", ReasonApprovalRequired, fmt.Sprintf("resource change %s requires approval: annotate the VWA with %s=%s", hash, vwav1.ApproveAnnotation, hash))
+	}
+	msg := fmt.Sprintf("waiting for approval of resource change %s", hash)
+	return false, r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionFalse, ReasonApprovalRequired, msg)
+}
+
+// removeAnnotation removes a one-shot annotation, such as the approve annotation, from the VWA
+func (r *VerticalWorkloadAutoscalerReconciler) removeAnnotation(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, key string) error {
+	patch := client.MergeFrom(wa.DeepCopy())
+	delete(wa.Annotations, key)
+	if err := r.Patch(ctx, wa, patch); err != nil {
+		return fmt.Errorf("failed to remove annotation %s: %w", key, err)
+	}
+	return nil
+}
+
+// consumeForceApply removes the force-apply annotation of an update held by the update mode or manual approval,
+// so it doesn't keep bypassing the update windows and frequency of later updates
+func (r *VerticalWorkloadAutoscalerReconciler) consumeForceApply(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, hold string) error {
+	r.recordEvent(wa, "Normal", ReasonForceApplyHeld, "force-apply consumed: "+hold)
+	return r.removeAnnotation(ctx, wa, vwav1.ForceApplyAnnotation)
+}
+
+// proposalHash returns the content hash of the resources to apply
+func proposalHash(resources map[string]corev1.ResourceRequirements) (string, error) {
+	// map keys are sorted and quantities serialized in their canonical form, so equal resources have equal hashes
+	data, err := json.Marshal(resources)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash proposed resources: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:proposalHashLength], nil
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestProposalHash(t *testing.T) {
+	hash, err := proposalHash(map[string]corev1.ResourceRequirements{"app": cpuRequests("1000m"), "sidecar": cpuRequests("50m")})
+	require.NoError(t, err)
+	assert.Len(t, hash, proposalHashLength)
+
+	// equal quantities have equal hashes
+	same, err := proposalHash(map[string]corev1.ResourceRequirements{"sidecar": cpuRequests("50m"), "app": cpuRequests("1")})
+	require.NoError(t, err)
+	assert.Equal(t, hash, same)
+
+	other, err := proposalHash(map[string]corev1.ResourceRequirements{"app": cpuRequests("1"), "sidecar": cpuRequests("100m")})
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+}
+
+func TestHandleVWAChangeManualApproval(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	updateModeOff := vpav1.UpdateModeOff
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference: vwav1.VPAReference{Name: "vpa1"},
+			Approval:     vwav1.ApprovalManual,
+		},
+	}
+	vpa := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &updateModeOff},
+			TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "web:1.0", Resources: cpuRequests("250m")},
+		}}}},
+	}
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment).Build()
+	recorder := record.NewFakeRecorder(100)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+
+	recommend := func(cpu string) {
+		stored := &vpav1.VerticalPodAutoscaler{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vpa), stored))
+		stored.Status.Recommendation = &vpav1.RecommendedPodResources{ContainerRecommendations: []vpav1.RecommendedContainerResources{
+			{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+		}}
+		require.NoError(t, c.Update(context.Background(), stored))
+	}
+	approve := func(hash string) {
+		vwa.Annotations = map[string]string{vwav1.ApproveAnnotation: hash}
+		require.NoError(t, c.Update(context.Background(), vwa))
+	}
+	reconcile := func() []string {
+		now = now.Add(10 * time.Minute)
+		_, err := r.handleVWAChange(context.Background(), vwa)
+		require.NoError(t, err)
+		// the approval is used once
+		assert.NotContains(t, vwa.Annotations, vwav1.ApproveAnnotation)
+		var reasons []string
+		for len(recorder.Events) > 0 {
+			event := <-recorder.Events
+			if reason := strings.Fields(event)[1]; strings.Contains(reason, "Approv") {
+				reasons = append(reasons, reason)
+			}
+		}
+		return reasons
+	}
+	cpu := func() string {
+		stored := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+		return stored.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String()
+	}
+
+	// the change is proposed once
+	recommend("500m")
+	assert.Equal(t, []string{ReasonApprovalRequired}, reconcile())
+	require.NotNil(t, vwa.Status.PendingProposal)
+	first := vwa.Status.PendingProposal.Hash
+	assert.Equal(t, cpuRequests("250m"), vwa.Status.PendingProposal.Containers["app"].Before)
+	assert.Equal(t, ReasonApprovalRequired, findCondition(vwa.Status.Conditions, ConditionTypeReconciled).Reason)
+	assert.Empty(t, reconcile())
+	assert.Equal(t, "250m", cpu())
+
+	// a newer recommendation replaces the proposal, and the stale hash is rejected
+	recommend("600m")
+	assert.Equal(t, []string{ReasonApprovalRequired}, reconcile())
+	second := vwa.Status.PendingProposal.Hash
+	assert.NotEqual(t, first, second)
+	approve(first)
+	assert.Equal(t, []string{ReasonApprovalRejected}, reconcile())
+	assert.Equal(t, "250m", cpu())
+	assert.Equal(t, second, vwa.Status.PendingProposal.Hash)
+
+	// the matching hash applies the change
+	approve(second)
+	assert.Equal(t, []string{ReasonProposalApproved}, reconcile())
+	assert.Equal(t, "600m", cpu())
+	assert.Nil(t, vwa.Status.PendingProposal)
+	require.Len(t, vwa.Status.Revisions, 1)
+
+	stored := &vwav1.VerticalWorkloadAutoscaler{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vwa), stored))
+	assert.Nil(t, stored.Status.PendingProposal)
+	assert.NotContains(t, stored.Annotations, vwav1.ApproveAnnotation)
+
+	// force-apply doesn't bypass the approval, and is consumed by the pending proposal
+	recommend("700m")
+	vwa.Annotations = map[string]string{vwav1.ForceApplyAnnotation: "true"}
+	require.NoError(t, c.Update(context.Background(), vwa))
+	assert.Equal(t, []string{ReasonApprovalRequired}, reconcile())
+	assert.Equal(t, "600m", cpu())
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vwa), stored))
+	assert.NotContains(t, stored.Annotations, vwav1.ForceApplyAnnotation)
+	assert.NotNil(t, stored.Status.PendingProposal)
+}
@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// blackoutSkipReasonPrefix starts the skip reason of the updates blocked by a blackout
+const blackoutSkipReasonPrefix = "updates blocked by blackout "
+
+// blackoutPeriod is a time range during which updates are not allowed
+type blackoutPeriod struct {
+	name  string
+	start time.Time
+	end   time.Time
+}
+
+// resolveBlackouts collects the blackout windows and the calendar events that are relevant at the given time,
+// and the calendar events skipped as unsupported. Calendar ConfigMaps are read from namespace; when namespace
+// is empty (cluster-scoped schedules) the namespace of each calendar reference is used.
+func (r *VerticalWorkloadAutoscalerReconciler) resolveBlackouts(ctx context.Context, namespace string, windows []vwav1.BlackoutWindow, calendars []vwav1.CalendarReference, now time.Time) ([]blackoutPeriod, []string, error) {
+	blackouts := make([]blackoutPeriod, 0, len(windows))
+	var skipped []string
+	for _, window := range windows {
+		blackouts = append(blackouts, blackoutPeriod{name: window.Name, start: window.Start.Time, end: window.End.Time})
+	}
+
+	for _, ref := range calendars {
+		calendarNamespace := namespace
+		if calendarNamespace == "" {
+			if ref.Namespace == "" {
+				return nil, nil, fmt.Errorf("calendar ConfigMap '%s' has no namespace", ref.Name)
+			}
+			calendarNamespace = ref.Namespace
+		}
+		events, skippedEvents, err := r.fetchCalendar(ctx, calendarNamespace, ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		skipped = append(skipped, skippedEvents...)
+		for _, event := range events {
+			if start, end, ok := event.occurrenceAt(now); ok {
+				blackouts = append(blackouts, blackoutPeriod{name: event.summary, start: start, end: end})
+			}
+		}
+	}
+	return blackouts, skipped, nil
+}
+
+// fetchCalendar reads and parses the iCalendar file referenced by the CalendarReference; it also returns the
+// skipped events prefixed with the calendar
+func (r *VerticalWorkloadAutoscalerReconciler) fetchCalendar(ctx context.Context, namespace string, ref vwav1.CalendarReference) ([]icalEvent, []string, error) {
+	key := ref.Key
+	if key == "" {
+		key = vwav1.DefaultCalendarKey
+	}
+
+	// calendar ConfigMaps are read uncached, so the controller doesn't cache every ConfigMap of the cluster
+	var cm corev1.ConfigMap
+	if err := r.uncachedReader().Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, &cm); err != nil {
+		return nil, nil, fmt.Errorf("failed to get calendar ConfigMap %s/%s: %w", namespace, ref.Name, err)
+	}
+	data, ok := cm.Data[key]
+	if !ok {
+		return nil, nil, fmt.Errorf("calendar ConfigMap %s/%s has no key '%s'", namespace, ref.Name, key)
+	}
+	events, skipped, err := parseICalendar(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse calendar %s/%s[%s]: %w", namespace, ref.Name, key, err)
+	}
+	for i := range skipped {
+		skipped[i] = fmt.Sprintf("%s/%s[%s] %s", namespace, ref.Name, key, skipped[i])
+	}
+	return events, skipped, nil
+}
+
+// activeBlackout returns the blackout period covering now; if several overlap, the one ending last wins
+func activeBlackout(now time.Time, blackouts []blackoutPeriod) *blackoutPeriod {
+	var active *blackoutPeriod
+	for i := range blackouts {
+		b := &blackouts[i]
+		if now.Before(b.start) || !now.Before(b.end) {
+			continue
+		}
+		if active == nil || b.end.After(active.end) {
+			active = b
+		}
+	}
+	return active
+}
+
+// shouldDelayUpdateBlackout checks if the current time is within a blackout period
+func (r *VerticalWorkloadAutoscalerReconciler) shouldDelayUpdateBlackout(blackouts []blackoutPeriod) (time.Duration, bool) {
+	now := timeNow()
+	if blackout := activeBlackout(now, blackouts); blackout != nil {
+		return blackout.end.Sub(now), true
+	}
+	return 0, false
+}
+
+// updateBlackoutStatus records the blackout that blocks updates in the VWA status, or clears it; the event is
+// only recorded when the blackout starts
+func (r *VerticalWorkloadAutoscalerReconciler) updateBlackoutStatus(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, blackout *blackoutPeriod) error {
+	if blackout == nil {
+		if wa.Status.ActiveBlackout == "" {
+			return nil
+		}
+		wa.Status.ActiveBlackout = ""
+		if strings.HasPrefix(wa.Status.SkipReason, blackoutSkipReasonPrefix) {
+			wa.Status.SkippedUpdates = false
+			wa.Status.SkipReason = ""
+		}
+		return r.Status().Update(ctx, wa)
+	}
+
+	msg := fmt.Sprintf("%s'%s' until %s", blackoutSkipReasonPrefix, blackout.name, blackout.end.Format(time.RFC3339))
+	condition := findCondition(wa.Status.Conditions, ConditionTypeReconciled)
+	if wa.Status.ActiveBlackout == blackout.name && condition != nil && condition.Reason == ReasonBlackoutWindow && condition.Message == msg {
+		return nil
+	}
+	if wa.Status.ActiveBlackout != blackout.name {
+		r.recordEvent(wa, "Normal", ReasonBlackoutWindow, msg)
+	}
+	wa.Status.ActiveBlackout = blackout.name
+	wa.Status.SkippedUpdates = true
+	wa.Status.SkipReason = msg
+	return r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionFalse, ReasonBlackoutWindow, msg)
+}
+
+// updateCalendarWarning sets the Warning condition listing the calendar events skipped as unsupported, or clears
+// it; the event is only recorded when the skipped events change
+func (r *VerticalWorkloadAutoscalerReconciler) updateCalendarWarning(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, skipped []string) error {
+	condition := findCondition(wa.Status.Conditions, ConditionTypeWarning)
+	if len(skipped) == 0 {
+		if condition == nil || condition.Reason != ReasonCalendarEventsSkipped || condition.Status == metav1.ConditionFalse {
+			return nil
+		}
+		return r.updateStatusCondition(ctx, wa, ConditionTypeWarning, metav1.ConditionFalse, ReasonCalendarEventsSkipped, "no calendar events are skipped")
+	}
+
+	msg := fmt.Sprintf("skipped unsupported calendar events: %s", strings.Join(skipped, "; "))
+	if condition != nil && condition.Status == metav1.ConditionTrue && condition.Reason == ReasonCalendarEventsSkipped && condition.Message == msg {
+		return nil
+	}
+	r.recordEvent(wa, "Warning", ReasonCalendarEventsSkipped, msg)
+	return r.updateStatusCondition(ctx, wa, ConditionTypeWarning, metav1.ConditionTrue, ReasonCalendarEventsSkipped, msg)
+}
@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+SUMMARY:Black Friday
+DTSTART:20231124T000000Z
+DTEND:20231128T000000Z
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Quarter-end close
+DTSTART;VALUE=DATE:20231229
+DTEND;VALUE=DATE:20240103
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Offsite
+DTSTART;TZID=Mars/Olympus_Mons:20231201T090000
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestResolveBlackouts(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	calendar := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "holidays", Namespace: "default"},
+		Data:       map[string]string{vwav1.DefaultCalendarKey: testCalendar, "broken.ics": "BEGIN:VEVENT\n"},
+	}
+
+	tests := []struct {
+		name            string
+		namespace       string
+		spec            vwav1.VerticalWorkloadAutoscalerSpec
+		now             time.Time
+		expectedNames   []string
+		expectedSkipped []string
+		expectError     bool
+	}{
+		{
+			name: "Inline blackout windows only",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				BlackoutWindows: []vwav1.BlackoutWindow{
+					{
+						Name:  "Release freeze",
+						Start: metav1.NewTime(time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)),
+						End:   metav1.NewTime(time.Date(2023, 12, 8, 0, 0, 0, 0, time.UTC)),
+					},
+				},
+			},
+			now:           time.Date(2023, 11, 25, 0, 0, 0, 0, time.UTC),
+			expectedNames: []string{"Release freeze"},
+		},
+		{
+			name:      "Active calendar event",
+			namespace: "default",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "holidays"}},
+			},
+			now:             time.Date(2023, 11, 25, 0, 0, 0, 0, time.UTC),
+			expectedNames:   []string{"Black Friday"},
+			expectedSkipped: []string{`default/holidays[calendar.ics] "Offsite": invalid DTSTART "20231201T090000": unknown time zone "Mars/Olympus_Mons"`},
+		},
+		{
+			name:      "No active calendar event",
+			namespace: "default",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "holidays"}},
+			},
+			now:           time.Date(2023, 12, 10, 0, 0, 0, 0, time.UTC),
+			expectedNames: []string{},
+		},
+		{
+			name: "Calendar namespace from reference",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "holidays", Namespace: "default"}},
+			},
+			now:           time.Date(2023, 12, 30, 0, 0, 0, 0, time.UTC),
+			expectedNames: []string{"Quarter-end close"},
+		},
+		{
+			name:      "Calendar namespace from reference ignored for namespaced objects",
+			namespace: "other",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "holidays", Namespace: "default"}},
+			},
+			now:         time.Date(2023, 12, 30, 0, 0, 0, 0, time.UTC),
+			expectError: true,
+		},
+		{
+			name:      "Missing calendar ConfigMap",
+			namespace: "default",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "missing"}},
+			},
+			now:         time.Date(2023, 11, 25, 0, 0, 0, 0, time.UTC),
+			expectError: true,
+		},
+		{
+			name:      "Missing calendar key",
+			namespace: "default",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "holidays", Key: "other.ics"}},
+			},
+			now:         time.Date(2023, 11, 25, 0, 0, 0, 0, time.UTC),
+			expectError: true,
+		},
+		{
+			name:      "Invalid calendar",
+			namespace: "default",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "holidays", Key: "broken.ics"}},
+			},
+			now:         time.Date(2023, 11, 25, 0, 0, 0, 0, time.UTC),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// calendars are read with the API reader, not from the cache
+			apiReader := fake.NewClientBuilder().WithScheme(scheme).WithObjects([]client.Object{calendar}...).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), APIReader: apiReader}
+			blackouts, skipped, err := r.resolveBlackouts(context.Background(), tt.namespace, tt.spec.BlackoutWindows, tt.spec.BlackoutCalendars, tt.now)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			names := make([]string, 0, len(blackouts))
+			for _, b := range blackouts {
+				names = append(names, b.name)
+			}
+			assert.Equal(t, tt.expectedNames, names)
+			if tt.expectedSkipped != nil {
+				assert.Equal(t, tt.expectedSkipped, skipped)
+			}
+		})
+	}
+}
+
+func TestActiveBlackout(t *testing.T) {
+	blackouts := []blackoutPeriod{
+		{name: "short", start: time.Date(2023, 10, 10, 0, 0, 0, 0, time.UTC), end: time.Date(2023, 10, 11, 0, 0, 0, 0, time.UTC)},
+		{name: "long", start: time.Date(2023, 10, 9, 0, 0, 0, 0, time.UTC), end: time.Date(2023, 10, 15, 0, 0, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		name         string
+		now          time.Time
+		expectedName string
+	}{
+		{name: "Overlapping blackouts pick the one ending last", now: time.Date(2023, 10, 10, 12, 0, 0, 0, time.UTC), expectedName: "long"},
+		{name: "Single active blackout", now: time.Date(2023, 10, 12, 0, 0, 0, 0, time.UTC), expectedName: "long"},
+		{name: "End is exclusive", now: time.Date(2023, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "Before all blackouts", now: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active := activeBlackout(tt.now, blackouts)
+			if tt.expectedName == "" {
+				assert.Nil(t, active)
+				return
+			}
+			assert.NotNil(t, active)
+			assert.Equal(t, tt.expectedName, active.name)
+		})
+	}
+}
+
+func TestUpdateBlackoutStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	timeNow = func() time.Time { return time.Date(2023, 11, 25, 0, 0, 0, 0, time.UTC) }
+
+	wa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(wa).WithObjects(wa).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+
+	// the event is recorded once per blackout
+	blackout := &blackoutPeriod{name: "Black Friday", start: time.Date(2023, 11, 24, 0, 0, 0, 0, time.UTC), end: time.Date(2023, 11, 28, 0, 0, 0, 0, time.UTC)}
+	for range 2 {
+		assert.NoError(t, r.updateBlackoutStatus(context.Background(), wa, blackout))
+	}
+	assert.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Black Friday", wa.Status.ActiveBlackout)
+	assert.True(t, wa.Status.SkippedUpdates)
+	condition := findCondition(wa.Status.Conditions, ConditionTypeReconciled)
+	assert.NotNil(t, condition)
+	assert.Equal(t, ReasonBlackoutWindow, condition.Reason)
+
+	assert.NoError(t, r.updateBlackoutStatus(context.Background(), wa, nil))
+	assert.Empty(t, wa.Status.ActiveBlackout)
+	assert.False(t, wa.Status.SkippedUpdates)
+	assert.Empty(t, wa.Status.SkipReason)
+}
+
+func TestUpdateCalendarWarning(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+
+	wa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(wa).WithObjects(wa).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+
+	// nothing is written without skipped events
+	assert.NoError(t, r.updateCalendarWarning(context.Background(), wa, nil))
+	assert.Nil(t, findCondition(wa.Status.Conditions, ConditionTypeWarning))
+
+	// the event is recorded once for the same skipped events
+	skipped := []string{`default/holidays[calendar.ics] "Last workday": invalid RRULE "FREQ=MONTHLY;BYSETPOS=-1": unsupported rule part "BYSETPOS"`}
+	for range 2 {
+		assert.NoError(t, r.updateCalendarWarning(context.Background(), wa, skipped))
+	}
+	assert.Len(t, recorder.Events, 1)
+	condition := findCondition(wa.Status.Conditions, ConditionTypeWarning)
+	assert.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, ReasonCalendarEventsSkipped, condition.Reason)
+	assert.Contains(t, condition.Message, `"Last workday"`)
+
+	assert.NoError(t, r.updateCalendarWarning(context.Background(), wa, nil))
+	condition = findCondition(wa.Status.Conditions, ConditionTypeWarning)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+}
@@ -17,6 +17,14 @@ const (
 	ConditionTypeError = "Error"
 	// ConditionTypeReconciled is the condition type for reconciliation
 	ConditionTypeReconciled = "Reconciled"
+	// ConditionTypeFrozen is the condition type for a VWA held by a change freeze
+	ConditionTypeFrozen = "Frozen"
+	// ConditionTypeSuspended is the condition type for a VWA with suspended updates
+	ConditionTypeSuspended = "Suspended"
+	// ConditionTypeDrifted is the condition type for applied resources reverted by another field manager
+	ConditionTypeDrifted = "Drifted"
+	// ConditionTypeHPASaturated is the condition type for an HPA at its maxReplicas with its metrics over their targets
+	ConditionTypeHPASaturated = "HPASaturated"
 	// ReasonVPAReferenceConflict is the condition reason for VPA reference conflict
 	ReasonVPAReferenceConflict = "VPAReferenceConflict"
 	// ReasonVPAReferenceNotFound is the condition reason for VPA reference not found
@@ -37,6 +45,80 @@ const (
 	ReasonUpdatedResources = "UpdatedResources"
 	// ReasonWaitingForRecommendations reason waiting for recommendations
 	ReasonWaitingForRecommendations = "WaitingForRecommendations"
+	// ReasonBlackoutWindow is the condition reason for updates blocked by a blackout window
+	ReasonBlackoutWindow = "BlackoutWindow"
+	// ReasonChangesDeferred is the condition reason for resource changes deferred until their update window
+	ReasonChangesDeferred = "ChangesDeferred"
+	// ReasonChangeFreeze is the condition reason for an active change freeze
+	ReasonChangeFreeze = "ChangeFreeze"
+	// ReasonNoChangeFreeze is the condition reason for a lifted change freeze
+	ReasonNoChangeFreeze = "NoChangeFreeze"
+	// ReasonInvalidSchedule is the condition reason for an unreadable update schedule or blackout calendar
+	ReasonInvalidSchedule = "InvalidSchedule"
+	// ReasonTargetMismatch is the condition reason for a VPA targeting another workload than the VWA targetRef
+	ReasonTargetMismatch = "TargetMismatch"
+	// ReasonFieldManagerConflict is the event reason for target fields owned by another field manager
+	ReasonFieldManagerConflict = "FieldManagerConflict"
+	// ReasonResourcesReverted is the condition reason for applied resources reverted by another field manager
+	ReasonResourcesReverted = "ResourcesReverted"
+	// ReasonResourcesRestored is the event reason for original resources restored on VWA deletion
+	ReasonResourcesRestored = "ResourcesRestored"
+	// ReasonResourcesCommitted is the event reason for resources committed to the Git write-back repository
+	ReasonResourcesCommitted = "ResourcesCommitted"
+	// ReasonResourcesSynced is the event reason for committed resources observed on the target object
+	ReasonResourcesSynced = "ResourcesSynced"
+	// ReasonWaitingForSync is the condition reason for committed resources not yet synced to the target object
+	ReasonWaitingForSync = "WaitingForSync"
+	// ReasonGitOpsConflict is the condition and conflict reason for resources reverted too often to keep re-applying them
+	ReasonGitOpsConflict = "GitOpsConflict"
+	// ReasonRolledBack is the condition and event reason for target resources held at an earlier revision
+	ReasonRolledBack = "RolledBack"
+	// ReasonRollbackPending is the condition and event reason for a rollback held by a change freeze, suspension or blackout
+	ReasonRollbackPending = "RollbackPending"
+	// ReasonRevisionNotFound is the condition reason for a spec.rollbackTo revision missing from the revision history
+	ReasonRevisionNotFound = "RevisionNotFound"
+	// ReasonApprovalRequired is the condition and event reason for a resource change waiting for approval
+	ReasonApprovalRequired = "ApprovalRequired"
+	// ReasonProposalApproved is the event reason for an approved resource change
+	ReasonProposalApproved = "ProposalApproved"
+	// ReasonApprovalRejected is the event reason for an approval not matching the pending proposal
+	ReasonApprovalRejected = "ApprovalRejected"
+	// ReasonSuspended is the condition and event reason for suspended updates
+	ReasonSuspended = "Suspended"
+	// ReasonResumed is the condition and event reason for resumed updates
+	ReasonResumed = "Resumed"
+	// ReasonForceApplied is the condition and event reason for an update bypassing the update windows and frequency
+	ReasonForceApplied = "ForceApplied"
+	// ReasonForceApplyHeld is the event reason for a force-apply consumed by an update the update mode or approval holds
+	ReasonForceApplyHeld = "ForceApplyHeld"
+	// ReasonAppliedOnce is the condition reason for updates stopped after the first one by the Once update mode
+	ReasonAppliedOnce = "AppliedOnce"
+	// ReasonWaitingForRollout is the condition reason for updates held until the next rollout by the OnRollout update mode
+	ReasonWaitingForRollout = "WaitingForRollout"
+	// ReasonHPAResourceMetric is the conflict reason for an HPA scaling the target workload on a resource metric
+	ReasonHPAResourceMetric = "HPAResourceMetric"
+	// ReasonHPACustomMetric is the conflict reason for an HPA scaling the target workload on Pods, Object or External metrics
+	ReasonHPACustomMetric = "HPACustomMetric"
+	// ReasonMultipleHPAs is the conflict reason for more than one HPA scaling the target workload
+	ReasonMultipleHPAs = "MultipleHPAs"
+	// ReasonDuplicateTarget is the conflict reason for another VWA updating the target workload through another VPA
+	ReasonDuplicateTarget = "DuplicateTarget"
+	// ReasonKEDAScaledObject is the conflict reason for a KEDA ScaledObject scaling the target workload
+	ReasonKEDAScaledObject = "KEDAScaledObject"
+	// ReasonKEDAScaledJob is the conflict reason for a KEDA ScaledJob controlling the target Job
+	ReasonKEDAScaledJob = "KEDAScaledJob"
+	// ReasonHPATargetsCoordinated is the event reason for HPA utilization targets scaled with the requests
+	ReasonHPATargetsCoordinated = "HPATargetsCoordinated"
+	// ReasonHPAAtMaxReplicas is the condition reason for a saturated HPA before the CPU increases are applied
+	ReasonHPAAtMaxReplicas = "HPAAtMaxReplicas"
+	// ReasonSaturationScaleUp is the condition and event reason for CPU increases applied while the HPA is saturated
+	ReasonSaturationScaleUp = "SaturationScaleUp"
+	// ReasonHPANotSaturated is the condition and event reason for an HPA back below its maxReplicas
+	ReasonHPANotSaturated = "HPANotSaturated"
+	// ReasonNoDrift is the condition reason for no reverts within the drift period
+	ReasonNoDrift = "NoDrift"
+	// ReasonCalendarEventsSkipped is the condition and event reason for unsupported blackout calendar events
+	ReasonCalendarEventsSkipped = "CalendarEventsSkipped"
 )
 
 // updateStatusCondition updates the VWA status with a new condition
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// conflictResourceReplicas is the conflict resource of controllers scaling the replicas of the target workload
+	conflictResourceReplicas = "replicas"
+	// conflictResourceResources is the conflict resource of controllers changing all the container resources
+	conflictResourceResources = "resources"
+)
+
+// updateConflicts replaces the detected conflicts of the VWA status, keeping the GitOps conflicts recorded by
+// drift detection while it is on, and records an event for every new conflict
+func (r *VerticalWorkloadAutoscalerReconciler) updateConflicts(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, vpa *vpav1.VerticalPodAutoscaler) error {
+	detected, err := r.detectConflicts(ctx, wa, vpa)
+	if err != nil {
+		return err
+	}
+	detectsDrift := r.detectsDrift(wa)
+	conflicts := slices.DeleteFunc(slices.Clone(wa.Status.Conflicts), func(c vwav1.Conflict) bool {
+		return c.Reason != ReasonGitOpsConflict || !detectsDrift
+	})
+	conflicts = append(conflicts, detected...)
+	if equality.Semantic.DeepEqual(conflicts, wa.Status.Conflicts) || (len(conflicts) == 0 && len(wa.Status.Conflicts) == 0) {
+		return nil
+	}
+
+	for _, conflict := range detected {
+		if !slices.Contains(wa.Status.Conflicts, conflict) {
+			r.recordEvent(wa, "Warning", conflict.Reason, fmt.Sprintf("%s conflict with %s", conflict.Resource, conflict.ConflictWith))
+		}
+	}
+	wa.Status.Conflicts = conflicts
+	return r.Status().Update(ctx, wa)
+}
+
+// detectConflicts returns the conflicts of the VWA with other controllers scaling its target workload
+func (r *VerticalWorkloadAutoscalerReconciler) detectConflicts(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, vpa *vpav1.VerticalPodAutoscaler) ([]vwav1.Conflict, error) {
+	var conflicts []vwav1.Conflict
+	if vpa.Spec.UpdatePolicy == nil || vpa.Spec.UpdatePolicy.UpdateMode == nil || *vpa.Spec.UpdatePolicy.UpdateMode != vpav1.UpdateModeOff {
+		conflicts = append(conflicts, vwav1.Conflict{
+			Resource:     conflictResourceResources,
+			ConflictWith: "VerticalPodAutoscaler/" + vpa.Name,
+			Reason:       ReasonUpdateModeNotOff,
+		})
+	}
+	if vpa.Spec.TargetRef == nil {
+		return conflicts, nil
+	}
+	kind, name := vpa.Spec.TargetRef.Kind, vpa.Spec.TargetRef.Name
+
+	// HPAs scaling on CPU or memory utilization fight with vertical scaling of the same resource
+	var hpaList autoscalingv2.HorizontalPodAutoscalerList
+	if err := r.List(ctx, &hpaList, client.InNamespace(wa.Namespace)); err != nil {
+		return nil, err
+	}
+	var hpas []string
+	for _, hpa := range hpaList.Items {
+		if hpa.Spec.ScaleTargetRef.Kind != kind || hpa.Spec.ScaleTargetRef.Name != name {
+			continue
+		}
+		hpas = append(hpas, "HorizontalPodAutoscaler/"+hpa.Name)
+		// vertical changes change the throughput per pod the custom metrics depend on
+		if hasCustomMetrics(&hpa) {
+			conflicts = append(conflicts, vwav1.Conflict{
+				Resource:     conflictResourceResources,
+				ConflictWith: "HorizontalPodAutoscaler/" + hpa.Name,
+				Reason:       ReasonHPACustomMetric,
+			})
+		}
+		// the VWA scales the utilization targets of the resource metrics with the requests
+		if r.coordinatesHPA(wa, &hpa) {
+			continue
+		}
+		ignoreCPU, ignoreMemory := r.getIgnoreFlags(&hpa)
+		for resource, ignored := range map[corev1.ResourceName]bool{corev1.ResourceCPU: ignoreCPU, corev1.ResourceMemory: ignoreMemory} {
+			if ignored {
+				conflicts = append(conflicts, vwav1.Conflict{
+					Resource:     string(resource),
+					ConflictWith: "HorizontalPodAutoscaler/" + hpa.Name,
+					Reason:       ReasonHPAResourceMetric,
+				})
+			}
+		}
+		for _, container := range r.getContainerIgnores(&hpa) {
+			for resource, ignored := range map[corev1.ResourceName]bool{corev1.ResourceCPU: container.CPU, corev1.ResourceMemory: container.Memory} {
+				if ignored {
+					conflicts = append(conflicts, vwav1.Conflict{
+						Resource:     container.Name + "/" + string(resource),
+						ConflictWith: "HorizontalPodAutoscaler/" + hpa.Name,
+						Reason:       ReasonHPAResourceMetric,
+					})
+				}
+			}
+		}
+	}
+	if len(hpas) > 1 {
+		conflicts = append(conflicts, vwav1.Conflict{
+			Resource:     conflictResourceReplicas,
+			ConflictWith: strings.Join(hpas, ","),
+			Reason:       ReasonMultipleHPAs,
+		})
+	}
+
+	// another VWA updating the same workload through another VPA
+	var vwaList vwav1.VerticalWorkloadAutoscalerList
+	if err := r.List(ctx, &vwaList, client.InNamespace(wa.Namespace)); err != nil {
+		return nil, err
+	}
+	for _, other := range vwaList.Items {
+		if other.Name != wa.Name && other.Spec.VPAReference.Name != wa.Spec.VPAReference.Name &&
+			other.Status.ScaleTargetRef.Kind == kind && other.Status.ScaleTargetRef.Name == name {
+			conflicts = append(conflicts, vwav1.Conflict{
+				Resource:     conflictResourceResources,
+				ConflictWith: "VerticalWorkloadAutoscaler/" + other.Name,
+				Reason:       ReasonDuplicateTarget,
+			})
+		}
+	}
+
+	keda, err := r.kedaConflicts(ctx, wa.Namespace, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	conflicts = append(conflicts, keda...)
+
+	slices.SortFunc(conflicts, func(a, b vwav1.Conflict) int {
+		return strings.Compare(a.Reason+a.ConflictWith+a.Resource, b.Reason+b.ConflictWith+b.Resource)
+	})
+	return conflicts, nil
+}
+
+// kedaConflicts returns the conflicts with the KEDA ScaledObjects and ScaledJobs scaling the workload
+func (r *VerticalWorkloadAutoscalerReconciler) kedaConflicts(ctx context.Context, namespace, kind, name string) ([]vwav1.Conflict, error) {
+	scalers, err := r.findKEDAScalers(ctx, namespace, kind, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []vwav1.Conflict
+	for _, scaler := range scalers {
+		reason := ReasonKEDAScaledObject
+		if scaler.kind == scaledJobGVK.Kind {
+			reason = ReasonKEDAScaledJob
+		}
+		conflicts = append(conflicts, vwav1.Conflict{Resource: conflictResourceReplicas, ConflictWith: scaler.ref(), Reason: reason})
+		for resource, triggered := range map[corev1.ResourceName]bool{corev1.ResourceCPU: scaler.cpu, corev1.ResourceMemory: scaler.memory} {
+			if triggered {
+				conflicts = append(conflicts, vwav1.Conflict{Resource: string(resource), ConflictWith: scaler.ref(), Reason: reason})
+			}
+		}
+		for _, container := range scaler.containers {
+			for resource, triggered := range map[corev1.ResourceName]bool{corev1.ResourceCPU: container.CPU, corev1.ResourceMemory: container.Memory} {
+				if triggered {
+					conflicts = append(conflicts, vwav1.Conflict{Resource: container.Name + "/" + string(resource), ConflictWith: scaler.ref(), Reason: reason})
+				}
+			}
+		}
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// conflictTestVPA returns a VPA targeting the web Deployment with the update mode
+func conflictTestVPA(mode vpav1.UpdateMode) *vpav1.VerticalPodAutoscaler {
+	return &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &mode},
+			TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+	}
+}
+
+// addKEDAToScheme registers the KEDA kinds as unstructured
+func addKEDAToScheme(scheme *runtime.Scheme) {
+	for _, gvk := range []schema.GroupVersionKind{scaledObjectGVK, scaledJobGVK} {
+		scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+}
+
+// kedaObject returns a KEDA object of the kind with the triggers; the ScaledObject scales the web workload of
+// the target kind and a trigger "cpu:app" sets the containerName
+func kedaObject(gvk schema.GroupVersionKind, name, targetKind string, triggers ...string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace("default")
+	spec := map[string]interface{}{}
+	if gvk == scaledObjectGVK {
+		target := map[string]interface{}{"name": "web"}
+		if targetKind != "" {
+			target["kind"] = targetKind
+		}
+		spec["scaleTargetRef"] = target
+	}
+	var items []interface{}
+	for _, trigger := range triggers {
+		triggerType, container, _ := strings.Cut(trigger, ":")
+		item := map[string]interface{}{"type": triggerType}
+		if container != "" {
+			item["metadata"] = map[string]interface{}{"containerName": container}
+		}
+		items = append(items, item)
+	}
+	spec["triggers"] = items
+	obj.Object["spec"] = spec
+	return obj
+}
+
+// resourceMetricHPA returns an HPA scaling the web Deployment on the resource metrics
+func resourceMetricHPA(name string, resources ...corev1.ResourceName) *autoscalingv2.HorizontalPodAutoscaler {
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+	}
+	for _, resource := range resources {
+		hpa.Spec.Metrics = append(hpa.Spec.Metrics, autoscalingv2.MetricSpec{
+			Type:     autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{Name: resource},
+		})
+	}
+	return hpa
+}
+
+func TestDetectConflicts(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	addKEDAToScheme(scheme)
+
+	scaledObject := func(kind string, triggers ...string) *unstructured.Unstructured {
+		return kedaObject(scaledObjectGVK, "web-keda", kind, triggers...)
+	}
+	otherVWA := func(vpaName string) *vwav1.VerticalWorkloadAutoscaler {
+		return &vwav1.VerticalWorkloadAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+			Spec:       vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: vpaName}},
+			Status: vwav1.VerticalWorkloadAutoscalerStatus{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		mode     vpav1.UpdateMode
+		objects  []client.Object
+		expected []vwav1.Conflict
+	}{
+		{
+			name: "No conflicts",
+			mode: vpav1.UpdateModeOff,
+			objects: []client.Object{
+				resourceMetricHPA("web-hpa"),
+				// another VWA sharing the VPA is rejected by the duplicate check instead
+				otherVWA("vpa1"),
+			},
+		},
+		{
+			name: "VPA updating the pods itself",
+			mode: vpav1.UpdateModeAuto,
+			expected: []vwav1.Conflict{
+				{Resource: "resources", ConflictWith: "VerticalPodAutoscaler/vpa1", Reason: ReasonUpdateModeNotOff},
+			},
+		},
+		{
+			name:    "HPAs on resource metrics",
+			mode:    vpav1.UpdateModeOff,
+			objects: []client.Object{resourceMetricHPA("cpu-hpa", corev1.ResourceCPU), resourceMetricHPA("memory-hpa", corev1.ResourceMemory)},
+			expected: []vwav1.Conflict{
+				{Resource: "cpu", ConflictWith: "HorizontalPodAutoscaler/cpu-hpa", Reason: ReasonHPAResourceMetric},
+				{Resource: "memory", ConflictWith: "HorizontalPodAutoscaler/memory-hpa", Reason: ReasonHPAResourceMetric},
+				{Resource: "replicas", ConflictWith: "HorizontalPodAutoscaler/cpu-hpa,HorizontalPodAutoscaler/memory-hpa", Reason: ReasonMultipleHPAs},
+			},
+		},
+		{
+			name:    "HPA on ContainerResource and custom metrics",
+			mode:    vpav1.UpdateModeOff,
+			objects: []client.Object{metricHPA(containerResourceMetric("app", corev1.ResourceMemory), podsMetric)},
+			expected: []vwav1.Conflict{
+				{Resource: "resources", ConflictWith: "HorizontalPodAutoscaler/web-hpa", Reason: ReasonHPACustomMetric},
+				{Resource: "app/memory", ConflictWith: "HorizontalPodAutoscaler/web-hpa", Reason: ReasonHPAResourceMetric},
+			},
+		},
+		{
+			name:    "Another VWA targeting the workload",
+			mode:    vpav1.UpdateModeOff,
+			objects: []client.Object{otherVWA("vpa2")},
+			expected: []vwav1.Conflict{
+				{Resource: "resources", ConflictWith: "VerticalWorkloadAutoscaler/other", Reason: ReasonDuplicateTarget},
+			},
+		},
+		{
+			name:    "KEDA ScaledObject",
+			mode:    vpav1.UpdateModeOff,
+			objects: []client.Object{scaledObject("", "cpu", "prometheus")},
+			expected: []vwav1.Conflict{
+				{Resource: "cpu", ConflictWith: "ScaledObject/web-keda", Reason: ReasonKEDAScaledObject},
+				{Resource: "replicas", ConflictWith: "ScaledObject/web-keda", Reason: ReasonKEDAScaledObject},
+			},
+		},
+		{
+			name:    "KEDA ScaledObject with a container trigger",
+			mode:    vpav1.UpdateModeOff,
+			objects: []client.Object{scaledObject("Deployment", "memory:app")},
+			expected: []vwav1.Conflict{
+				{Resource: "app/memory", ConflictWith: "ScaledObject/web-keda", Reason: ReasonKEDAScaledObject},
+				{Resource: "replicas", ConflictWith: "ScaledObject/web-keda", Reason: ReasonKEDAScaledObject},
+			},
+		},
+		{
+			name:    "KEDA ScaledObject of another kind",
+			mode:    vpav1.UpdateModeOff,
+			objects: []client.Object{scaledObject("StatefulSet", "cpu")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Spec:       vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "vpa1"}},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(tt.objects, wa)...).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+			conflicts, err := r.detectConflicts(context.Background(), wa, conflictTestVPA(tt.mode))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, conflicts)
+		})
+	}
+}
+
+func TestUpdateConflicts(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	gitOpsConflict := vwav1.Conflict{Resource: "memory", ConflictWith: "argocd-controller", Reason: ReasonGitOpsConflict}
+	wa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec:       vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "vpa1"}},
+		Status:     vwav1.VerticalWorkloadAutoscalerStatus{Conflicts: []vwav1.Conflict{gitOpsConflict}},
+	}
+	hpa := resourceMetricHPA("web-hpa", corev1.ResourceCPU)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(wa).WithObjects(wa, hpa).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+	hpaConflict := vwav1.Conflict{Resource: "cpu", ConflictWith: "HorizontalPodAutoscaler/web-hpa", Reason: ReasonHPAResourceMetric}
+
+	// a new conflict is recorded once, next to the GitOps conflict
+	for range 2 {
+		require.NoError(t, r.updateConflicts(context.Background(), wa, conflictTestVPA(vpav1.UpdateModeOff)))
+		assert.Equal(t, []vwav1.Conflict{gitOpsConflict, hpaConflict}, wa.Status.Conflicts)
+	}
+	assert.Len(t, recorder.Events, 1)
+
+	// a resolved conflict is cleared
+	require.NoError(t, c.Delete(context.Background(), hpa))
+	require.NoError(t, r.updateConflicts(context.Background(), wa, conflictTestVPA(vpav1.UpdateModeOff)))
+	stored := &vwav1.VerticalWorkloadAutoscaler{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(wa), stored))
+	assert.Equal(t, []vwav1.Conflict{gitOpsConflict}, stored.Status.Conflicts)
+
+	// the GitOps conflict is dropped once drift detection is off
+	wa.Spec.ApplyMethod = vwav1.ApplyMethodAdmission
+	require.NoError(t, r.updateConflicts(context.Background(), wa, conflictTestVPA(vpav1.UpdateModeOff)))
+	assert.Empty(t, wa.Status.Conflicts)
+}
@@ -0,0 +1,21 @@
+package controller
+
+import (
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+)
+
+// specDefaults returns the controller spec defaults, falling back to the built-in ones
+func (r *VerticalWorkloadAutoscalerReconciler) specDefaults() vwav1.SpecDefaults {
+	if r.Defaults == nil {
+		return vwav1.NewSpecDefaults()
+	}
+	return *r.Defaults
+}
+
+// effectiveSpec returns a copy of the VWA spec with all unset fields set to the controller defaults;
+// VWAs stored before the defaulting webhook was enabled may still miss some of them
+func (r *VerticalWorkloadAutoscalerReconciler) effectiveSpec(wa *vwav1.VerticalWorkloadAutoscaler) vwav1.VerticalWorkloadAutoscalerSpec {
+	spec := wa.Spec.DeepCopy()
+	r.specDefaults().Apply(spec)
+	return *spec
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestEffectiveSpec(t *testing.T) {
+	driftPolicy := &vwav1.DriftPolicy{MaxReverts: ptr.To(int32(3)), Period: &metav1.Duration{Duration: time.Hour}}
+	overrides := vwav1.SpecDefaults{
+		UpdateFrequency:  time.Hour,
+		QualityOfService: vwav1.BurstableQualityOfService,
+		CPUTolerance:     20,
+		MemoryTolerance:  30,
+	}
+
+	tests := []struct {
+		name     string
+		defaults *vwav1.SpecDefaults
+		spec     vwav1.VerticalWorkloadAutoscalerSpec
+		expected vwav1.VerticalWorkloadAutoscalerSpec
+	}{
+		{
+			name: "Built-in defaults",
+			expected: vwav1.VerticalWorkloadAutoscalerSpec{
+				UpdateFrequency:      &metav1.Duration{Duration: 5 * time.Minute},
+				QualityOfService:     vwav1.GuaranteedQualityOfService,
+				UpdateTolerance:      &vwav1.UpdateTolerance{CPU: ptr.To(10), Memory: ptr.To(10)},
+				DriftPolicy:          driftPolicy,
+				DeletionPolicy:       vwav1.DeletionPolicyRetain,
+				ApplyMethod:          vwav1.ApplyMethodTemplate,
+				UpdateMode:           vwav1.UpdateModeAuto,
+				Approval:             vwav1.ApprovalAutomatic,
+				RevisionHistoryLimit: ptr.To(int32(10)),
+			},
+		},
+		{
+			name:     "Controller defaults",
+			defaults: &overrides,
+			expected: vwav1.VerticalWorkloadAutoscalerSpec{
+				UpdateFrequency:      &metav1.Duration{Duration: time.Hour},
+				QualityOfService:     vwav1.BurstableQualityOfService,
+				UpdateTolerance:      &vwav1.UpdateTolerance{CPU: ptr.To(20), Memory: ptr.To(30)},
+				DriftPolicy:          driftPolicy,
+				DeletionPolicy:       vwav1.DeletionPolicyRetain,
+				ApplyMethod:          vwav1.ApplyMethodTemplate,
+				UpdateMode:           vwav1.UpdateModeAuto,
+				Approval:             vwav1.ApprovalAutomatic,
+				RevisionHistoryLimit: ptr.To(int32(10)),
+			},
+		},
+		{
+			name:     "Explicit zero tolerance",
+			defaults: &overrides,
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				UpdateTolerance: &vwav1.UpdateTolerance{CPU: ptr.To(0), Memory: ptr.To(0)},
+			},
+			expected: vwav1.VerticalWorkloadAutoscalerSpec{
+				UpdateFrequency:      &metav1.Duration{Duration: time.Hour},
+				QualityOfService:     vwav1.BurstableQualityOfService,
+				UpdateTolerance:      &vwav1.UpdateTolerance{CPU: ptr.To(0), Memory: ptr.To(0)},
+				DriftPolicy:          driftPolicy,
+				DeletionPolicy:       vwav1.DeletionPolicyRetain,
+				ApplyMethod:          vwav1.ApplyMethodTemplate,
+				UpdateMode:           vwav1.UpdateModeAuto,
+				Approval:             vwav1.ApprovalAutomatic,
+				RevisionHistoryLimit: ptr.To(int32(10)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &VerticalWorkloadAutoscalerReconciler{Defaults: tt.defaults}
+			wa := &vwav1.VerticalWorkloadAutoscaler{Spec: tt.spec}
+			assert.Equal(t, tt.expected, r.effectiveSpec(wa))
+			// the VWA itself is left untouched
+			assert.Equal(t, tt.spec, wa.Spec)
+		})
+	}
+}
@@ -10,12 +10,12 @@ var (
 	timeNow = time.Now
 )
 
-// shouldDelayUpdateWindow checks if the current time is within the allowed update window
-func (r *VerticalWorkloadAutoscalerReconciler) shouldDelayUpdateWindow(wa vwav1.VerticalWorkloadAutoscaler) (time.Duration, bool) {
+// shouldDelayUpdateWindow checks if the current time is within one of the allowed update windows
+func (r *VerticalWorkloadAutoscalerReconciler) shouldDelayUpdateWindow(windows []vwav1.UpdateWindow) (time.Duration, bool) {
 	now := timeNow()
 
 	// If no allowed update windows are set, update immediately
-	if len(wa.Spec.AllowedUpdateWindows) == 0 {
+	if len(windows) == 0 {
 		return 0, false
 	}
 
@@ -33,7 +33,7 @@ func (r *VerticalWorkloadAutoscalerReconciler) shouldDelayUpdateWindow(wa vwav1.
 		"Saturday":  time.Saturday,
 	}
 
-	for _, window := range wa.Spec.AllowedUpdateWindows {
+	for _, window := range windows {
 		start, end, err := parseWindowTimes(now, window)
 		if err != nil {
 			continue
@@ -66,6 +66,24 @@ func (r *VerticalWorkloadAutoscalerReconciler) shouldDelayUpdateWindow(wa vwav1.
 	return nextUpdate.Sub(now), true
 }
 
+// windowsForDirection returns the update windows that gate resource changes in the given direction
+func windowsForDirection(windows []vwav1.UpdateWindow, direction vwav1.UpdateDirection) []vwav1.UpdateWindow {
+	result := make([]vwav1.UpdateWindow, 0, len(windows))
+	for _, window := range windows {
+		if window.Direction == "" || window.Direction == vwav1.UpdateDirectionBoth || window.Direction == direction {
+			result = append(result, window)
+		}
+	}
+	return result
+}
+
+// shouldDelayUpdateDirection checks the update windows for resource increases and decreases separately
+func (r *VerticalWorkloadAutoscalerReconciler) shouldDelayUpdateDirection(windows []vwav1.UpdateWindow) (increaseDelay, decreaseDelay time.Duration) {
+	increaseDelay, _ = r.shouldDelayUpdateWindow(windowsForDirection(windows, vwav1.UpdateDirectionIncrease))
+	decreaseDelay, _ = r.shouldDelayUpdateWindow(windowsForDirection(windows, vwav1.UpdateDirectionDecrease))
+	return increaseDelay, decreaseDelay
+}
+
 func parseWindowTimes(now time.Time, window vwav1.UpdateWindow) (time.Time, time.Time, error) {
 	loc, err := time.LoadLocation(window.TimeZone)
 	if err != nil {
@@ -95,7 +113,7 @@ func (r *VerticalWorkloadAutoscalerReconciler) shouldDelayUpdateFrequency(wa vwa
 	}
 
 	now := timeNow()
-	nextUpdate := wa.Status.LastUpdated.Add(wa.Spec.UpdateFrequency.Duration)
+	nextUpdate := wa.Status.LastUpdated.Add(r.effectiveSpec(&wa).UpdateFrequency.Duration)
 	if now.Before(nextUpdate) {
 		return nextUpdate.Sub(now), true
 	}
@@ -103,11 +121,19 @@ func (r *VerticalWorkloadAutoscalerReconciler) shouldDelayUpdateFrequency(wa vwa
 	return 0, false
 }
 
-func (r *VerticalWorkloadAutoscalerReconciler) shouldDelayUpdate(wa vwav1.VerticalWorkloadAutoscaler) (time.Duration, bool) {
-	if delay, shouldDelay := r.shouldDelayUpdateWindow(wa); shouldDelay {
+// shouldDelayUpdate checks blackout periods, allowed update windows and update frequency of the
+// effective schedule; blackout periods take precedence over allowed update windows
+func (r *VerticalWorkloadAutoscalerReconciler) shouldDelayUpdate(wa vwav1.VerticalWorkloadAutoscaler, schedule updateSchedule) (time.Duration, bool) {
+	if delay, shouldDelay := r.shouldDelayUpdateBlackout(schedule.blackouts); shouldDelay {
 		return delay, true
 	}
 
+	// delay only if changes in both directions are outside their update windows;
+	// otherwise, changes in the blocked direction are deferred after calculating new resources
+	if increaseDelay, decreaseDelay := r.shouldDelayUpdateDirection(schedule.windows); increaseDelay > 0 && decreaseDelay > 0 {
+		return min(increaseDelay, decreaseDelay), true
+	}
+
 	if delay, shouldDelay := r.shouldDelayUpdateFrequency(wa); shouldDelay {
 		return delay, true
 	}
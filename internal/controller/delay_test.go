@@ -215,7 +215,7 @@ func TestShouldDelayUpdateWindow(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			timeNow = func() time.Time { return tt.currentTime }
 			r := &VerticalWorkloadAutoscalerReconciler{}
-			delay, result := r.shouldDelayUpdateWindow(tt.wa)
+			delay, result := r.shouldDelayUpdateWindow(tt.wa.Spec.AllowedUpdateWindows)
 			assert.Equal(t, tt.expectedDelay, delay)
 			assert.Equal(t, tt.expectedResult, result)
 		})
@@ -226,6 +226,7 @@ func TestShouldDelayUpdate(t *testing.T) {
 	tests := []struct {
 		name           string
 		wa             vwav1.VerticalWorkloadAutoscaler
+		blackouts      []blackoutPeriod
 		currentTime    time.Time
 		expectedDelay  time.Duration
 		expectedResult bool
@@ -321,15 +322,96 @@ func TestShouldDelayUpdate(t *testing.T) {
 			expectedDelay:  time.Hour,
 			expectedResult: true,
 		},
+		{
+			name: "Delay due to blackout within update window",
+			wa: vwav1.VerticalWorkloadAutoscaler{
+				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+					AllowedUpdateWindows: []vwav1.UpdateWindow{
+						{
+							DayOfWeek: "Tuesday",
+							StartTime: "09:00",
+							EndTime:   "11:00",
+							TimeZone:  "UTC",
+						},
+					},
+				},
+			},
+			blackouts: []blackoutPeriod{
+				{
+					name:  "Release freeze",
+					start: time.Date(2023, 10, 9, 0, 0, 0, 0, time.UTC),
+					end:   time.Date(2023, 10, 11, 0, 0, 0, 0, time.UTC),
+				},
+			},
+			currentTime:    time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC),
+			expectedDelay:  14 * time.Hour,
+			expectedResult: true,
+		},
+		{
+			name: "No delay when only decreases are outside their update window",
+			wa: vwav1.VerticalWorkloadAutoscaler{
+				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+					AllowedUpdateWindows: []vwav1.UpdateWindow{
+						{
+							DayOfWeek: "Tuesday",
+							StartTime: "01:00",
+							EndTime:   "05:00",
+							TimeZone:  "UTC",
+							Direction: vwav1.UpdateDirectionDecrease,
+						},
+					},
+				},
+			},
+			currentTime:    time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC),
+			expectedDelay:  0,
+			expectedResult: false,
+		},
+		{
+			name: "Delay until the first direction window opens",
+			wa: vwav1.VerticalWorkloadAutoscaler{
+				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+					AllowedUpdateWindows: []vwav1.UpdateWindow{
+						{
+							DayOfWeek: "Tuesday",
+							StartTime: "12:00",
+							EndTime:   "13:00",
+							TimeZone:  "UTC",
+							Direction: vwav1.UpdateDirectionIncrease,
+						},
+						{
+							DayOfWeek: "Tuesday",
+							StartTime: "22:00",
+							EndTime:   "23:00",
+							TimeZone:  "UTC",
+							Direction: vwav1.UpdateDirectionDecrease,
+						},
+					},
+				},
+			},
+			currentTime:    time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC),
+			expectedDelay:  2 * time.Hour,
+			expectedResult: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			timeNow = func() time.Time { return tt.currentTime }
 			r := &VerticalWorkloadAutoscalerReconciler{}
-			delay, result := r.shouldDelayUpdate(tt.wa)
+			delay, result := r.shouldDelayUpdate(tt.wa, updateSchedule{windows: tt.wa.Spec.AllowedUpdateWindows, blackouts: tt.blackouts})
 			assert.Equal(t, tt.expectedDelay, delay)
 			assert.Equal(t, tt.expectedResult, result)
 		})
 	}
 }
+
+func TestWindowsForDirection(t *testing.T) {
+	both := vwav1.UpdateWindow{DayOfWeek: "Monday", StartTime: "09:00", EndTime: "11:00", TimeZone: "UTC"}
+	increase := vwav1.UpdateWindow{DayOfWeek: "Monday", StartTime: "12:00", EndTime: "13:00", TimeZone: "UTC", Direction: vwav1.UpdateDirectionIncrease}
+	decrease := vwav1.UpdateWindow{DayOfWeek: "Monday", StartTime: "01:00", EndTime: "05:00", TimeZone: "UTC", Direction: vwav1.UpdateDirectionDecrease}
+	windows := []vwav1.UpdateWindow{both, increase, decrease}
+
+	assert.Equal(t, []vwav1.UpdateWindow{both, increase}, windowsForDirection(windows, vwav1.UpdateDirectionIncrease))
+	assert.Equal(t, []vwav1.UpdateWindow{both, decrease}, windowsForDirection(windows, vwav1.UpdateDirectionDecrease))
+	assert.Empty(t, windowsForDirection([]vwav1.UpdateWindow{decrease}, vwav1.UpdateDirectionIncrease))
+}
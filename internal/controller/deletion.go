@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Finalizer holds the deletion of a VWA until its target workload is cleaned up
+const Finalizer = "autoscaling.workload.io/finalizer"
+
+// ensureFinalizer adds the finalizer to the VWA
+func (r *VerticalWorkloadAutoscalerReconciler) ensureFinalizer(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) error {
+	if !controllerutil.AddFinalizer(wa, Finalizer) {
+		return nil
+	}
+	return r.Update(ctx, wa)
+}
+
+// handleVWADeletion cleans up the target workload and metrics of a deleted VWA and removes the finalizer
+func (r *VerticalWorkloadAutoscalerReconciler) handleVWADeletion(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) (ctrl.Result, error) {
+	vwaFrozen.DeletePartialMatch(map[string]string{"namespace": wa.Namespace, "name": wa.Name})
+	if !controllerutil.ContainsFinalizer(wa, Finalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if err := r.cleanupTarget(ctx, wa); err != nil {
+		return r.handleError(ctx, wa, err, "failed to clean up target object", ReasonAPIError, "failed to clean up target object")
+	}
+
+	controllerutil.RemoveFinalizer(wa, Finalizer)
+	if err := r.Update(ctx, wa); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// cleanupTarget removes the VWA annotations from the target object and, with the Restore deletion policy,
+// restores the original container resources. Targets the VWA never updated are left untouched.
+func (r *VerticalWorkloadAutoscalerReconciler) cleanupTarget(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) error {
+	logger := log.FromContext(ctx)
+
+	ref := wa.Status.ScaleTargetRef
+	if ref.Name == "" {
+		return nil
+	}
+	targetObject, err := newTargetObject(ref.Kind)
+	if err != nil {
+		return nil
+	}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: wa.Namespace, Name: ref.Name}, targetObject); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if targetObject.GetAnnotations()[vwav1.UpdatedByAnnotation] != wa.Name {
+		return nil
+	}
+
+	currentResources, err := r.fetchCurrentResources(targetObject)
+	if err != nil {
+		return err
+	}
+	restore := r.effectiveSpec(wa).DeletionPolicy == vwav1.DeletionPolicyRestore
+	// apply every container the VWA owns the resources of, otherwise server-side apply removes them
+	resources := make(map[string]corev1.ResourceRequirements)
+	for _, owned := range []map[string]corev1.ResourceRequirements{wa.Status.AppliedResources, wa.Status.OriginalResources} {
+		for name := range owned {
+			current, ok := currentResources[name]
+			if !ok {
+				continue
+			}
+			resources[name] = current
+			if original, ok := wa.Status.OriginalResources[name]; ok && restore {
+				resources[name] = original
+			}
+		}
+	}
+
+	// the apply configuration has no annotations, so server-side apply removes the ones only the VWA owns
+	if len(resources) > 0 {
+		applyObj, err := r.resourcesObject(targetObject, resources)
+		if err != nil {
+			return err
+		}
+		if err := r.applyTarget(ctx, targetObject, wa, applyObj); err != nil {
+			return err
+		}
+	}
+	if err := r.removeAnnotations(ctx, targetObject, wa); err != nil {
+		return err
+	}
+
+	if restore {
+		logger.Info("restored original resources", "target", targetObject.GetName())
+		r.recordEvent(wa, "Normal", ReasonResourcesRestored, fmt.Sprintf("restored original resources of '%s'", targetObject.GetName()))
+	}
+	return nil
+}
+
+// removeAnnotations removes the VWA annotations left on the target object, e.g. by updates made before
+// the VWA switched to server-side apply. Custom annotations are removed if the VWA value is unchanged.
+func (r *VerticalWorkloadAutoscalerReconciler) removeAnnotations(ctx context.Context, targetObject client.Object, wa *vwav1.VerticalWorkloadAutoscaler) error {
+	annotations := make(map[string]string)
+	removed := false
+	for k, v := range targetObject.GetAnnotations() {
+		value, isOwned := wa.Spec.CustomAnnotations[k]
+		if k == vwav1.UpdatedByAnnotation || k == vwav1.LastUpdatedAnnotation || (isOwned && value == v) {
+			removed = true
+			continue
+		}
+		annotations[k] = v
+	}
+	if !removed {
+		return nil
+	}
+
+	patch := client.MergeFrom(targetObject.DeepCopyObject().(client.Object))
+	targetObject.SetAnnotations(annotations)
+	if err := r.Patch(ctx, targetObject, patch, client.FieldOwner(FieldManager)); err != nil {
+		return fmt.Errorf("failed to remove target object annotations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureFinalizer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	vwa := &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "test-vwa", Namespace: "default"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vwa).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+	require.NoError(t, r.ensureFinalizer(context.Background(), vwa))
+	// adding the finalizer again is a no-op
+	require.NoError(t, r.ensureFinalizer(context.Background(), vwa))
+
+	updated := &vwav1.VerticalWorkloadAutoscaler{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vwa), updated))
+	assert.Equal(t, []string{Finalizer}, updated.Finalizers)
+}
+
+func TestHandleVWADeletion(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+
+	cpu := func(value string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(value)}}
+	}
+
+	tests := []struct {
+		name                string
+		deletionPolicy      vwav1.DeletionPolicy
+		updatedBy           string
+		noTarget            bool
+		expectedResources   map[string]corev1.ResourceRequirements
+		expectedAnnotations map[string]string
+		expectedEvents      int
+	}{
+		{
+			name:              "Retain keeps the applied resources",
+			deletionPolicy:    vwav1.DeletionPolicyRetain,
+			updatedBy:         "test-vwa",
+			expectedResources: map[string]corev1.ResourceRequirements{"app": cpu("300m"), "sidecar": cpu("50m")},
+			expectedAnnotations: map[string]string{
+				"team":                   "checkout",
+				"example.com/owner-note": "changed by hand",
+			},
+		},
+		{
+			name:              "Restore rolls back the original resources",
+			deletionPolicy:    vwav1.DeletionPolicyRestore,
+			updatedBy:         "test-vwa",
+			expectedResources: map[string]corev1.ResourceRequirements{"app": cpu("100m"), "sidecar": cpu("50m")},
+			expectedAnnotations: map[string]string{
+				"team":                   "checkout",
+				"example.com/owner-note": "changed by hand",
+			},
+			expectedEvents: 1,
+		},
+		{
+			name:              "Target updated by another VWA is left untouched",
+			deletionPolicy:    vwav1.DeletionPolicyRestore,
+			updatedBy:         "other-vwa",
+			expectedResources: map[string]corev1.ResourceRequirements{"app": cpu("300m"), "sidecar": cpu("50m")},
+			expectedAnnotations: map[string]string{
+				"team":                      "checkout",
+				"example.com/managed":       "true",
+				"example.com/owner-note":    "changed by hand",
+				vwav1.UpdatedByAnnotation:   "other-vwa",
+				vwav1.LastUpdatedAnnotation: "2024-09-21T12:00:00Z",
+			},
+		},
+		{
+			name:           "Target deleted",
+			deletionPolicy: vwav1.DeletionPolicyRestore,
+			noTarget:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{
+					"team":                      "checkout",
+					"example.com/managed":       "true",
+					"example.com/owner-note":    "changed by hand",
+					vwav1.UpdatedByAnnotation:   tt.updatedBy,
+					vwav1.LastUpdatedAnnotation: "2024-09-21T12:00:00Z",
+				}},
+				Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "app", Image: "web:1.0", Resources: cpu("300m")},
+					{Name: "sidecar", Image: "proxy:1.0", Resources: cpu("50m")},
+				}}}},
+			}
+			now := metav1.Now()
+			vwa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-vwa", Namespace: "default", Finalizers: []string{Finalizer}, DeletionTimestamp: &now},
+				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+					DeletionPolicy: tt.deletionPolicy,
+					CustomAnnotations: map[string]string{
+						"example.com/managed":    "true",
+						"example.com/owner-note": "set by VWA",
+					},
+				},
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{
+					ScaleTargetRef:    autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web", APIVersion: "apps/v1"},
+					AppliedResources:  map[string]corev1.ResourceRequirements{"app": cpu("300m")},
+					OriginalResources: map[string]corev1.ResourceRequirements{"app": cpu("100m")},
+				},
+			}
+			builder := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithObjects(vwa).WithStatusSubresource(vwa)
+			if !tt.noTarget {
+				builder = builder.WithObjects(deployment)
+			}
+			c := builder.Build()
+			recorder := record.NewFakeRecorder(10)
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder, Timeout: time.Minute}
+			vwaFrozen.WithLabelValues("default", "test-vwa", "release").Set(1)
+			vwaFrozen.WithLabelValues("default", "other-vwa", "release").Set(1)
+			defer vwaFrozen.Reset()
+
+			_, err := r.handleVWADeletion(context.Background(), vwa)
+			require.NoError(t, err)
+
+			// the frozen metric of the deleted VWA is dropped
+			assert.Equal(t, 1, testutil.CollectAndCount(vwaFrozen))
+			assert.Equal(t, float64(1), testutil.ToFloat64(vwaFrozen.WithLabelValues("default", "other-vwa", "release")))
+
+			// the VWA is gone once the finalizer is removed
+			err = c.Get(context.Background(), client.ObjectKeyFromObject(vwa), &vwav1.VerticalWorkloadAutoscaler{})
+			assert.True(t, apierrors.IsNotFound(err))
+			assert.Len(t, recorder.Events, tt.expectedEvents)
+			if tt.noTarget {
+				return
+			}
+
+			updated := &appsv1.Deployment{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), updated))
+			resources := make(map[string]corev1.ResourceRequirements)
+			for _, container := range updated.Spec.Template.Spec.Containers {
+				resources[container.Name] = container.Resources
+			}
+			assert.Equal(t, tt.expectedResources, resources)
+			assert.Equal(t, tt.expectedAnnotations, updated.Annotations)
+		})
+	}
+}
+
+func TestUpdateTargetObjectSnapshotsOriginalResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "web:1.0", Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			}},
+		}}}},
+	}
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithObjects(deployment).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+	vwa := &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "test-vwa", Namespace: "default"}}
+	original := map[string]corev1.ResourceRequirements{
+		"app": {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+	}
+
+	for _, cpu := range []string{"200m", "400m"} {
+		newResources := map[string]corev1.ResourceRequirements{
+			"app": {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+		}
+		updated, err := r.updateTargetObject(context.Background(), deployment, vwa, newResources, nil)
+		require.NoError(t, err)
+		assert.True(t, updated)
+		// the snapshot is taken the first time the VWA modifies the container
+		assert.Equal(t, original, vwa.Status.OriginalResources)
+	}
+}
+
+func TestCleanupTargetServerSide(t *testing.T) {
+	c := requireEnvtest(t)
+	ctx := context.Background()
+	cpu := func(value string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(value)}}
+	}
+
+	tests := []struct {
+		name              string
+		deletionPolicy    vwav1.DeletionPolicy
+		expectedResources corev1.ResourceRequirements
+	}{
+		{
+			name:              "Retain keeps the applied resources",
+			deletionPolicy:    vwav1.DeletionPolicyRetain,
+			expectedResources: cpu("300m"),
+		},
+		{
+			name:              "Restore rolls back the original resources",
+			deletionPolicy:    vwav1.DeletionPolicyRestore,
+			expectedResources: cpu("100m"),
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := fmt.Sprintf("cleanup-web-%d", i)
+			labels := map[string]string{"app": name}
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: map[string]string{"team": "checkout"}},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{Containers: []corev1.Container{
+							{Name: "app", Image: "web:1.0", Resources: cpu("100m")},
+							{Name: "sidecar", Image: "proxy:1.0", Resources: cpu("50m")},
+						}},
+					},
+				},
+			}
+			require.NoError(t, c.Create(ctx, deployment, client.FieldOwner("kubectl")))
+			t.Cleanup(func() { _ = c.Delete(ctx, deployment) })
+
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+			vwa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-vwa", Namespace: "default"},
+				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+					DeletionPolicy:    tt.deletionPolicy,
+					CustomAnnotations: map[string]string{"example.com/managed": "true"},
+				},
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{
+					ScaleTargetRef:    autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: name, APIVersion: "apps/v1"},
+					AppliedResources:  map[string]corev1.ResourceRequirements{"app": cpu("300m")},
+					OriginalResources: map[string]corev1.ResourceRequirements{"app": cpu("100m")},
+				},
+			}
+			require.NoError(t, r.applyTargetResources(ctx, deployment, vwa, vwa.Status.AppliedResources))
+
+			require.NoError(t, r.cleanupTarget(ctx, vwa))
+
+			updated := &appsv1.Deployment{}
+			require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(deployment), updated))
+			containers := updated.Spec.Template.Spec.Containers
+			require.Len(t, containers, 2)
+			assert.True(t, apiequality.Semantic.DeepEqual(tt.expectedResources, containers[0].Resources), "app resources: %v", containers[0].Resources)
+			assert.True(t, apiequality.Semantic.DeepEqual(cpu("50m"), containers[1].Resources), "sidecar resources: %v", containers[1].Resources)
+			// server-side apply drops the annotations only the VWA owned
+			assert.Equal(t, map[string]string{"team": "checkout"}, updated.Annotations)
+			assert.NotContains(t, managedFields(updated, FieldManager), `"f:annotations"`)
+		})
+	}
+}
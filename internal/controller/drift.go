@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// handleDrift detects reverts of the resources applied by the VWA and records them in the VWA status.
+// It returns a positive delay while the reverts within the drift period reach spec.driftPolicy.maxReverts:
+// the VWA must not re-apply its resources until the delay passes.
+func (r *VerticalWorkloadAutoscalerReconciler) handleDrift(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, targetObject client.Object, currentResources map[string]corev1.ResourceRequirements) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+	policy := r.effectiveSpec(wa).DriftPolicy
+	maxReverts := int(*policy.MaxReverts)
+	period := policy.Period.Duration
+	now := timeNow()
+
+	// forget the reverts older than the drift period
+	reverts := slices.DeleteFunc(slices.Clone(wa.Status.Reverts), func(t metav1.Time) bool {
+		return !t.Add(period).After(now)
+	})
+	changed := len(reverts) != len(wa.Status.Reverts)
+	wa.Status.Reverts = reverts
+
+	condition := findCondition(wa.Status.Conditions, ConditionTypeDrifted)
+	reason, message := "", ""
+	if drifted := driftedContainers(wa.Status.AppliedResources, currentResources); len(drifted) > 0 {
+		managers := r.resourcesFieldManagers(targetObject, drifted)
+		resources := make([]string, 0, 2)
+		for _, container := range drifted {
+			resources = append(resources, changedResourceNames(wa.Status.AppliedResources[container], currentResources[container])...)
+			// acknowledge the reverted resources, so the same revert is counted once
+			wa.Status.AppliedResources[container] = currentResources[container]
+		}
+		slices.Sort(resources)
+		resources = slices.Compact(resources)
+
+		wa.Status.Reverts = append(wa.Status.Reverts, metav1.NewTime(now))
+		changed = true
+		reason = ReasonResourcesReverted
+		message = fmt.Sprintf("resources of containers %v changed by %s", drifted, describeFieldManagers(managers))
+		logger.Info("applied resources reverted", "containers", drifted, "fieldManagers", managers, "reverts", len(wa.Status.Reverts))
+		r.recordEvent(wa, "Warning", ReasonResourcesReverted, message)
+
+		if len(wa.Status.Reverts) >= maxReverts {
+			wa.Status.Conflicts = removeGitOpsConflicts(wa.Status.Conflicts)
+			for _, resource := range resources {
+				wa.Status.Conflicts = append(wa.Status.Conflicts, vwav1.Conflict{
+					Resource:     resource,
+					ConflictWith: strings.Join(managers, ","),
+					Reason:       ReasonGitOpsConflict,
+				})
+			}
+		}
+	}
+
+	var delay time.Duration
+	if len(wa.Status.Reverts) >= maxReverts {
+		// resume once fewer than maxReverts reverts are within the period
+		delay = wa.Status.Reverts[len(wa.Status.Reverts)-maxReverts].Add(period).Sub(now)
+		reason = ReasonGitOpsConflict
+		message = fmt.Sprintf("resources reverted %d times within %s, not re-applying them until %s",
+			len(wa.Status.Reverts), period, now.Add(delay).UTC().Format(time.RFC3339))
+		if condition == nil || condition.Reason != ReasonGitOpsConflict {
+			r.recordEvent(wa, "Warning", ReasonGitOpsConflict, message)
+		}
+	} else if conflicts := removeGitOpsConflicts(wa.Status.Conflicts); len(conflicts) != len(wa.Status.Conflicts) {
+		wa.Status.Conflicts = conflicts
+		changed = true
+	}
+
+	switch {
+	case reason != "":
+		return delay, r.updateStatusCondition(ctx, wa, ConditionTypeDrifted, metav1.ConditionTrue, reason, message)
+	case condition != nil && condition.Status == metav1.ConditionTrue && len(wa.Status.Reverts) == 0:
+		return 0, r.updateStatusCondition(ctx, wa, ConditionTypeDrifted, metav1.ConditionFalse, ReasonNoDrift, "no reverts within the drift period")
+	case changed:
+		return 0, r.Status().Update(ctx, wa)
+	}
+	return 0, nil
+}
+
+// detectsDrift checks whether the VWA applies the resources to the workload itself, so reverts can be detected;
+// in Git write-back mode and with the Admission apply method, the workload resources are not the applied ones
+func (r *VerticalWorkloadAutoscalerReconciler) detectsDrift(wa *vwav1.VerticalWorkloadAutoscaler) bool {
+	return wa.Spec.GitWriteback == nil && r.effectiveSpec(wa).ApplyMethod != vwav1.ApplyMethodAdmission
+}
+
+// clearDrift forgets the reverts and the GitOps conflicts of a VWA that doesn't detect drift anymore
+func (r *VerticalWorkloadAutoscalerReconciler) clearDrift(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) error {
+	conflicts := removeGitOpsConflicts(wa.Status.Conflicts)
+	changed := len(conflicts) != len(wa.Status.Conflicts) || len(wa.Status.Reverts) > 0
+	wa.Status.Conflicts = conflicts
+	wa.Status.Reverts = nil
+
+	if condition := findCondition(wa.Status.Conditions, ConditionTypeDrifted); condition != nil && condition.Status == metav1.ConditionTrue {
+		return r.updateStatusCondition(ctx, wa, ConditionTypeDrifted, metav1.ConditionFalse, ReasonNoDrift, "drift detection is off for this apply method")
+	}
+	if changed {
+		return r.Status().Update(ctx, wa)
+	}
+	return nil
+}
+
+// findVWAForWorkload maps a workload to the VWA that last updated its resources
+func (r *VerticalWorkloadAutoscalerReconciler) findVWAForWorkload(_ context.Context, workload client.Object) []reconcile.Request {
+	vwaName := workload.GetAnnotations()[vwav1.UpdatedByAnnotation]
+	if vwaName == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: workload.GetNamespace(), Name: vwaName}}}
+}
+
+// workloadResourcesChanged checks whether an update of a VWA managed workload changed container resources
+func (r *VerticalWorkloadAutoscalerReconciler) workloadResourcesChanged(e event.UpdateEvent) bool {
+	if e.ObjectNew.GetAnnotations()[vwav1.UpdatedByAnnotation] == "" {
+		return false
+	}
+	oldResources, err := r.fetchCurrentResources(e.ObjectOld)
+	if err != nil {
+		return false
+	}
+	newResources, err := r.fetchCurrentResources(e.ObjectNew)
+	if err != nil {
+		return false
+	}
+	for container, resources := range newResources {
+		if oldResources, ok := oldResources[container]; ok && !resourceRequirementsEqual(oldResources, resources) {
+			return true
+		}
+	}
+	return false
+}
+
+// driftedContainers returns the sorted names of the containers whose resources differ from the applied ones
+func driftedContainers(applied, current map[string]corev1.ResourceRequirements) []string {
+	var drifted []string
+	for container, resources := range applied {
+		if currentResources, ok := current[container]; ok && !resourceRequirementsEqual(resources, currentResources) {
+			drifted = append(drifted, container)
+		}
+	}
+	sort.Strings(drifted)
+	return drifted
+}
+
+// changedResourceNames returns the names of the resources whose requests or limits differ
+func changedResourceNames(a, b corev1.ResourceRequirements) []string {
+	var names []string
+	if !a.Requests.Cpu().Equal(*b.Requests.Cpu()) || !a.Limits.Cpu().Equal(*b.Limits.Cpu()) {
+		names = append(names, string(corev1.ResourceCPU))
+	}
+	if !a.Requests.Memory().Equal(*b.Requests.Memory()) || !a.Limits.Memory().Equal(*b.Limits.Memory()) {
+		names = append(names, string(corev1.ResourceMemory))
+	}
+	return names
+}
+
+// resourcesFieldManagers returns the sorted field managers other than the VWA owning resources of the containers
+func (r *VerticalWorkloadAutoscalerReconciler) resourcesFieldManagers(targetObject client.Object, containers []string) []string {
+	gvk, err := apiutil.GVKForObject(targetObject, r.Client.Scheme())
+	if err != nil {
+		return nil
+	}
+	path, err := containersPath(gvk.Kind)
+	if err != nil {
+		return nil
+	}
+
+	var managers []string
+	for _, entry := range targetObject.GetManagedFields() {
+		if entry.Manager == FieldManager || entry.FieldsV1 == nil || slices.Contains(managers, entry.Manager) {
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		for _, name := range path {
+			fields, _ = fields["f:"+name].(map[string]interface{})
+		}
+		for _, container := range containers {
+			key, _ := json.Marshal(map[string]string{"name": container})
+			if containerFields, ok := fields["k:"+string(key)].(map[string]interface{}); ok {
+				if _, ok := containerFields["f:resources"]; ok {
+					managers = append(managers, entry.Manager)
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(managers)
+	return managers
+}
+
+// describeFieldManagers returns a human readable list of field managers
+func describeFieldManagers(managers []string) string {
+	if len(managers) == 0 {
+		return "another field manager"
+	}
+	return fmt.Sprintf("field manager(s) %s", strings.Join(managers, ", "))
+}
+
+// removeGitOpsConflicts returns the conflicts without the GitOps conflicts
+func removeGitOpsConflicts(conflicts []vwav1.Conflict) []vwav1.Conflict {
+	return slices.DeleteFunc(slices.Clone(conflicts), func(c vwav1.Conflict) bool {
+		return c.Reason == ReasonGitOpsConflict
+	})
+}
@@ -0,0 +1,405 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func cpuRequests(cpu string) corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}}
+}
+
+// managedDeployment returns a Deployment with the app container resources owned by the field manager
+func managedDeployment(cpu, manager string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Annotations: map[string]string{vwav1.UpdatedByAnnotation: "vwa1"},
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: FieldManager, Operation: metav1.ManagedFieldsOperationApply, FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{
+					Raw: []byte(`{"f:metadata":{"f:annotations":{}}}`),
+				}},
+				{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{
+					Raw: []byte(`{"f:spec":{"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"app\"}":{".":{},"f:image":{},"f:resources":{"f:requests":{"f:cpu":{}}}}}}}}}`),
+				}},
+				{Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{
+					Raw: []byte(`{"f:status":{"f:replicas":{}}}`),
+				}},
+			},
+		},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Resources: cpuRequests(cpu)},
+		}}}},
+	}
+}
+
+func TestHandleDrift(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	ago := func(d time.Duration) metav1.Time { return metav1.NewTime(now.Add(-d)) }
+
+	tests := []struct {
+		name               string
+		status             vwav1.VerticalWorkloadAutoscalerStatus
+		currentCPU         string
+		expectedDelay      time.Duration
+		expectedReverts    int
+		expectedCondition  *metav1.Condition
+		expectedConflicts  []vwav1.Conflict
+		expectedAppliedCPU string
+	}{
+		{
+			name:               "No drift",
+			status:             vwav1.VerticalWorkloadAutoscalerStatus{AppliedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("200m")}},
+			currentCPU:         "200m",
+			expectedAppliedCPU: "200m",
+		},
+		{
+			name:               "Nothing applied yet",
+			currentCPU:         "100m",
+			expectedAppliedCPU: "",
+		},
+		{
+			name:            "First revert",
+			status:          vwav1.VerticalWorkloadAutoscalerStatus{AppliedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("200m")}},
+			currentCPU:      "100m",
+			expectedReverts: 1,
+			expectedCondition: &metav1.Condition{Type: ConditionTypeDrifted, Status: metav1.ConditionTrue, Reason: ReasonResourcesReverted,
+				Message: "resources of containers [app] changed by field manager(s) argocd-controller"},
+			expectedAppliedCPU: "100m",
+		},
+		{
+			name: "Reverts reach the limit",
+			status: vwav1.VerticalWorkloadAutoscalerStatus{
+				AppliedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("200m")},
+				Reverts:          []metav1.Time{ago(2 * time.Hour), ago(40 * time.Minute), ago(20 * time.Minute)},
+			},
+			currentCPU:      "100m",
+			expectedDelay:   20 * time.Minute,
+			expectedReverts: 3,
+			expectedCondition: &metav1.Condition{Type: ConditionTypeDrifted, Status: metav1.ConditionTrue, Reason: ReasonGitOpsConflict,
+				Message: "resources reverted 3 times within 1h0m0s, not re-applying them until 2023-10-10T10:20:00Z"},
+			expectedConflicts:  []vwav1.Conflict{{Resource: "cpu", ConflictWith: "argocd-controller", Reason: ReasonGitOpsConflict}},
+			expectedAppliedCPU: "100m",
+		},
+		{
+			name: "GitOps conflict holds without new reverts",
+			status: vwav1.VerticalWorkloadAutoscalerStatus{
+				AppliedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("100m")},
+				Reverts:          []metav1.Time{ago(50 * time.Minute), ago(40 * time.Minute), ago(20 * time.Minute)},
+				Conflicts:        []vwav1.Conflict{{Resource: "cpu", ConflictWith: "argocd-controller", Reason: ReasonGitOpsConflict}},
+			},
+			currentCPU:      "100m",
+			expectedDelay:   10 * time.Minute,
+			expectedReverts: 3,
+			expectedCondition: &metav1.Condition{Type: ConditionTypeDrifted, Status: metav1.ConditionTrue, Reason: ReasonGitOpsConflict,
+				Message: "resources reverted 3 times within 1h0m0s, not re-applying them until 2023-10-10T10:10:00Z"},
+			expectedConflicts:  []vwav1.Conflict{{Resource: "cpu", ConflictWith: "argocd-controller", Reason: ReasonGitOpsConflict}},
+			expectedAppliedCPU: "100m",
+		},
+		{
+			name: "GitOps conflict expires",
+			status: vwav1.VerticalWorkloadAutoscalerStatus{
+				AppliedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("100m")},
+				Reverts:          []metav1.Time{ago(70 * time.Minute), ago(65 * time.Minute), ago(61 * time.Minute)},
+				Conditions:       []metav1.Condition{{Type: ConditionTypeDrifted, Status: metav1.ConditionTrue, Reason: ReasonGitOpsConflict}},
+				Conflicts: []vwav1.Conflict{
+					{Resource: "cpu", ConflictWith: "HPA/web", Reason: "HPA scales on CPU"},
+					{Resource: "cpu", ConflictWith: "argocd-controller", Reason: ReasonGitOpsConflict},
+				},
+			},
+			currentCPU: "100m",
+			expectedCondition: &metav1.Condition{Type: ConditionTypeDrifted, Status: metav1.ConditionFalse, Reason: ReasonNoDrift,
+				Message: "no reverts within the drift period"},
+			expectedConflicts:  []vwav1.Conflict{{Resource: "cpu", ConflictWith: "HPA/web", Reason: "HPA scales on CPU"}},
+			expectedAppliedCPU: "100m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Status:     tt.status,
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(wa).WithObjects(wa).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+			deployment := managedDeployment(tt.currentCPU, "argocd-controller")
+			currentResources, err := r.fetchCurrentResources(deployment)
+			require.NoError(t, err)
+
+			delay, err := r.handleDrift(context.Background(), wa, deployment, currentResources)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedDelay, delay)
+
+			stored := &vwav1.VerticalWorkloadAutoscaler{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(wa), stored))
+			assert.Len(t, stored.Status.Reverts, tt.expectedReverts)
+			assert.Equal(t, tt.expectedConflicts, stored.Status.Conflicts)
+			if tt.expectedAppliedCPU != "" {
+				applied := stored.Status.AppliedResources["app"]
+				assert.True(t, applied.Requests.Cpu().Equal(resource.MustParse(tt.expectedAppliedCPU)))
+			}
+			condition := findCondition(stored.Status.Conditions, ConditionTypeDrifted)
+			if tt.expectedCondition == nil {
+				assert.Nil(t, condition)
+				return
+			}
+			require.NotNil(t, condition)
+			assert.Equal(t, tt.expectedCondition.Status, condition.Status)
+			assert.Equal(t, tt.expectedCondition.Reason, condition.Reason)
+			assert.Equal(t, tt.expectedCondition.Message, condition.Message)
+		})
+	}
+}
+
+func TestHandleVWAChangeStopsOnGitOpsConflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	updateModeOff := vpav1.UpdateModeOff
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference: vwav1.VPAReference{Name: "vpa1"},
+			DriftPolicy:  &vwav1.DriftPolicy{MaxReverts: ptr.To(int32(2)), Period: &metav1.Duration{Duration: time.Hour}},
+		},
+	}
+	vpa := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &updateModeOff},
+			TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Recommendation: &vpav1.RecommendedPodResources{
+				ContainerRecommendations: []vpav1.RecommendedContainerResources{
+					{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+				},
+			},
+		},
+	}
+	deployment := managedDeployment("250m", "argocd-controller")
+	deployment.Annotations = nil
+
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+	// reverts happen after the update frequency passed
+	revert := func() {
+		now = now.Add(10 * time.Minute)
+		stored := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+		stored.Spec.Template.Spec.Containers[0].Resources = cpuRequests("250m")
+		require.NoError(t, c.Update(context.Background(), stored))
+	}
+	cpu := func() string {
+		stored := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+		return stored.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String()
+	}
+
+	// the VWA applies the recommendation and re-applies it after the first revert
+	_, err := r.handleVWAChange(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.Equal(t, "500m", cpu())
+	revert()
+	_, err = r.handleVWAChange(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.Equal(t, "500m", cpu())
+	assert.Equal(t, ReasonResourcesReverted, findCondition(vwa.Status.Conditions, ConditionTypeDrifted).Reason)
+
+	// the second revert within the period stops the fight
+	revert()
+	result, err := r.handleVWAChange(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.Equal(t, 50*time.Minute, result.RequeueAfter)
+	assert.Equal(t, "250m", cpu())
+	assert.Equal(t, ReasonGitOpsConflict, findCondition(vwa.Status.Conditions, ConditionTypeDrifted).Reason)
+	assert.Equal(t, []vwav1.Conflict{{Resource: "cpu", ConflictWith: "argocd-controller", Reason: ReasonGitOpsConflict}}, vwa.Status.Conflicts)
+}
+
+func TestHandleVWAChangeTracksDriftOutsideUpdateWindow(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	// outside the evening update window
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	updateModeOff := vpav1.UpdateModeOff
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference:         vwav1.VPAReference{Name: "vpa1"},
+			AllowedUpdateWindows: []vwav1.UpdateWindow{{DayOfWeek: "Tuesday", StartTime: "20:00", EndTime: "22:00", TimeZone: "UTC"}},
+		},
+		Status: vwav1.VerticalWorkloadAutoscalerStatus{
+			AppliedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("500m")},
+		},
+	}
+	vpa := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &updateModeOff},
+			TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Recommendation: &vpav1.RecommendedPodResources{
+				ContainerRecommendations: []vpav1.RecommendedContainerResources{
+					{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+				},
+			},
+		},
+	}
+	deployment := managedDeployment("250m", "argocd-controller")
+
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+	// the revert is recorded, but the resources are only re-applied in the update window
+	result, err := r.handleVWAChange(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Hour, result.RequeueAfter)
+	assert.Len(t, vwa.Status.Reverts, 1)
+	assert.Equal(t, ReasonResourcesReverted, findCondition(vwa.Status.Conditions, ConditionTypeDrifted).Reason)
+	stored := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+	assert.Equal(t, "250m", stored.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String())
+}
+
+func TestClearDrift(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	hpaConflict := vwav1.Conflict{Resource: "cpu", ConflictWith: "HorizontalPodAutoscaler/web-hpa", Reason: ReasonHPAResourceMetric}
+	wa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			GitWriteback: &vwav1.GitWriteback{Repository: "https://example.com/repo.git", Path: "apps/web.yaml"},
+		},
+		Status: vwav1.VerticalWorkloadAutoscalerStatus{
+			Reverts: []metav1.Time{metav1.NewTime(now.Add(-time.Minute))},
+			Conflicts: []vwav1.Conflict{
+				{Resource: "cpu", ConflictWith: "argocd-controller", Reason: ReasonGitOpsConflict},
+				hpaConflict,
+			},
+			Conditions: []metav1.Condition{{Type: ConditionTypeDrifted, Status: metav1.ConditionTrue, Reason: ReasonGitOpsConflict}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(wa).WithObjects(wa).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+	assert.False(t, r.detectsDrift(wa))
+	require.NoError(t, r.clearDrift(context.Background(), wa))
+	stored := &vwav1.VerticalWorkloadAutoscaler{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(wa), stored))
+	assert.Empty(t, stored.Status.Reverts)
+	assert.Equal(t, []vwav1.Conflict{hpaConflict}, stored.Status.Conflicts)
+	condition := findCondition(stored.Status.Conditions, ConditionTypeDrifted)
+	require.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, ReasonNoDrift, condition.Reason)
+}
+
+func TestResourcesFieldManagers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+
+	assert.Equal(t, []string{"kustomize-controller"}, r.resourcesFieldManagers(managedDeployment("100m", "kustomize-controller"), []string{"app"}))
+	assert.Empty(t, r.resourcesFieldManagers(managedDeployment("100m", "kustomize-controller"), []string{"sidecar"}))
+	assert.Empty(t, r.resourcesFieldManagers(managedDeployment("100m", FieldManager), []string{"app"}))
+}
+
+func TestDriftedContainers(t *testing.T) {
+	applied := map[string]corev1.ResourceRequirements{
+		"app":     cpuRequests("200m"),
+		"sidecar": cpuRequests("50m"),
+		"removed": cpuRequests("10m"),
+	}
+	current := map[string]corev1.ResourceRequirements{
+		"app":     cpuRequests("100m"),
+		"sidecar": cpuRequests("0.05"),
+		"debug":   cpuRequests("10m"),
+	}
+
+	assert.Equal(t, []string{"app"}, driftedContainers(applied, current))
+	assert.Empty(t, driftedContainers(nil, current))
+}
+
+func TestChangedResourceNames(t *testing.T) {
+	a := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+	}
+	b := *a.DeepCopy()
+	assert.Empty(t, changedResourceNames(a, b))
+
+	b.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}
+	assert.Equal(t, []string{"memory"}, changedResourceNames(a, b))
+
+	b.Requests[corev1.ResourceCPU] = resource.MustParse("200m")
+	assert.Equal(t, []string{"cpu", "memory"}, changedResourceNames(a, b))
+}
+
+func TestFindVWAForWorkload(t *testing.T) {
+	r := &VerticalWorkloadAutoscalerReconciler{}
+
+	assert.Equal(t, []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: "default", Name: "vwa1"}}},
+		r.findVWAForWorkload(context.Background(), managedDeployment("100m", "kubectl")))
+	assert.Empty(t, r.findVWAForWorkload(context.Background(), &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}))
+}
+
+func TestWorkloadResourcesChanged(t *testing.T) {
+	r := &VerticalWorkloadAutoscalerReconciler{}
+	unmanaged := managedDeployment("200m", "kubectl")
+	unmanaged.Annotations = nil
+
+	tests := []struct {
+		name     string
+		oldObj   client.Object
+		newObj   client.Object
+		expected bool
+	}{
+		{name: "Resources changed", oldObj: managedDeployment("100m", "kubectl"), newObj: managedDeployment("200m", "kubectl"), expected: true},
+		{name: "Resources unchanged", oldObj: managedDeployment("100m", "kubectl"), newObj: managedDeployment("100m", "kubectl")},
+		{name: "Unmanaged workload", oldObj: managedDeployment("100m", "kubectl"), newObj: unmanaged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, r.workloadResourcesChanged(event.UpdateEvent{ObjectOld: tt.oldObj, ObjectNew: tt.newObj}))
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// RecommendationsPath is the path of the recommendations export endpoint on the metrics server
+const RecommendationsPath = "/recommendations"
+
+const (
+	// ExportFormatKustomize renders a kustomize strategic merge patch per target workload
+	ExportFormatKustomize = "kustomize"
+	// ExportFormatHelm renders a Helm values fragment per target workload, keyed by container
+	ExportFormatHelm = "helm"
+	// ExportFormatJSON renders the recommendations as a JSON list
+	ExportFormatJSON = "json"
+)
+
+// exportValuesKey is the Helm values key of the exported container resources
+const exportValuesKey = "{{ .Container }}.resources"
+
+// exportedRecommendation is a VWA recommendation in the JSON export format
+type exportedRecommendation struct {
+	Namespace           string                                    `json:"namespace"`
+	Name                string                                    `json:"name"`
+	TargetRef           autoscalingv2.CrossVersionObjectReference `json:"targetRef"`
+	RecommendedRequests map[string]corev1.ResourceRequirements    `json:"recommendedRequests"`
+	LastUpdated         *metav1.Time                              `json:"lastUpdated,omitempty"`
+}
+
+// RecommendationsHandler serves the recommended resources of VWAs as ready-to-commit artifacts. The namespace,
+// name and labelSelector query parameters select the VWAs, the format parameter picks kustomize (default),
+// helm or json. VWAs without recommendations are left out.
+type RecommendationsHandler struct {
+	Reader client.Reader
+}
+
+// NewRecommendationsHandler returns a handler reading the VWAs with the reader
+func NewRecommendationsHandler(reader client.Reader) *RecommendationsHandler {
+	return &RecommendationsHandler{Reader: reader}
+}
+
+// ServeHTTP renders the recommendations of the selected VWAs
+func (h *RecommendationsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := req.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = ExportFormatKustomize
+	}
+	if format != ExportFormatKustomize && format != ExportFormatHelm && format != ExportFormatJSON {
+		http.Error(w, fmt.Sprintf("unknown format '%s': must be %s, %s or %s", format, ExportFormatKustomize, ExportFormatHelm, ExportFormatJSON), http.StatusBadRequest)
+		return
+	}
+
+	vwas, status, err := h.selectVWAs(req.Context(), query)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	var body []byte
+	if format == ExportFormatJSON {
+		w.Header().Set("Content-Type", "application/json")
+		body, err = renderRecommendationsJSON(vwas)
+	} else {
+		w.Header().Set("Content-Type", "application/yaml")
+		body, err = renderRecommendationsYAML(vwas, format)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(body) //nolint:errcheck
+}
+
+// selectVWAs returns the VWAs with recommendations selected by the query, sorted by namespace and name,
+// or the HTTP status code of the error
+func (h *RecommendationsHandler) selectVWAs(ctx context.Context, query url.Values) ([]vwav1.VerticalWorkloadAutoscaler, int, error) {
+	namespace, name := query.Get("namespace"), query.Get("name")
+
+	var vwas []vwav1.VerticalWorkloadAutoscaler
+	if name != "" {
+		if namespace == "" {
+			return nil, http.StatusBadRequest, fmt.Errorf("the name parameter requires the namespace parameter")
+		}
+		vwa := &vwav1.VerticalWorkloadAutoscaler{}
+		if err := h.Reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, vwa); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, http.StatusNotFound, fmt.Errorf("VerticalWorkloadAutoscaler '%s/%s' not found", namespace, name)
+			}
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to get VerticalWorkloadAutoscaler: %w", err)
+		}
+		vwas = append(vwas, *vwa)
+	} else {
+		selector, err := labels.Parse(query.Get("labelSelector"))
+		if err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid labelSelector: %w", err)
+		}
+		var vwaList vwav1.VerticalWorkloadAutoscalerList
+		if err := h.Reader.List(ctx, &vwaList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to list VerticalWorkloadAutoscaler objects: %w", err)
+		}
+		vwas = vwaList.Items
+	}
+
+	selected := make([]vwav1.VerticalWorkloadAutoscaler, 0, len(vwas))
+	for _, vwa := range vwas {
+		if vwa.Status.ScaleTargetRef.Kind != "" && len(vwa.Status.RecommendedRequests) > 0 {
+			selected = append(selected, vwa)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if selected[i].Namespace != selected[j].Namespace {
+			return selected[i].Namespace < selected[j].Namespace
+		}
+		return selected[i].Name < selected[j].Name
+	})
+	return selected, http.StatusOK, nil
+}
+
+// renderRecommendationsJSON renders the recommendations as a JSON list
+func renderRecommendationsJSON(vwas []vwav1.VerticalWorkloadAutoscaler) ([]byte, error) {
+	recommendations := make([]exportedRecommendation, 0, len(vwas))
+	for _, vwa := range vwas {
+		recommendations = append(recommendations, exportedRecommendation{
+			Namespace:           vwa.Namespace,
+			Name:                vwa.Name,
+			TargetRef:           vwa.Status.ScaleTargetRef,
+			RecommendedRequests: vwa.Status.RecommendedRequests,
+			LastUpdated:         vwa.Status.LastUpdated,
+		})
+	}
+	body, err := json.MarshalIndent(recommendations, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render recommendations: %w", err)
+	}
+	return append(body, '\n'), nil
+}
+
+// renderRecommendationsYAML renders a kustomize patch or Helm values YAML document per VWA
+func renderRecommendationsYAML(vwas []vwav1.VerticalWorkloadAutoscaler, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, vwa := range vwas {
+		var doc interface{}
+		if format == ExportFormatHelm {
+			values, err := helmValues(exportValuesKey, vwa.Status.RecommendedRequests)
+			if err != nil {
+				return nil, err
+			}
+			doc = values
+		} else {
+			patch, err := resourcesPatch(targetGroupVersionKind(vwa.Status.ScaleTargetRef), vwa.Namespace, vwa.Status.ScaleTargetRef.Name, vwa.Status.RecommendedRequests)
+			if err != nil {
+				return nil, err
+			}
+			doc = patch.Object
+		}
+		out, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render recommendations of '%s/%s': %w", vwa.Namespace, vwa.Name, err)
+		}
+
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		fmt.Fprintf(&buf, "# VerticalWorkloadAutoscaler %s/%s, %s %s\n", vwa.Namespace, vwa.Name, vwa.Status.ScaleTargetRef.Kind, vwa.Status.ScaleTargetRef.Name)
+		buf.Write(out)
+	}
+	return buf.Bytes(), nil
+}
+
+// targetGroupVersionKind returns the kind of the target reference, defaulting the API version of the kind
+func targetGroupVersionKind(ref autoscalingv2.CrossVersionObjectReference) schema.GroupVersionKind {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil || gv.Empty() {
+		gv = schema.GroupVersion{Group: "apps", Version: "v1"}
+		if ref.Kind == "Job" || ref.Kind == "CronJob" {
+			gv = schema.GroupVersion{Group: "batch", Version: "v1"}
+		}
+	}
+	return gv.WithKind(ref.Kind)
+}
@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func exportVWA(namespace, name, team string, targetRef autoscalingv2.CrossVersionObjectReference, recommendations map[string]corev1.ResourceRequirements) *vwav1.VerticalWorkloadAutoscaler {
+	return &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"team": team}},
+		Status: vwav1.VerticalWorkloadAutoscalerStatus{
+			ScaleTargetRef:      targetRef,
+			RecommendedRequests: recommendations,
+		},
+	}
+}
+
+func TestRecommendationsHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	lastUpdated := metav1.NewTime(time.Date(2024, 9, 21, 12, 0, 0, 0, time.UTC))
+	web := exportVWA("shop", "web", "checkout", autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web", APIVersion: "apps/v1"},
+		map[string]corev1.ResourceRequirements{
+			"app":     {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("256Mi")}},
+			"sidecar": cpuRequests("50m"),
+		})
+	web.Status.LastUpdated = &lastUpdated
+	cleanup := exportVWA("shop", "cleanup", "platform", autoscalingv2.CrossVersionObjectReference{Kind: "CronJob", Name: "cleanup"},
+		map[string]corev1.ResourceRequirements{"job": cpuRequests("100m")})
+	pending := exportVWA("shop", "pending", "checkout", autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "pending"}, nil)
+	other := exportVWA("other", "api", "checkout", autoscalingv2.CrossVersionObjectReference{Kind: "StatefulSet", Name: "api", APIVersion: "apps/v1"},
+		map[string]corev1.ResourceRequirements{"api": cpuRequests("1")})
+
+	tests := []struct {
+		name         string
+		method       string
+		query        string
+		listErr      error
+		expectedCode int
+		expectedType string
+		expectedBody string
+	}{
+		{
+			name:         "Kustomize patch of a VWA",
+			query:        "namespace=shop&name=web",
+			expectedCode: http.StatusOK,
+			expectedType: "application/yaml",
+			expectedBody: `# VerticalWorkloadAutoscaler shop/web, Deployment web
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: 500m
+            memory: 256Mi
+      - name: sidecar
+        resources:
+          requests:
+            cpu: 50m
+`,
+		},
+		{
+			name:         "Kustomize patches of a namespace",
+			query:        "namespace=shop",
+			expectedCode: http.StatusOK,
+			expectedType: "application/yaml",
+			expectedBody: `# VerticalWorkloadAutoscaler shop/cleanup, CronJob cleanup
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: cleanup
+  namespace: shop
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            resources:
+              requests:
+                cpu: 100m
+---
+# VerticalWorkloadAutoscaler shop/web, Deployment web
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: 500m
+            memory: 256Mi
+      - name: sidecar
+        resources:
+          requests:
+            cpu: 50m
+`,
+		},
+		{
+			name:         "Helm values selected by label",
+			query:        "labelSelector=team%3Dcheckout&format=helm",
+			expectedCode: http.StatusOK,
+			expectedType: "application/yaml",
+			expectedBody: `# VerticalWorkloadAutoscaler other/api, StatefulSet api
+api:
+  resources:
+    requests:
+      cpu: "1"
+---
+# VerticalWorkloadAutoscaler shop/web, Deployment web
+app:
+  resources:
+    requests:
+      cpu: 500m
+      memory: 256Mi
+sidecar:
+  resources:
+    requests:
+      cpu: 50m
+`,
+		},
+		{
+			name:         "JSON",
+			query:        "namespace=shop&name=web&format=json",
+			expectedCode: http.StatusOK,
+			expectedType: "application/json",
+			expectedBody: `[
+  {
+    "namespace": "shop",
+    "name": "web",
+    "targetRef": {
+      "kind": "Deployment",
+      "name": "web",
+      "apiVersion": "apps/v1"
+    },
+    "recommendedRequests": {
+      "app": {
+        "requests": {
+          "cpu": "500m",
+          "memory": "256Mi"
+        }
+      },
+      "sidecar": {
+        "requests": {
+          "cpu": "50m"
+        }
+      }
+    },
+    "lastUpdated": "2024-09-21T12:00:00Z"
+  }
+]
+`,
+		},
+		{
+			name:         "No recommendations",
+			query:        "namespace=shop&name=pending&format=json",
+			expectedCode: http.StatusOK,
+			expectedType: "application/json",
+			expectedBody: "[]\n",
+		},
+		{
+			name:         "Unknown format",
+			query:        "format=xml",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "unknown format 'xml': must be kustomize, helm or json\n",
+		},
+		{
+			name:         "Name without namespace",
+			query:        "name=web",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "the name parameter requires the namespace parameter\n",
+		},
+		{
+			name:         "Invalid label selector",
+			query:        "labelSelector=team%3D%3D%3D",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "VWA not found",
+			query:        "namespace=shop&name=missing",
+			expectedCode: http.StatusNotFound,
+			expectedBody: "VerticalWorkloadAutoscaler 'shop/missing' not found\n",
+		},
+		{
+			name:         "List failure",
+			listErr:      errors.New("cache not synced"),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "failed to list VerticalWorkloadAutoscaler objects: cache not synced\n",
+		},
+		{
+			name:         "Method not allowed",
+			method:       http.MethodPost,
+			expectedCode: http.StatusMethodNotAllowed,
+			expectedBody: "only GET is allowed\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(web, cleanup, pending, other)
+			if tt.listErr != nil {
+				builder = builder.WithInterceptorFuncs(interceptor.Funcs{
+					List: func(_ context.Context, _ client.WithWatch, _ client.ObjectList, _ ...client.ListOption) error {
+						return tt.listErr
+					},
+				})
+			}
+			handler := NewRecommendationsHandler(builder.Build())
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(method, RecommendationsPath+"?"+tt.query, nil))
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedType != "" {
+				assert.Equal(t, tt.expectedType, rec.Header().Get("Content-Type"))
+			}
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestTargetGroupVersionKind(t *testing.T) {
+	tests := []struct {
+		ref      autoscalingv2.CrossVersionObjectReference
+		expected schema.GroupVersionKind
+	}{
+		{ref: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", APIVersion: "apps/v1"}, expected: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}},
+		{ref: autoscalingv2.CrossVersionObjectReference{Kind: "StatefulSet"}, expected: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}},
+		{ref: autoscalingv2.CrossVersionObjectReference{Kind: "Job"}, expected: schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}},
+		{ref: autoscalingv2.CrossVersionObjectReference{Kind: "CronJob", APIVersion: "batch/v1"}, expected: schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref.Kind, func(t *testing.T) {
+			assert.Equal(t, tt.expected, targetGroupVersionKind(tt.ref))
+		})
+	}
+}
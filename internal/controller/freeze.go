@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// changeFreezeSkipReasonPrefix starts the skip reason of the updates held by a change freeze
+const changeFreezeSkipReasonPrefix = "change freeze "
+
+// changeFreezeIsActive checks if the ChangeFreeze hasn't expired yet
+func changeFreezeIsActive(freeze *vwav1.ChangeFreeze, now time.Time) bool {
+	return freeze.Spec.ExpiresAt == nil || now.Before(freeze.Spec.ExpiresAt.Time)
+}
+
+// changeFreezeMatches checks if the ChangeFreeze selects the VWA; nsLabels are the labels of the VWA namespace
+func changeFreezeMatches(freeze *vwav1.ChangeFreeze, wa *vwav1.VerticalWorkloadAutoscaler, nsLabels map[string]string) (bool, error) {
+	if freeze.Spec.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(freeze.Spec.NamespaceSelector)
+		if err != nil {
+			return false, fmt.Errorf("invalid namespaceSelector of ChangeFreeze '%s': %w", freeze.Name, err)
+		}
+		if !selector.Matches(labels.Set(nsLabels)) {
+			return false, nil
+		}
+	}
+	if freeze.Spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(freeze.Spec.Selector)
+		if err != nil {
+			return false, fmt.Errorf("invalid selector of ChangeFreeze '%s': %w", freeze.Name, err)
+		}
+		if !selector.Matches(labels.Set(wa.Labels)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// namespaceLabels returns the labels of the namespace
+func (r *VerticalWorkloadAutoscalerReconciler) namespaceLabels(ctx context.Context, namespace string) (map[string]string, error) {
+	var ns corev1.Namespace
+	if err := r.Get(ctx, client.ObjectKey{Name: namespace}, &ns); err != nil {
+		return nil, err
+	}
+	return ns.Labels, nil
+}
+
+// findActiveChangeFreeze returns the active ChangeFreeze that selects the VWA, if any.
+// It also refreshes the vwa_change_freeze_active metric of every ChangeFreeze object.
+func (r *VerticalWorkloadAutoscalerReconciler) findActiveChangeFreeze(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) (*vwav1.ChangeFreeze, error) {
+	var freezeList vwav1.ChangeFreezeList
+	if err := r.List(ctx, &freezeList); err != nil {
+		return nil, err
+	}
+
+	now := timeNow()
+	var nsLabels map[string]string
+	var active *vwav1.ChangeFreeze
+	for i := range freezeList.Items {
+		freeze := &freezeList.Items[i]
+		if !changeFreezeIsActive(freeze, now) {
+			changeFreezeActive.WithLabelValues(freeze.Name).Set(0)
+			continue
+		}
+		changeFreezeActive.WithLabelValues(freeze.Name).Set(1)
+		if active != nil {
+			continue
+		}
+
+		if freeze.Spec.NamespaceSelector != nil && nsLabels == nil {
+			l, err := r.namespaceLabels(ctx, wa.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			nsLabels = l
+		}
+		matches, err := changeFreezeMatches(freeze, wa, nsLabels)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			active = freeze
+		}
+	}
+	return active, nil
+}
+
+// updateFreezeStatus sets the Frozen condition and metric of the VWA; the condition is only
+// reset when the VWA was frozen before
+func (r *VerticalWorkloadAutoscalerReconciler) updateFreezeStatus(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, freeze *vwav1.ChangeFreeze) error {
+	vwaFrozen.DeletePartialMatch(map[string]string{"namespace": wa.Namespace, "name": wa.Name})
+	if freeze == nil {
+		if condition := findCondition(wa.Status.Conditions, ConditionTypeFrozen); condition == nil || condition.Status == metav1.ConditionFalse {
+			return nil
+		}
+		r.recordEvent(wa, "Normal", ReasonNoChangeFreeze, "change freeze lifted")
+		if strings.HasPrefix(wa.Status.SkipReason, changeFreezeSkipReasonPrefix) {
+			wa.Status.SkippedUpdates = false
+			wa.Status.SkipReason = ""
+		}
+		return r.updateStatusCondition(ctx, wa, ConditionTypeFrozen, metav1.ConditionFalse, ReasonNoChangeFreeze, "no active change freeze")
+	}
+
+	vwaFrozen.WithLabelValues(wa.Namespace, wa.Name, freeze.Name).Set(1)
+	msg := fmt.Sprintf("changes frozen by ChangeFreeze '%s'", freeze.Name)
+	if freeze.Spec.Reason != "" {
+		msg += ": " + freeze.Spec.Reason
+	}
+	if freeze.Spec.ExpiresAt != nil {
+		msg += fmt.Sprintf(" (until %s)", freeze.Spec.ExpiresAt.Format(time.RFC3339))
+	}
+	if condition := findCondition(wa.Status.Conditions, ConditionTypeFrozen); condition != nil && condition.Status == metav1.ConditionTrue && condition.Message == msg {
+		return nil
+	}
+	r.recordEvent(wa, "Warning", ReasonChangeFreeze, msg)
+	return r.updateStatusCondition(ctx, wa, ConditionTypeFrozen, metav1.ConditionTrue, ReasonChangeFreeze, msg)
+}
+
+// handleChangeFreeze records the new recommendations in the VWA status without applying them
+func (r *VerticalWorkloadAutoscalerReconciler) handleChangeFreeze(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, freeze *vwav1.ChangeFreeze, newResources map[string]corev1.ResourceRequirements) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("change freeze active, recording recommendations only", "VWA", wa.Name, "ChangeFreeze", freeze.Name)
+
+	wa.Status.RecommendedRequests = newResources
+	wa.Status.SkippedUpdates = true
+	wa.Status.SkipReason = fmt.Sprintf("%s'%s' is active", changeFreezeSkipReasonPrefix, freeze.Name)
+	if err := r.Status().Update(ctx, wa); err != nil {
+		return r.handleError(ctx, wa, err, "failed to record recommendations", ReasonAPIError, "failed to record recommendations")
+	}
+
+	if freeze.Spec.ExpiresAt != nil {
+		return ctrl.Result{RequeueAfter: freeze.Spec.ExpiresAt.Sub(timeNow())}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// changeFreezeDeleted drops the vwa_change_freeze_active metric of the deleted ChangeFreeze
+func changeFreezeDeleted(e event.DeleteEvent) bool {
+	changeFreezeActive.DeleteLabelValues(e.Object.GetName())
+	return true
+}
+
+// findVWAForChangeFreeze maps a ChangeFreeze to the VWAs it selects
+func (r *VerticalWorkloadAutoscalerReconciler) findVWAForChangeFreeze(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := make([]reconcile.Request, 0)
+	freeze, ok := obj.(*vwav1.ChangeFreeze)
+	if !ok {
+		return requests
+	}
+
+	var vwaList vwav1.VerticalWorkloadAutoscalerList
+	if err := r.List(ctx, &vwaList); err != nil {
+		log.Log.Error(err, "failed to list VerticalWorkloadAutoscaler objects")
+		return requests
+	}
+
+	nsLabels := make(map[string]map[string]string)
+	for i := range vwaList.Items {
+		vwa := &vwaList.Items[i]
+		if _, ok := nsLabels[vwa.Namespace]; !ok && freeze.Spec.NamespaceSelector != nil {
+			l, err := r.namespaceLabels(ctx, vwa.Namespace)
+			if err != nil {
+				log.Log.Error(err, "failed to get namespace", "namespace", vwa.Namespace)
+				continue
+			}
+			nsLabels[vwa.Namespace] = l
+		}
+		matches, err := changeFreezeMatches(freeze, vwa, nsLabels[vwa.Namespace])
+		if err != nil {
+			log.Log.Error(err, "failed to match ChangeFreeze", "ChangeFreeze", freeze.Name)
+			return requests
+		}
+		if matches {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKey{Namespace: vwa.Namespace, Name: vwa.Name},
+			})
+		}
+	}
+	return requests
+}
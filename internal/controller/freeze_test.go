@@ -0,0 +1,313 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestFindActiveChangeFreeze(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	timeNow = func() time.Time { return time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC) }
+
+	namespaces := []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"tier": "0"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+	}
+	expired := metav1.NewTime(time.Date(2023, 10, 10, 9, 0, 0, 0, time.UTC))
+	future := metav1.NewTime(time.Date(2023, 10, 10, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name           string
+		vwa            *vwav1.VerticalWorkloadAutoscaler
+		freezes        []client.Object
+		expectedFreeze string
+		expectError    bool
+	}{
+		{
+			name:    "No change freezes",
+			vwa:     &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"}},
+			freezes: []client.Object{},
+		},
+		{
+			name: "Global freeze",
+			vwa:  &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"}},
+			freezes: []client.Object{
+				&vwav1.ChangeFreeze{ObjectMeta: metav1.ObjectMeta{Name: "incident-42"}, Spec: vwav1.ChangeFreezeSpec{ExpiresAt: &future}},
+			},
+			expectedFreeze: "incident-42",
+		},
+		{
+			name: "Expired freeze",
+			vwa:  &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"}},
+			freezes: []client.Object{
+				&vwav1.ChangeFreeze{ObjectMeta: metav1.ObjectMeta{Name: "incident-41"}, Spec: vwav1.ChangeFreezeSpec{ExpiresAt: &expired}},
+			},
+		},
+		{
+			name: "Namespace selector matches",
+			vwa:  &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "payments"}},
+			freezes: []client.Object{
+				&vwav1.ChangeFreeze{ObjectMeta: metav1.ObjectMeta{Name: "tier0"}, Spec: vwav1.ChangeFreezeSpec{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "0"}},
+				}},
+			},
+			expectedFreeze: "tier0",
+		},
+		{
+			name: "Namespace selector doesn't match",
+			vwa:  &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"}},
+			freezes: []client.Object{
+				&vwav1.ChangeFreeze{ObjectMeta: metav1.ObjectMeta{Name: "tier0"}, Spec: vwav1.ChangeFreezeSpec{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "0"}},
+				}},
+			},
+		},
+		{
+			name: "Label selector matches",
+			vwa: &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{
+				Name: "vwa1", Namespace: "default", Labels: map[string]string{"team": "checkout"},
+			}},
+			freezes: []client.Object{
+				&vwav1.ChangeFreeze{ObjectMeta: metav1.ObjectMeta{Name: "checkout"}, Spec: vwav1.ChangeFreezeSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "checkout"}},
+				}},
+			},
+			expectedFreeze: "checkout",
+		},
+		{
+			name: "Invalid selector",
+			vwa:  &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"}},
+			freezes: []client.Object{
+				&vwav1.ChangeFreeze{ObjectMeta: metav1.ObjectMeta{Name: "broken"}, Spec: vwav1.ChangeFreezeSpec{
+					Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Bogus"}}},
+				}},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(namespaces, tt.freezes...)...).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+			freeze, err := r.findActiveChangeFreeze(context.Background(), tt.vwa)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.expectedFreeze == "" {
+				assert.Nil(t, freeze)
+				return
+			}
+			assert.NotNil(t, freeze)
+			assert.Equal(t, tt.expectedFreeze, freeze.Name)
+		})
+	}
+}
+
+func TestFindVWAForChangeFreeze(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	objs := []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"tier": "0"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "payments"}},
+		&vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "vwa2", Namespace: "default"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+	freeze := &vwav1.ChangeFreeze{ObjectMeta: metav1.ObjectMeta{Name: "tier0"}, Spec: vwav1.ChangeFreezeSpec{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "0"}},
+	}}
+	assert.Equal(t, []reconcile.Request{{NamespacedName: client.ObjectKey{Name: "vwa1", Namespace: "payments"}}}, r.findVWAForChangeFreeze(context.Background(), freeze))
+
+	global := &vwav1.ChangeFreeze{ObjectMeta: metav1.ObjectMeta{Name: "global"}}
+	assert.Len(t, r.findVWAForChangeFreeze(context.Background(), global), 2)
+}
+
+func TestHandleVWAChangeDuringChangeFreeze(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	_ = autoscalingv2.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	// the freeze is recorded before the update window opens
+	timeNow = func() time.Time { return time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC) }
+
+	updateModeOff := vpav1.UpdateModeOff
+	expiresAt := metav1.NewTime(time.Date(2023, 10, 10, 12, 0, 0, 0, time.UTC))
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference:         vwav1.VPAReference{Name: "vpa1"},
+			AllowedUpdateWindows: []vwav1.UpdateWindow{{DayOfWeek: "Tuesday", StartTime: "11:00", EndTime: "13:00", TimeZone: "UTC"}},
+		},
+	}
+	vpa := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &updateModeOff},
+			TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "deployment1"},
+		},
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Recommendation: &vpav1.RecommendedPodResources{
+				ContainerRecommendations: []vpav1.RecommendedContainerResources{
+					{
+						ContainerName: "container1",
+						Target: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("500m"),
+							corev1.ResourceMemory: resource.MustParse("256Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+	current := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("250m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployment1", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "container1", Resources: current}}},
+			},
+		},
+	}
+	freeze := &vwav1.ChangeFreeze{
+		ObjectMeta: metav1.ObjectMeta{Name: "incident-42"},
+		Spec:       vwav1.ChangeFreezeSpec{Reason: "INC-42", ExpiresAt: &expiresAt},
+	}
+
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment, freeze).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+	result, err := r.handleVWAChange(context.Background(), vwa)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, result.RequeueAfter)
+
+	// the recommendation is recorded but not applied
+	updatedDeployment := &appsv1.Deployment{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), updatedDeployment))
+	assert.Equal(t, current, updatedDeployment.Spec.Template.Spec.Containers[0].Resources)
+	assert.Contains(t, vwa.Status.RecommendedRequests, "container1")
+	assert.True(t, vwa.Status.SkippedUpdates)
+	assert.Equal(t, int32(0), vwa.Status.UpdateCount)
+
+	condition := findCondition(vwa.Status.Conditions, ConditionTypeFrozen)
+	assert.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Contains(t, condition.Message, "INC-42")
+
+	// lifting the freeze resets the Frozen condition and applies the recommendation in the update window
+	timeNow = func() time.Time { return time.Date(2023, 10, 10, 11, 30, 0, 0, time.UTC) }
+	assert.NoError(t, c.Delete(context.Background(), freeze))
+	_, err = r.handleVWAChange(context.Background(), vwa)
+	assert.NoError(t, err)
+	condition = findCondition(vwa.Status.Conditions, ConditionTypeFrozen)
+	assert.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), updatedDeployment))
+	assert.Equal(t, resource.MustParse("500m"), updatedDeployment.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU])
+}
+
+func TestUpdateFreezeStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	timeNow = func() time.Time { return time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC) }
+	frozen := []metav1.Condition{{Type: ConditionTypeFrozen, Status: metav1.ConditionTrue, Reason: ReasonChangeFreeze}}
+
+	tests := []struct {
+		name                   string
+		skipReason             string
+		expectedSkippedUpdates bool
+		expectedSkipReason     string
+	}{
+		{
+			name:       "Skip reason of the lifted freeze is cleared",
+			skipReason: "change freeze 'incident-42' is active",
+		},
+		{
+			name:                   "Other skip reason is kept",
+			skipReason:             "updates blocked by blackout 'Release'",
+			expectedSkippedUpdates: true,
+			expectedSkipReason:     "updates blocked by blackout 'Release'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{
+					Conditions:     slices.Clone(frozen),
+					SkippedUpdates: true,
+					SkipReason:     tt.skipReason,
+				},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(wa).WithObjects(wa).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+			assert.NoError(t, r.updateFreezeStatus(context.Background(), wa, nil))
+			stored := &vwav1.VerticalWorkloadAutoscaler{}
+			assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(wa), stored))
+			assert.Equal(t, tt.expectedSkippedUpdates, stored.Status.SkippedUpdates)
+			assert.Equal(t, tt.expectedSkipReason, stored.Status.SkipReason)
+			assert.Equal(t, metav1.ConditionFalse, findCondition(stored.Status.Conditions, ConditionTypeFrozen).Status)
+		})
+	}
+}
+
+func TestChangeFreezeActiveMetric(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	timeNow = func() time.Time { return time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC) }
+	changeFreezeActive.Reset()
+
+	expired := metav1.NewTime(time.Date(2023, 10, 9, 0, 0, 0, 0, time.UTC))
+	freezes := []client.Object{
+		&vwav1.ChangeFreeze{ObjectMeta: metav1.ObjectMeta{Name: "incident-42"}},
+		&vwav1.ChangeFreeze{ObjectMeta: metav1.ObjectMeta{Name: "release"}, Spec: vwav1.ChangeFreezeSpec{ExpiresAt: &expired}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(freezes...).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+	wa := &vwav1.VerticalWorkloadAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"}}
+
+	_, err := r.findActiveChangeFreeze(context.Background(), wa)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(changeFreezeActive.WithLabelValues("incident-42")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(changeFreezeActive.WithLabelValues("release")))
+
+	// the metric of a deleted freeze is dropped
+	assert.True(t, changeFreezeDeleted(event.DeleteEvent{Object: freezes[0]}))
+	assert.Equal(t, 1, testutil.CollectAndCount(changeFreezeActive))
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	// argoCDTrackingIDAnnotation is set by Argo CD annotation based resource tracking: <app>:<group>/<kind>:<namespace>/<name>
+	argoCDTrackingIDAnnotation = "argocd.argoproj.io/tracking-id"
+	// argoCDInstanceLabel is set by Argo CD label based resource tracking (the default)
+	argoCDInstanceLabel = "app.kubernetes.io/instance"
+	// fluxKustomizationNameLabel and fluxKustomizationNamespaceLabel are set by the Flux kustomize-controller
+	fluxKustomizationNameLabel      = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizationNamespaceLabel = "kustomize.toolkit.fluxcd.io/namespace"
+	// fluxHelmReleaseNameLabel and fluxHelmReleaseNamespaceLabel are set by the Flux helm-controller
+	fluxHelmReleaseNameLabel      = "helm.toolkit.fluxcd.io/name"
+	fluxHelmReleaseNamespaceLabel = "helm.toolkit.fluxcd.io/namespace"
+)
+
+// detectGitOpsOwner returns the GitOps object managing the workload, detected with the tool tracking
+// labels and annotations. The Argo CD instance label is also set by Helm, so it is only trusted
+// when the VWA integrates with Argo CD.
+func detectGitOpsOwner(workload client.Object, gitOps *vwav1.GitOpsIntegration) *vwav1.GitOpsOwner {
+	labels := workload.GetLabels()
+	if name := labels[fluxKustomizationNameLabel]; name != "" {
+		return &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolFlux, Kind: "Kustomization", Name: name, Namespace: labels[fluxKustomizationNamespaceLabel]}
+	}
+	if name := labels[fluxHelmReleaseNameLabel]; name != "" {
+		return &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolFlux, Kind: "HelmRelease", Name: name, Namespace: labels[fluxHelmReleaseNamespaceLabel]}
+	}
+	if trackingID := workload.GetAnnotations()[argoCDTrackingIDAnnotation]; trackingID != "" {
+		app, _, _ := strings.Cut(trackingID, ":")
+		// applications outside of the Argo CD namespace are tracked as <namespace>_<name>
+		if namespace, name, found := strings.Cut(app, "_"); found {
+			return &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolArgoCD, Kind: "Application", Name: name, Namespace: namespace}
+		}
+		return &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolArgoCD, Kind: "Application", Name: app}
+	}
+	if name := labels[argoCDInstanceLabel]; name != "" && gitOps != nil && gitOps.Tool == vwav1.GitOpsToolArgoCD {
+		return &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolArgoCD, Kind: "Application", Name: name}
+	}
+	return nil
+}
+
+// argoCDIgnoreDifferences returns the Argo CD ignoreDifferences entry of the fields the VWA owns in the workload,
+// so Argo CD ignores the VWA resources and annotations but still syncs the rest of the workload
+func argoCDIgnoreDifferences(gvk schema.GroupVersionKind, workload client.Object) *vwav1.ArgoCDIgnoreDifferences {
+	return &vwav1.ArgoCDIgnoreDifferences{
+		Group:                 gvk.Group,
+		Kind:                  gvk.Kind,
+		Name:                  workload.GetName(),
+		Namespace:             workload.GetNamespace(),
+		ManagedFieldsManagers: []string{FieldManager},
+	}
+}
+
+// updateGitOpsOwner updates the VWA status with the GitOps object managing the target workload
+func (r *VerticalWorkloadAutoscalerReconciler) updateGitOpsOwner(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, targetObject client.Object) error {
+	owner := detectGitOpsOwner(targetObject, wa.Spec.GitOps)
+	if owner != nil && owner.Tool == vwav1.GitOpsToolArgoCD {
+		gvk, err := apiutil.GVKForObject(targetObject, r.Client.Scheme())
+		if err != nil {
+			return fmt.Errorf("failed to get target object kind: %w", err)
+		}
+		owner.IgnoreDifferences = argoCDIgnoreDifferences(gvk, targetObject)
+	}
+	if equality.Semantic.DeepEqual(owner, wa.Status.GitOpsOwner) {
+		return nil
+	}
+	wa.Status.GitOpsOwner = owner
+	if err := r.Status().Update(ctx, wa); err != nil {
+		return err
+	}
+	if owner != nil {
+		msg := fmt.Sprintf("target managed by %s %s '%s'", owner.Tool, owner.Kind, owner.Name)
+		if owner.IgnoreDifferences != nil {
+			msg += ", add status.gitOpsOwner.ignoreDifferences to its ignoreDifferences with the RespectIgnoreDifferences=true sync option"
+		}
+		r.recordEvent(wa, "Normal", "GitOpsOwnerDetected", msg)
+		if wa.Spec.GitOps == nil || wa.Spec.GitOps.Tool != owner.Tool {
+			r.recordEvent(wa, "Warning", "GitOpsNotConfigured",
+				fmt.Sprintf("target managed by %s, set spec.gitOps.tool to %s", owner.Tool, owner.Tool))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDetectGitOpsOwner(t *testing.T) {
+	argoCD := &vwav1.GitOpsIntegration{Tool: vwav1.GitOpsToolArgoCD}
+
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		gitOps      *vwav1.GitOpsIntegration
+		expected    *vwav1.GitOpsOwner
+	}{
+		{name: "Not managed by GitOps"},
+		{
+			name:     "Flux Kustomization",
+			labels:   map[string]string{fluxKustomizationNameLabel: "apps", fluxKustomizationNamespaceLabel: "flux-system"},
+			expected: &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolFlux, Kind: "Kustomization", Name: "apps", Namespace: "flux-system"},
+		},
+		{
+			name:     "Flux HelmRelease",
+			labels:   map[string]string{fluxHelmReleaseNameLabel: "web", fluxHelmReleaseNamespaceLabel: "default"},
+			expected: &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolFlux, Kind: "HelmRelease", Name: "web", Namespace: "default"},
+		},
+		{
+			name:        "Argo CD annotation tracking",
+			annotations: map[string]string{argoCDTrackingIDAnnotation: "web:apps/Deployment:default/web"},
+			expected:    &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolArgoCD, Kind: "Application", Name: "web"},
+		},
+		{
+			name:        "Argo CD annotation tracking of an application in any namespace",
+			annotations: map[string]string{argoCDTrackingIDAnnotation: "team-a_web:apps/Deployment:default/web"},
+			expected:    &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolArgoCD, Kind: "Application", Name: "web", Namespace: "team-a"},
+		},
+		{
+			name:     "Argo CD label tracking",
+			labels:   map[string]string{argoCDInstanceLabel: "web"},
+			gitOps:   argoCD,
+			expected: &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolArgoCD, Kind: "Application", Name: "web"},
+		},
+		{
+			name:   "Instance label without Argo CD integration",
+			labels: map[string]string{argoCDInstanceLabel: "web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workload := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: tt.labels, Annotations: tt.annotations}}
+			assert.Equal(t, tt.expected, detectGitOpsOwner(workload, tt.gitOps))
+		})
+	}
+}
+
+func TestUpdateGitOpsOwner(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	fluxWorkload := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:      "web",
+		Namespace: "default",
+		Labels:    map[string]string{fluxKustomizationNameLabel: "apps", fluxKustomizationNamespaceLabel: "flux-system"},
+	}}
+	argoCDWorkload := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:        "web",
+		Namespace:   "default",
+		Annotations: map[string]string{argoCDTrackingIDAnnotation: "web:apps/Deployment:default/web"},
+	}}
+
+	tests := []struct {
+		name           string
+		workload       client.Object
+		gitOps         *vwav1.GitOpsIntegration
+		expectedOwner  *vwav1.GitOpsOwner
+		expectedEvents int
+	}{
+		{
+			name:           "GitOps integration configured",
+			workload:       fluxWorkload,
+			gitOps:         &vwav1.GitOpsIntegration{Tool: vwav1.GitOpsToolFlux},
+			expectedOwner:  &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolFlux, Kind: "Kustomization", Name: "apps", Namespace: "flux-system"},
+			expectedEvents: 1,
+		},
+		{
+			name:           "GitOps integration not configured",
+			workload:       fluxWorkload,
+			expectedOwner:  &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolFlux, Kind: "Kustomization", Name: "apps", Namespace: "flux-system"},
+			expectedEvents: 2,
+		},
+		{
+			name:           "Other GitOps tool configured",
+			workload:       fluxWorkload,
+			gitOps:         &vwav1.GitOpsIntegration{Tool: vwav1.GitOpsToolArgoCD},
+			expectedOwner:  &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolFlux, Kind: "Kustomization", Name: "apps", Namespace: "flux-system"},
+			expectedEvents: 2,
+		},
+		{
+			name:     "Argo CD ignoreDifferences scoped to the VWA field manager",
+			workload: argoCDWorkload,
+			gitOps:   &vwav1.GitOpsIntegration{Tool: vwav1.GitOpsToolArgoCD},
+			expectedOwner: &vwav1.GitOpsOwner{Tool: vwav1.GitOpsToolArgoCD, Kind: "Application", Name: "web",
+				IgnoreDifferences: &vwav1.ArgoCDIgnoreDifferences{Group: "apps", Kind: "Deployment", Name: "web", Namespace: "default",
+					ManagedFieldsManagers: []string{FieldManager}}},
+			expectedEvents: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Spec:       vwav1.VerticalWorkloadAutoscalerSpec{GitOps: tt.gitOps},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(wa).WithObjects(wa).Build()
+			recorder := record.NewFakeRecorder(10)
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+
+			require.NoError(t, r.updateGitOpsOwner(context.Background(), wa, tt.workload))
+			// an unchanged owner is not reported again
+			require.NoError(t, r.updateGitOpsOwner(context.Background(), wa, tt.workload))
+
+			stored := &vwav1.VerticalWorkloadAutoscaler{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(wa), stored))
+			assert.Equal(t, tt.expectedOwner, stored.Status.GitOpsOwner)
+			assert.Len(t, recorder.Events, tt.expectedEvents)
+		})
+	}
+}
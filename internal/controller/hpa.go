@@ -2,6 +2,8 @@ package controller
 
 import (
 	"context"
+	"slices"
+	"strings"
 
 	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
@@ -64,3 +66,119 @@ func (r *VerticalWorkloadAutoscalerReconciler) getIgnoreFlags(hpa *autoscalingv2
 	}
 	return ignoreCPU, ignoreMemory
 }
+
+// getContainerIgnores returns the containers with recommendations ignored for the HPA ContainerResource metrics,
+// sorted by name
+func (r *VerticalWorkloadAutoscalerReconciler) getContainerIgnores(hpa *autoscalingv2.HorizontalPodAutoscaler) []vwav1.ContainerIgnore {
+	var ignores []vwav1.ContainerIgnore
+	for _, metric := range hpa.Spec.Metrics {
+		if metric.Type != autoscalingv2.ContainerResourceMetricSourceType || metric.ContainerResource == nil {
+			continue
+		}
+		ignores = mergeContainerIgnores(ignores, []vwav1.ContainerIgnore{{
+			Name:   metric.ContainerResource.Container,
+			CPU:    metric.ContainerResource.Name == corev1.ResourceCPU,
+			Memory: metric.ContainerResource.Name == corev1.ResourceMemory,
+		}})
+	}
+	return ignores
+}
+
+// hasCustomMetrics returns whether the HPA scales on Pods, Object or External metrics
+func hasCustomMetrics(hpa *autoscalingv2.HorizontalPodAutoscaler) bool {
+	return slices.ContainsFunc(hpa.Spec.Metrics, func(metric autoscalingv2.MetricSpec) bool {
+		return metric.Type == autoscalingv2.PodsMetricSourceType || metric.Type == autoscalingv2.ObjectMetricSourceType ||
+			metric.Type == autoscalingv2.ExternalMetricSourceType
+	})
+}
+
+// effectiveIgnore returns the resource recommendations to ignore: the ones ignored by the VWA spec, the ones of
+// the resources the HPA and the KEDA scalers of the target workload use as metrics or triggers and the ones set
+// by the custom metrics policy; nil when none are ignored
+func (r *VerticalWorkloadAutoscalerReconciler) effectiveIgnore(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) (*vwav1.EffectiveIgnore, error) {
+	spec := r.effectiveSpec(wa)
+	ignore := vwav1.EffectiveIgnore{CPU: spec.IgnoreCPURecommendations, Memory: spec.IgnoreMemoryRecommendations}
+	var sources []string
+	hpa, err := r.findHPAForVWA(ctx, wa)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	// the CPU increases are applied while the HPA can't add more replicas
+	saturated := hpa != nil && saturatedScaleUp(wa, hpa)
+	if hpa != nil {
+		// the recommendations of the resource metrics are applied when the VWA scales the HPA targets with them
+		ignoreCPU, ignoreMemory := false, false
+		if !r.coordinatesHPA(wa, hpa) {
+			ignoreCPU, ignoreMemory = r.getIgnoreFlags(hpa)
+			ignore.Containers = r.getContainerIgnores(hpa)
+		}
+		if hasCustomMetrics(hpa) && spec.HPAPolicy != nil {
+			switch spec.HPAPolicy.CustomMetrics {
+			case vwav1.CustomMetricsIgnoreCPU:
+				ignoreCPU = true
+			case vwav1.CustomMetricsIgnoreAll:
+				ignoreCPU, ignoreMemory = true, true
+			}
+		}
+		if saturated {
+			ignoreCPU = false
+			ignore.Containers = withoutCPUIgnores(ignore.Containers)
+		}
+		if ignoreCPU || ignoreMemory || len(ignore.Containers) > 0 {
+			sources = append(sources, "HorizontalPodAutoscaler/"+hpa.Name)
+		}
+		ignore.CPU = ignore.CPU || ignoreCPU
+		ignore.Memory = ignore.Memory || ignoreMemory
+	}
+
+	// KEDA creates an HPA for a ScaledObject, but not for a ScaledJob
+	scalers, err := r.findKEDAScalers(ctx, wa.Namespace, wa.Status.ScaleTargetRef.Kind, wa.Status.ScaleTargetRef.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, scaler := range scalers {
+		// a ScaledObject scales the workload through the saturated HPA
+		if saturated && scaler.kind == scaledObjectGVK.Kind {
+			scaler.cpu = false
+			scaler.containers = withoutCPUIgnores(scaler.containers)
+		}
+		if scaler.cpu || scaler.memory || len(scaler.containers) > 0 {
+			sources = append(sources, scaler.ref())
+		}
+		ignore.CPU = ignore.CPU || scaler.cpu
+		ignore.Memory = ignore.Memory || scaler.memory
+		ignore.Containers = mergeContainerIgnores(ignore.Containers, scaler.containers)
+	}
+	ignore.Source = strings.Join(sources, ",")
+
+	if !ignore.CPU && !ignore.Memory && len(ignore.Containers) == 0 {
+		return nil, nil
+	}
+	return &ignore, nil
+}
+
+// withoutCPUIgnores returns the container ignores without the CPU ones
+func withoutCPUIgnores(ignores []vwav1.ContainerIgnore) []vwav1.ContainerIgnore {
+	var memoryIgnores []vwav1.ContainerIgnore
+	for _, ignore := range ignores {
+		if ignore.Memory {
+			memoryIgnores = append(memoryIgnores, vwav1.ContainerIgnore{Name: ignore.Name, Memory: true})
+		}
+	}
+	return memoryIgnores
+}
+
+// ignoredResources returns whether the CPU and memory recommendations of the container are ignored
+func ignoredResources(ignore *vwav1.EffectiveIgnore, container string) (ignoreCPU, ignoreMemory bool) {
+	if ignore == nil {
+		return false, false
+	}
+	ignoreCPU, ignoreMemory = ignore.CPU, ignore.Memory
+	for _, c := range ignore.Containers {
+		if c.Name == container {
+			ignoreCPU = ignoreCPU || c.CPU
+			ignoreMemory = ignoreMemory || c.Memory
+		}
+	}
+	return ignoreCPU, ignoreMemory
+}
@@ -3,12 +3,20 @@ package controller
 import (
 	"context"
 	"testing"
+	"time"
 
 	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -295,3 +303,221 @@ func TestFindVWAForHPA(t *testing.T) {
 		})
 	}
 }
+
+// metricHPA returns an HPA scaling the web Deployment on the metrics
+func metricHPA(metrics ...autoscalingv2.MetricSpec) *autoscalingv2.HorizontalPodAutoscaler {
+	hpa := resourceMetricHPA("web-hpa")
+	hpa.Spec.Metrics = metrics
+	return hpa
+}
+
+// containerResourceMetric returns a ContainerResource metric of the container resource
+func containerResourceMetric(container string, resource corev1.ResourceName) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type:              autoscalingv2.ContainerResourceMetricSourceType,
+		ContainerResource: &autoscalingv2.ContainerResourceMetricSource{Name: resource, Container: container},
+	}
+}
+
+var podsMetric = autoscalingv2.MetricSpec{
+	Type: autoscalingv2.PodsMetricSourceType,
+	Pods: &autoscalingv2.PodsMetricSource{Metric: autoscalingv2.MetricIdentifier{Name: "requests_per_second"}},
+}
+
+func TestGetContainerIgnores(t *testing.T) {
+	tests := []struct {
+		name     string
+		hpa      *autoscalingv2.HorizontalPodAutoscaler
+		expected []vwav1.ContainerIgnore
+	}{
+		{
+			name: "No ContainerResource metrics",
+			hpa:  metricHPA(podsMetric),
+		},
+		{
+			name: "ContainerResource metrics",
+			hpa: metricHPA(
+				containerResourceMetric("proxy", corev1.ResourceMemory),
+				containerResourceMetric("app", corev1.ResourceCPU),
+				containerResourceMetric("proxy", corev1.ResourceCPU),
+			),
+			expected: []vwav1.ContainerIgnore{{Name: "app", CPU: true}, {Name: "proxy", CPU: true, Memory: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &VerticalWorkloadAutoscalerReconciler{}
+			assert.Equal(t, tt.expected, r.getContainerIgnores(tt.hpa))
+		})
+	}
+}
+
+func TestIgnoredResources(t *testing.T) {
+	ignore := &vwav1.EffectiveIgnore{Memory: true, Containers: []vwav1.ContainerIgnore{{Name: "app", CPU: true}}}
+
+	tests := []struct {
+		name           string
+		ignore         *vwav1.EffectiveIgnore
+		container      string
+		expectedCPU    bool
+		expectedMemory bool
+	}{
+		{
+			name:      "Nothing ignored",
+			container: "app",
+		},
+		{
+			name:           "Ignored container",
+			ignore:         ignore,
+			container:      "app",
+			expectedCPU:    true,
+			expectedMemory: true,
+		},
+		{
+			name:           "Other container",
+			ignore:         ignore,
+			container:      "proxy",
+			expectedMemory: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ignoreCPU, ignoreMemory := ignoredResources(tt.ignore, tt.container)
+			assert.Equal(t, tt.expectedCPU, ignoreCPU)
+			assert.Equal(t, tt.expectedMemory, ignoreMemory)
+		})
+	}
+}
+
+func TestEffectiveIgnore(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = vwav1.AddToScheme(s)
+	_ = autoscalingv2.AddToScheme(s)
+
+	tests := []struct {
+		name     string
+		spec     vwav1.VerticalWorkloadAutoscalerSpec
+		hpa      *autoscalingv2.HorizontalPodAutoscaler
+		expected *vwav1.EffectiveIgnore
+	}{
+		{
+			name: "Nothing ignored",
+			hpa:  resourceMetricHPA("web-hpa"),
+		},
+		{
+			name:     "Ignored by the spec",
+			spec:     vwav1.VerticalWorkloadAutoscalerSpec{IgnoreMemoryRecommendations: true},
+			expected: &vwav1.EffectiveIgnore{Memory: true},
+		},
+		{
+			name:     "Ignored for the HPA",
+			hpa:      resourceMetricHPA("web-hpa", corev1.ResourceCPU),
+			expected: &vwav1.EffectiveIgnore{CPU: true, Source: "HorizontalPodAutoscaler/web-hpa"},
+		},
+		{
+			name:     "Ignored by the spec and for the HPA",
+			spec:     vwav1.VerticalWorkloadAutoscalerSpec{IgnoreMemoryRecommendations: true},
+			hpa:      resourceMetricHPA("web-hpa", corev1.ResourceCPU),
+			expected: &vwav1.EffectiveIgnore{CPU: true, Memory: true, Source: "HorizontalPodAutoscaler/web-hpa"},
+		},
+		{
+			name: "Ignored for the HPA ContainerResource metrics",
+			hpa:  metricHPA(containerResourceMetric("app", corev1.ResourceCPU)),
+			expected: &vwav1.EffectiveIgnore{
+				Containers: []vwav1.ContainerIgnore{{Name: "app", CPU: true}},
+				Source:     "HorizontalPodAutoscaler/web-hpa",
+			},
+		},
+		{
+			name: "Custom metrics allowed by default",
+			hpa:  metricHPA(podsMetric),
+		},
+		{
+			name:     "Custom metrics ignoring CPU",
+			spec:     vwav1.VerticalWorkloadAutoscalerSpec{HPAPolicy: &vwav1.HPAPolicy{CustomMetrics: vwav1.CustomMetricsIgnoreCPU}},
+			hpa:      metricHPA(podsMetric),
+			expected: &vwav1.EffectiveIgnore{CPU: true, Source: "HorizontalPodAutoscaler/web-hpa"},
+		},
+		{
+			name:     "Custom metrics ignoring all",
+			spec:     vwav1.VerticalWorkloadAutoscalerSpec{HPAPolicy: &vwav1.HPAPolicy{CustomMetrics: vwav1.CustomMetricsIgnoreAll}},
+			hpa:      metricHPA(podsMetric),
+			expected: &vwav1.EffectiveIgnore{CPU: true, Memory: true, Source: "HorizontalPodAutoscaler/web-hpa"},
+		},
+		{
+			name: "Coordinated HPA",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{HPAPolicy: &vwav1.HPAPolicy{CoordinateTargets: true}},
+			hpa:  metricHPA(containerResourceMetric("app", corev1.ResourceCPU), resourceMetricHPA("", corev1.ResourceMemory).Spec.Metrics[0]),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(s)
+			if tt.hpa != nil {
+				builder = builder.WithObjects(tt.hpa)
+			}
+			r := &VerticalWorkloadAutoscalerReconciler{Client: builder.Build()}
+			vwa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Spec:       tt.spec,
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+				},
+			}
+
+			ignore, err := r.effectiveIgnore(context.Background(), vwa)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ignore)
+		})
+	}
+}
+
+func TestHandleVWAChangeWithHPA(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec:       vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "vpa1"}},
+	}
+	vpa := conflictTestVPA(vpav1.UpdateModeOff)
+	vpa.Status.Recommendation = &vpav1.RecommendedPodResources{ContainerRecommendations: []vpav1.RecommendedContainerResources{
+		{ContainerName: "app", Target: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		}},
+	}}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "web:1.0", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+			}}},
+		}}}},
+	}
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment, resourceMetricHPA("web-hpa", corev1.ResourceCPU)).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(100)}
+
+	now = now.Add(10 * time.Minute)
+	result, err := r.handleVWAChange(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.False(t, result.Requeue)
+	assert.Equal(t, &vwav1.EffectiveIgnore{CPU: true, Source: "HorizontalPodAutoscaler/web-hpa"}, vwa.Status.EffectiveIgnore)
+
+	// the HPA keeps scaling on the current CPU requests while the memory requests are updated
+	updated := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), updated))
+	requests := updated.Spec.Template.Spec.Containers[0].Resources.Requests
+	assert.Equal(t, resource.MustParse("250m"), requests[corev1.ResourceCPU])
+	assert.Equal(t, resource.MustParse("512Mi"), requests[corev1.ResourceMemory])
+}
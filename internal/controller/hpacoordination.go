@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// coordinatesHPA checks whether the VWA scales the utilization targets of the HPA instead of ignoring the
+// recommendations of its resource metrics; the HPAs of KEDA ScaledObjects are managed by KEDA
+func (r *VerticalWorkloadAutoscalerReconciler) coordinatesHPA(wa *vwav1.VerticalWorkloadAutoscaler, hpa *autoscalingv2.HorizontalPodAutoscaler) bool {
+	policy := r.effectiveSpec(wa).HPAPolicy
+	if policy == nil || !policy.CoordinateTargets {
+		return false
+	}
+	owner := metav1.GetControllerOf(hpa)
+	return owner == nil || owner.Kind != scaledObjectGVK.Kind
+}
+
+// coordinateHPA scales the averageUtilization targets of the HPA resource metrics with the requests changed from
+// the current to the new resources, so the usage per pod the HPA scales at stays the same, and records the
+// targets in the VWA status. It runs on every reconcile and patches the HPA whenever its targets differ from the
+// recorded thresholds, so a failed patch is retried; it returns whether the VWA status changed.
+func (r *VerticalWorkloadAutoscalerReconciler) coordinateHPA(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, currentResources, newResources map[string]corev1.ResourceRequirements) (bool, error) {
+	hpa, err := r.findHPAForVWA(ctx, wa)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !r.coordinatesHPA(wa, hpa) {
+		return false, nil
+	}
+
+	// the resources of the containers without recommendations are kept
+	resources := make(map[string]corev1.ResourceRequirements, len(currentResources))
+	for name, current := range currentResources {
+		resources[name] = current
+	}
+	for name, updated := range newResources {
+		resources[name] = updated
+	}
+
+	var previous []vwav1.HPATarget
+	if wa.Status.HPACoordination != nil && wa.Status.HPACoordination.HPA == hpa.Name {
+		previous = wa.Status.HPACoordination.Targets
+	}
+	patch := client.MergeFrom(hpa.DeepCopy())
+	var targets []vwav1.HPATarget
+	var utilizations []int32
+	var changes []string
+	for i, metric := range hpa.Spec.Metrics {
+		var name corev1.ResourceName
+		var target *autoscalingv2.MetricTarget
+		container := ""
+		switch {
+		case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil:
+			name, target = metric.Resource.Name, &hpa.Spec.Metrics[i].Resource.Target
+		case metric.Type == autoscalingv2.ContainerResourceMetricSourceType && metric.ContainerResource != nil:
+			name, target = metric.ContainerResource.Name, &hpa.Spec.Metrics[i].ContainerResource.Target
+			container = metric.ContainerResource.Container
+		default:
+			continue
+		}
+		// averageValue targets are absolute and don't depend on the requests
+		if target.Type != autoscalingv2.UtilizationMetricType || target.AverageUtilization == nil {
+			continue
+		}
+		currentRequest := requestsSum(currentResources, container, name)
+		newRequest := requestsSum(resources, container, name)
+		if currentRequest.IsZero() || newRequest.IsZero() {
+			continue
+		}
+
+		key := string(name)
+		if container != "" {
+			key = container + "/" + key
+		}
+		// keep the threshold while the VWA owns the target, so rounding errors don't add up; a target changed
+		// by someone else is taken over with the current requests
+		threshold := scaleQuantity(currentRequest, float64(*target.AverageUtilization)/100)
+		if i := slices.IndexFunc(previous, func(t vwav1.HPATarget) bool { return t.Metric == key }); i >= 0 &&
+			previous[i].AverageUtilization == *target.AverageUtilization {
+			threshold = previous[i].Threshold
+		}
+		utilization := int32(max(1, math.Round(float64(threshold.MilliValue())*100/float64(newRequest.MilliValue()))))
+		targets = append(targets, vwav1.HPATarget{Metric: key, Threshold: threshold, AverageUtilization: *target.AverageUtilization})
+		utilizations = append(utilizations, utilization)
+		if utilization != *target.AverageUtilization {
+			changes = append(changes, fmt.Sprintf("%s %d%% -> %d%%", key, *target.AverageUtilization, utilization))
+			target.AverageUtilization = &utilization
+		}
+	}
+	if len(targets) == 0 {
+		return false, nil
+	}
+
+	coordination := &vwav1.HPACoordination{HPA: hpa.Name, Targets: targets}
+	if wa.Status.HPACoordination != nil {
+		coordination.UpdatedAt = wa.Status.HPACoordination.UpdatedAt
+	}
+	changed := !equality.Semantic.DeepEqual(coordination, wa.Status.HPACoordination)
+	if len(changes) > 0 {
+		// the thresholds are recorded with the targets of the HPA until it is patched, so they survive a failed patch
+		wa.Status.HPACoordination = coordination
+		if err := r.Patch(ctx, hpa, patch); err != nil {
+			return true, fmt.Errorf("failed to patch HPA '%s': %w", hpa.Name, err)
+		}
+		coordination = coordination.DeepCopy()
+		for i := range coordination.Targets {
+			coordination.Targets[i].AverageUtilization = utilizations[i]
+		}
+		now := metav1.NewTime(timeNow())
+		coordination.UpdatedAt = &now
+		changed = true
+		msg := fmt.Sprintf("scaled utilization targets of HPA '%s': %s", hpa.Name, strings.Join(changes, ", "))
+		log.FromContext(ctx).Info(msg)
+		r.recordEvent(wa, "Normal", ReasonHPATargetsCoordinated, msg)
+	}
+	wa.Status.HPACoordination = coordination
+	return changed, nil
+}
+
+// requestsSum returns the requests of the resource of the container, or of all the containers of the pod when
+// the container is empty, like the HPA utilization is computed
+func requestsSum(resources map[string]corev1.ResourceRequirements, container string, name corev1.ResourceName) resource.Quantity {
+	if container != "" {
+		return resources[container].Requests[name]
+	}
+	var sum resource.Quantity
+	for _, requirements := range resources {
+		if request, ok := requirements.Requests[name]; ok {
+			sum.Add(request)
+		}
+	}
+	return sum
+}
+
+// scaleQuantity returns the quantity multiplied by the factor, in the format of the quantity
+func scaleQuantity(q resource.Quantity, factor float64) resource.Quantity {
+	return *resource.NewMilliQuantity(int64(math.Round(float64(q.MilliValue())*factor)), q.Format)
+}
@@ -0,0 +1,310 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// utilizationTarget returns a MetricTarget of the average utilization
+func utilizationTarget(utilization int32) autoscalingv2.MetricTarget {
+	return autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: ptr.To(utilization)}
+}
+
+// metricUtilizations returns the average utilization targets of the HPA metrics, 0 for the other targets
+func metricUtilizations(hpa *autoscalingv2.HorizontalPodAutoscaler) []int32 {
+	var utilizations []int32
+	for _, metric := range hpa.Spec.Metrics {
+		var target autoscalingv2.MetricTarget
+		if metric.Resource != nil {
+			target = metric.Resource.Target
+		} else if metric.ContainerResource != nil {
+			target = metric.ContainerResource.Target
+		}
+		utilizations = append(utilizations, ptr.Deref(target.AverageUtilization, 0))
+	}
+	return utilizations
+}
+
+// cacheThresholds caches the string form of the thresholds, so the quantities compare equal
+func cacheThresholds(coordination *vwav1.HPACoordination) {
+	if coordination == nil {
+		return
+	}
+	for i := range coordination.Targets {
+		_ = coordination.Targets[i].Threshold.String()
+	}
+}
+
+func TestCoordinateHPA(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	cpuMetric := func(target autoscalingv2.MetricTarget) autoscalingv2.MetricSpec {
+		return autoscalingv2.MetricSpec{
+			Type:     autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{Name: corev1.ResourceCPU, Target: target},
+		}
+	}
+	containerCPUMetric := containerResourceMetric("app", corev1.ResourceCPU)
+	containerCPUMetric.ContainerResource.Target = utilizationTarget(50)
+	kedaHPA := metricHPA(cpuMetric(utilizationTarget(80)))
+	kedaHPA.OwnerReferences = []metav1.OwnerReference{{Kind: "ScaledObject", Name: "web-keda", Controller: ptr.To(true)}}
+	resources := func(app, proxy string) map[string]corev1.ResourceRequirements {
+		return map[string]corev1.ResourceRequirements{"app": cpuRequests(app), "proxy": cpuRequests(proxy)}
+	}
+	coordinated := &vwav1.HPAPolicy{CoordinateTargets: true}
+
+	tests := []struct {
+		name                 string
+		policy               *vwav1.HPAPolicy
+		hpa                  *autoscalingv2.HorizontalPodAutoscaler
+		previous             *vwav1.HPACoordination
+		current, new         map[string]corev1.ResourceRequirements
+		expectedUtilizations []int32
+		expected             *vwav1.HPACoordination
+	}{
+		{
+			name:                 "Pod utilization target",
+			policy:               coordinated,
+			hpa:                  metricHPA(cpuMetric(utilizationTarget(80))),
+			current:              resources("200m", "50m"),
+			new:                  resources("450m", "50m"),
+			expectedUtilizations: []int32{40},
+			expected: &vwav1.HPACoordination{
+				HPA:       "web-hpa",
+				Targets:   []vwav1.HPATarget{{Metric: "cpu", Threshold: resource.MustParse("200m"), AverageUtilization: 40}},
+				UpdatedAt: &metav1.Time{Time: now},
+			},
+		},
+		{
+			name:                 "Container utilization target",
+			policy:               coordinated,
+			hpa:                  metricHPA(containerCPUMetric, podsMetric),
+			current:              resources("200m", "50m"),
+			new:                  resources("400m", "100m"),
+			expectedUtilizations: []int32{25, 0},
+			expected: &vwav1.HPACoordination{
+				HPA:       "web-hpa",
+				Targets:   []vwav1.HPATarget{{Metric: "app/cpu", Threshold: resource.MustParse("100m"), AverageUtilization: 25}},
+				UpdatedAt: &metav1.Time{Time: now},
+			},
+		},
+		{
+			name:   "Threshold kept while the target is unchanged",
+			policy: coordinated,
+			hpa:    metricHPA(cpuMetric(utilizationTarget(40))),
+			previous: &vwav1.HPACoordination{
+				HPA:     "web-hpa",
+				Targets: []vwav1.HPATarget{{Metric: "cpu", Threshold: resource.MustParse("200m"), AverageUtilization: 40}},
+			},
+			current:              resources("450m", "50m"),
+			new:                  resources("250m", "50m"),
+			expectedUtilizations: []int32{67},
+			expected: &vwav1.HPACoordination{
+				HPA:       "web-hpa",
+				Targets:   []vwav1.HPATarget{{Metric: "cpu", Threshold: resource.MustParse("200m"), AverageUtilization: 67}},
+				UpdatedAt: &metav1.Time{Time: now},
+			},
+		},
+		{
+			name:   "Threshold recomputed after the target was changed",
+			policy: coordinated,
+			hpa:    metricHPA(cpuMetric(utilizationTarget(60))),
+			previous: &vwav1.HPACoordination{
+				HPA:     "web-hpa",
+				Targets: []vwav1.HPATarget{{Metric: "cpu", Threshold: resource.MustParse("200m"), AverageUtilization: 40}},
+			},
+			current:              resources("450m", "50m"),
+			new:                  resources("450m", "50m"),
+			expectedUtilizations: []int32{60},
+			expected: &vwav1.HPACoordination{
+				HPA:     "web-hpa",
+				Targets: []vwav1.HPATarget{{Metric: "cpu", Threshold: resource.MustParse("300m"), AverageUtilization: 60}},
+			},
+		},
+		{
+			name:   "Average value target",
+			policy: coordinated,
+			hpa: metricHPA(cpuMetric(autoscalingv2.MetricTarget{
+				Type:         autoscalingv2.AverageValueMetricType,
+				AverageValue: ptr.To(resource.MustParse("300m")),
+			})),
+			current:              resources("200m", "50m"),
+			new:                  resources("450m", "50m"),
+			expectedUtilizations: []int32{0},
+		},
+		{
+			name:                 "KEDA HPA",
+			policy:               coordinated,
+			hpa:                  kedaHPA,
+			current:              resources("200m", "50m"),
+			new:                  resources("450m", "50m"),
+			expectedUtilizations: []int32{80},
+		},
+		{
+			name:                 "Coordination disabled",
+			hpa:                  metricHPA(cpuMetric(utilizationTarget(80))),
+			current:              resources("200m", "50m"),
+			new:                  resources("450m", "50m"),
+			expectedUtilizations: []int32{80},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.hpa.DeepCopy()).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Spec:       vwav1.VerticalWorkloadAutoscalerSpec{HPAPolicy: tt.policy},
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{
+					ScaleTargetRef:  autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+					HPACoordination: tt.previous,
+				},
+			}
+
+			_, err := r.coordinateHPA(context.Background(), wa, tt.current, tt.new)
+			require.NoError(t, err)
+			cacheThresholds(tt.expected)
+			cacheThresholds(wa.Status.HPACoordination)
+			assert.Equal(t, tt.expected, wa.Status.HPACoordination)
+			stored := &autoscalingv2.HorizontalPodAutoscaler{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(tt.hpa), stored))
+			assert.Equal(t, tt.expectedUtilizations, metricUtilizations(stored))
+		})
+	}
+}
+
+func TestCoordinateHPARetriesFailedPatch(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	hpa := resourceMetricHPA("web-hpa", corev1.ResourceCPU)
+	hpa.Spec.Metrics[0].Resource.Target = utilizationTarget(80)
+	failPatch := true
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hpa).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if failPatch {
+				return apierrors.NewConflict(schema.GroupResource{Group: "autoscaling", Resource: "horizontalpodautoscalers"}, obj.GetName(), nil)
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+	wa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec:       vwav1.VerticalWorkloadAutoscalerSpec{HPAPolicy: &vwav1.HPAPolicy{CoordinateTargets: true}},
+		Status: vwav1.VerticalWorkloadAutoscalerStatus{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+	}
+	utilizations := func() []int32 {
+		stored := &autoscalingv2.HorizontalPodAutoscaler{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(hpa), stored))
+		return metricUtilizations(stored)
+	}
+
+	// the failed patch keeps the threshold with the target of the HPA
+	_, err := r.coordinateHPA(context.Background(), wa, map[string]corev1.ResourceRequirements{"app": cpuRequests("250m")},
+		map[string]corev1.ResourceRequirements{"app": cpuRequests("500m")})
+	require.Error(t, err)
+	assert.Equal(t, []int32{80}, utilizations())
+	cacheThresholds(wa.Status.HPACoordination)
+	assert.Equal(t, []vwav1.HPATarget{{Metric: "cpu", Threshold: resource.MustParse("200m"), AverageUtilization: 80}}, wa.Status.HPACoordination.Targets)
+
+	// the next reconcile patches the HPA for the requests already applied
+	failPatch = false
+	applied := map[string]corev1.ResourceRequirements{"app": cpuRequests("500m")}
+	changed, err := r.coordinateHPA(context.Background(), wa, applied, nil)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []int32{40}, utilizations())
+	cacheThresholds(wa.Status.HPACoordination)
+	assert.Equal(t, []vwav1.HPATarget{{Metric: "cpu", Threshold: resource.MustParse("200m"), AverageUtilization: 40}}, wa.Status.HPACoordination.Targets)
+
+	// the coordinated HPA is left alone
+	changed, err = r.coordinateHPA(context.Background(), wa, applied, nil)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, []int32{40}, utilizations())
+}
+
+func TestHandleVWAChangeCoordinatesHPA(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference: vwav1.VPAReference{Name: "vpa1"},
+			HPAPolicy:    &vwav1.HPAPolicy{CoordinateTargets: true},
+		},
+	}
+	vpa := conflictTestVPA(vpav1.UpdateModeOff)
+	vpa.Status.Recommendation = &vpav1.RecommendedPodResources{ContainerRecommendations: []vpav1.RecommendedContainerResources{
+		{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+	}}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "web:1.0", Resources: cpuRequests("250m")},
+		}}}},
+	}
+	hpa := resourceMetricHPA("web-hpa", corev1.ResourceCPU)
+	hpa.Spec.Metrics[0].Resource.Target = utilizationTarget(80)
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment, hpa).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(100)}
+
+	now = now.Add(10 * time.Minute)
+	_, err := r.handleVWAChange(context.Background(), vwa)
+	require.NoError(t, err)
+
+	// the CPU recommendation is applied and the HPA keeps scaling at 200m per pod
+	assert.Nil(t, vwa.Status.EffectiveIgnore)
+	updated := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), updated))
+	assert.Equal(t, resource.MustParse("500m"), updated.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU])
+	storedHPA := &autoscalingv2.HorizontalPodAutoscaler{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(hpa), storedHPA))
+	assert.Equal(t, []int32{40}, metricUtilizations(storedHPA))
+
+	stored := &vwav1.VerticalWorkloadAutoscaler{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vwa), stored))
+	require.NotNil(t, stored.Status.HPACoordination)
+	cacheThresholds(stored.Status.HPACoordination)
+	assert.Equal(t, []vwav1.HPATarget{{Metric: "cpu", Threshold: resource.MustParse("200m"), AverageUtilization: 40}}, stored.Status.HPACoordination.Targets)
+}
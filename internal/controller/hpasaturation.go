@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// updateHPASaturation tracks the HPA scaling the target workload at its maxReplicas with its metrics over their
+// targets and allows the CPU increases once it has been saturated for spec.hpaPolicy.saturationScaleUp.after;
+// it returns the time left until then
+func (r *VerticalWorkloadAutoscalerReconciler) updateHPASaturation(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) (time.Duration, error) {
+	previous := wa.Status.HPASaturation
+	var saturation *vwav1.HPASaturation
+	var wait time.Duration
+	var after time.Duration
+
+	policy := r.effectiveSpec(wa).HPAPolicy
+	if policy != nil && policy.SaturationScaleUp != nil {
+		hpa, err := r.findHPAForVWA(ctx, wa)
+		if err != nil && !errors.IsNotFound(err) {
+			return 0, err
+		}
+		// the CPU recommendations aren't ignored for a coordinated HPA
+		if hpa != nil && !r.coordinatesHPA(wa, hpa) {
+			if metrics := saturatedMetrics(hpa); len(metrics) > 0 {
+				saturation = &vwav1.HPASaturation{HPA: hpa.Name, Metrics: metrics, Since: metav1.NewTime(timeNow())}
+				if previous != nil && previous.HPA == hpa.Name {
+					saturation.Since = previous.Since
+				}
+				after = vwav1.DefaultSaturationScaleUpAfter
+				if policy.SaturationScaleUp.After != nil {
+					after = policy.SaturationScaleUp.After.Duration
+				}
+				elapsed := timeNow().Sub(saturation.Since.Time)
+				saturation.ScaleUp = elapsed >= after
+				if !saturation.ScaleUp {
+					wait = after - elapsed
+				}
+			}
+		}
+	}
+
+	condition := findCondition(wa.Status.Conditions, ConditionTypeHPASaturated)
+	if saturation == nil && (condition == nil || condition.Status == metav1.ConditionFalse) {
+		return 0, nil
+	}
+	status, reason, message := metav1.ConditionFalse, ReasonHPANotSaturated, "HPA is below maxReplicas, CPU changes handed back to the HPA"
+	switch {
+	case saturation == nil && previous != nil:
+		message = fmt.Sprintf("HPA '%s' is below maxReplicas, CPU changes handed back to the HPA", previous.HPA)
+	case saturation != nil && saturation.ScaleUp:
+		status, reason = metav1.ConditionTrue, ReasonSaturationScaleUp
+		message = fmt.Sprintf("HPA '%s' is at maxReplicas with %s over target, applying CPU increases", saturation.HPA, strings.Join(saturation.Metrics, ", "))
+	case saturation != nil:
+		status, reason = metav1.ConditionTrue, ReasonHPAAtMaxReplicas
+		message = fmt.Sprintf("HPA '%s' is at maxReplicas with %s over target since %s, applying CPU increases after %s",
+			saturation.HPA, strings.Join(saturation.Metrics, ", "), saturation.Since.UTC().Format(time.RFC3339), after)
+	}
+	if equality.Semantic.DeepEqual(saturation, previous) && condition != nil && condition.Status == status &&
+		condition.Reason == reason && condition.Message == message {
+		return wait, nil
+	}
+
+	wa.Status.HPASaturation = saturation
+	scaleUp := saturation != nil && saturation.ScaleUp
+	if scaleUp != (previous != nil && previous.ScaleUp) {
+		log.FromContext(ctx).Info(message)
+		r.recordEvent(wa, "Normal", reason, message)
+	}
+	return wait, r.updateStatusCondition(ctx, wa, ConditionTypeHPASaturated, status, reason, message)
+}
+
+// saturatedScaleUp checks whether the VWA applies the CPU increases ignored for the saturated HPA
+func saturatedScaleUp(wa *vwav1.VerticalWorkloadAutoscaler, hpa *autoscalingv2.HorizontalPodAutoscaler) bool {
+	saturation := wa.Status.HPASaturation
+	return saturation != nil && saturation.ScaleUp && saturation.HPA == hpa.Name
+}
+
+// saturatedMetrics returns the metrics over their targets of the HPA running at its maxReplicas
+func saturatedMetrics(hpa *autoscalingv2.HorizontalPodAutoscaler) []string {
+	if hpa.Spec.MaxReplicas == 0 || hpa.Status.CurrentReplicas < hpa.Spec.MaxReplicas {
+		return nil
+	}
+	var metrics []string
+	for _, metric := range hpa.Spec.Metrics {
+		key, target := metricSpecTarget(metric)
+		if target == nil {
+			continue
+		}
+		for _, status := range hpa.Status.CurrentMetrics {
+			if statusKey, current := metricStatusValue(status); status.Type == metric.Type && statusKey == key &&
+				current != nil && overTarget(*target, *current) {
+				metrics = append(metrics, key)
+				break
+			}
+		}
+	}
+	return metrics
+}
+
+// metricSpecTarget returns the name of the HPA metric, prefixed with the container name for ContainerResource
+// metrics, and its target
+func metricSpecTarget(metric autoscalingv2.MetricSpec) (string, *autoscalingv2.MetricTarget) {
+	switch {
+	case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil:
+		return string(metric.Resource.Name), &metric.Resource.Target
+	case metric.Type == autoscalingv2.ContainerResourceMetricSourceType && metric.ContainerResource != nil:
+		return metric.ContainerResource.Container + "/" + string(metric.ContainerResource.Name), &metric.ContainerResource.Target
+	case metric.Type == autoscalingv2.PodsMetricSourceType && metric.Pods != nil:
+		return metric.Pods.Metric.Name, &metric.Pods.Target
+	case metric.Type == autoscalingv2.ObjectMetricSourceType && metric.Object != nil:
+		return metric.Object.Metric.Name, &metric.Object.Target
+	case metric.Type == autoscalingv2.ExternalMetricSourceType && metric.External != nil:
+		return metric.External.Metric.Name, &metric.External.Target
+	}
+	return "", nil
+}
+
+// metricStatusValue returns the name of the HPA metric status, like metricSpecTarget, and its current value
+func metricStatusValue(status autoscalingv2.MetricStatus) (string, *autoscalingv2.MetricValueStatus) {
+	switch {
+	case status.Type == autoscalingv2.ResourceMetricSourceType && status.Resource != nil:
+		return string(status.Resource.Name), &status.Resource.Current
+	case status.Type == autoscalingv2.ContainerResourceMetricSourceType && status.ContainerResource != nil:
+		return status.ContainerResource.Container + "/" + string(status.ContainerResource.Name), &status.ContainerResource.Current
+	case status.Type == autoscalingv2.PodsMetricSourceType && status.Pods != nil:
+		return status.Pods.Metric.Name, &status.Pods.Current
+	case status.Type == autoscalingv2.ObjectMetricSourceType && status.Object != nil:
+		return status.Object.Metric.Name, &status.Object.Current
+	case status.Type == autoscalingv2.ExternalMetricSourceType && status.External != nil:
+		return status.External.Metric.Name, &status.External.Current
+	}
+	return "", nil
+}
+
+// overTarget checks whether the current value of the metric is over its target
+func overTarget(target autoscalingv2.MetricTarget, current autoscalingv2.MetricValueStatus) bool {
+	switch target.Type {
+	case autoscalingv2.UtilizationMetricType:
+		return target.AverageUtilization != nil && current.AverageUtilization != nil && *current.AverageUtilization > *target.AverageUtilization
+	case autoscalingv2.AverageValueMetricType:
+		return target.AverageValue != nil && current.AverageValue != nil && current.AverageValue.Cmp(*target.AverageValue) > 0
+	case autoscalingv2.ValueMetricType:
+		return target.Value != nil && current.Value != nil && current.Value.Cmp(*target.Value) > 0
+	}
+	return false
+}
+
+// limitSaturationScaleUp keeps the current CPU of the containers instead of decreasing it and caps the increased
+// CPU at maxCPU, so the saturated HPA only gets raised requests
+func limitSaturationScaleUp(currentResources, newResources map[string]corev1.ResourceRequirements, maxCPU *resource.Quantity) map[string]corev1.ResourceRequirements {
+	limited := make(map[string]corev1.ResourceRequirements, len(newResources))
+	for name, requirements := range newResources {
+		current := currentResources[name]
+		requirements.Requests = limitCPU(current.Requests, requirements.Requests, maxCPU)
+		requirements.Limits = limitCPU(current.Limits, requirements.Limits, maxCPU)
+		limited[name] = requirements
+	}
+	return limited
+}
+
+// limitCPU returns the new resources with the CPU capped at maxCPU, but not below the current CPU
+func limitCPU(current, updated corev1.ResourceList, maxCPU *resource.Quantity) corev1.ResourceList {
+	cpu, ok := updated[corev1.ResourceCPU]
+	if !ok {
+		return updated
+	}
+	currentCPU, hasCurrent := current[corev1.ResourceCPU]
+	if maxCPU != nil && cpu.Cmp(*maxCPU) > 0 {
+		cpu = maxCPU.DeepCopy()
+	}
+	if hasCurrent && cpu.Cmp(currentCPU) < 0 {
+		cpu = currentCPU.DeepCopy()
+	}
+	updated = updated.DeepCopy()
+	updated[corev1.ResourceCPU] = cpu
+	return updated
+}
@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// saturatedHPA returns the web-hpa scaling on the CPU utilization with an 80% target, running the replicas of
+// at most 10 at the utilization
+func saturatedHPA(replicas, utilization int32) *autoscalingv2.HorizontalPodAutoscaler {
+	hpa := resourceMetricHPA("web-hpa", corev1.ResourceCPU)
+	hpa.Spec.Metrics[0].Resource.Target = utilizationTarget(80)
+	hpa.Spec.MaxReplicas = 10
+	hpa.Status = autoscalingv2.HorizontalPodAutoscalerStatus{
+		CurrentReplicas: replicas,
+		CurrentMetrics: []autoscalingv2.MetricStatus{{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricStatus{
+				Name:    corev1.ResourceCPU,
+				Current: autoscalingv2.MetricValueStatus{AverageUtilization: ptr.To(utilization)},
+			},
+		}},
+	}
+	return hpa
+}
+
+func TestSaturatedMetrics(t *testing.T) {
+	rpsHPA := metricHPA(autoscalingv2.MetricSpec{
+		Type: autoscalingv2.PodsMetricSourceType,
+		Pods: &autoscalingv2.PodsMetricSource{
+			Metric: autoscalingv2.MetricIdentifier{Name: "requests_per_second"},
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: ptr.To(resource.MustParse("100"))},
+		},
+	})
+	rpsHPA.Spec.MaxReplicas = 5
+	rpsHPA.Status = autoscalingv2.HorizontalPodAutoscalerStatus{
+		CurrentReplicas: 5,
+		CurrentMetrics: []autoscalingv2.MetricStatus{{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricStatus{
+				Metric:  autoscalingv2.MetricIdentifier{Name: "requests_per_second"},
+				Current: autoscalingv2.MetricValueStatus{AverageValue: ptr.To(resource.MustParse("150"))},
+			},
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		hpa      *autoscalingv2.HorizontalPodAutoscaler
+		expected []string
+	}{
+		{
+			name:     "At maxReplicas over the CPU target",
+			hpa:      saturatedHPA(10, 95),
+			expected: []string{"cpu"},
+		},
+		{
+			name: "At maxReplicas under the CPU target",
+			hpa:  saturatedHPA(10, 70),
+		},
+		{
+			name: "Below maxReplicas",
+			hpa:  saturatedHPA(8, 95),
+		},
+		{
+			name:     "At maxReplicas over a Pods metric target",
+			hpa:      rpsHPA,
+			expected: []string{"requests_per_second"},
+		},
+		{
+			name: "No metrics reported",
+			hpa:  metricHPA(podsMetric),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, saturatedMetrics(tt.hpa))
+		})
+	}
+}
+
+func TestLimitSaturationScaleUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  corev1.ResourceRequirements
+		new      corev1.ResourceRequirements
+		maxCPU   *resource.Quantity
+		expected corev1.ResourceRequirements
+	}{
+		{
+			name:     "CPU increase",
+			current:  cpuRequests("500m"),
+			new:      cpuRequests("800m"),
+			expected: cpuRequests("800m"),
+		},
+		{
+			name:     "CPU increase capped",
+			current:  cpuRequests("500m"),
+			new:      cpuRequests("1500m"),
+			maxCPU:   ptr.To(resource.MustParse("1")),
+			expected: cpuRequests("1"),
+		},
+		{
+			name:     "CPU decrease held",
+			current:  cpuRequests("500m"),
+			new:      cpuRequests("300m"),
+			expected: cpuRequests("500m"),
+		},
+		{
+			name:     "Current CPU over the cap kept",
+			current:  cpuRequests("1500m"),
+			new:      cpuRequests("2"),
+			maxCPU:   ptr.To(resource.MustParse("1")),
+			expected: cpuRequests("1500m"),
+		},
+		{
+			name: "CPU limit raised with the request and memory decreased",
+			current: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("256Mi")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+			},
+			new: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			},
+			maxCPU: ptr.To(resource.MustParse("1")),
+			expected: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limited := limitSaturationScaleUp(
+				map[string]corev1.ResourceRequirements{"app": tt.current},
+				map[string]corev1.ResourceRequirements{"app": tt.new},
+				tt.maxCPU,
+			)
+			assert.True(t, resourceRequirementsEqual(tt.expected, limited["app"]), "expected %v, got %v", tt.expected, limited["app"])
+		})
+	}
+}
+
+func TestUpdateHPASaturation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	start := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	now := start
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	wa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{HPAPolicy: &vwav1.HPAPolicy{
+			SaturationScaleUp: &vwav1.SaturationScaleUp{After: &metav1.Duration{Duration: 10 * time.Minute}},
+		}},
+		Status: vwav1.VerticalWorkloadAutoscalerStatus{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+	}
+	hpa := saturatedHPA(10, 95)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(wa).WithObjects(wa, hpa).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+	assertCondition := func(status metav1.ConditionStatus, reason string) {
+		condition := findCondition(wa.Status.Conditions, ConditionTypeHPASaturated)
+		require.NotNil(t, condition)
+		assert.Equal(t, status, condition.Status)
+		assert.Equal(t, reason, condition.Reason)
+	}
+
+	// the saturated HPA is tracked until the CPU increases are allowed
+	wait, err := r.updateHPASaturation(context.Background(), wa)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, wait)
+	require.NotNil(t, wa.Status.HPASaturation)
+	assert.Equal(t, "web-hpa", wa.Status.HPASaturation.HPA)
+	assert.Equal(t, []string{"cpu"}, wa.Status.HPASaturation.Metrics)
+	assert.True(t, wa.Status.HPASaturation.Since.Equal(&metav1.Time{Time: start}))
+	assertCondition(metav1.ConditionTrue, ReasonHPAAtMaxReplicas)
+
+	now = start.Add(4 * time.Minute)
+	wait, err = r.updateHPASaturation(context.Background(), wa)
+	require.NoError(t, err)
+	assert.Equal(t, 6*time.Minute, wait)
+	assert.False(t, wa.Status.HPASaturation.ScaleUp)
+	assert.Empty(t, recorder.Events)
+
+	// the CPU increases are applied once the HPA has been saturated long enough
+	now = start.Add(10 * time.Minute)
+	wait, err = r.updateHPASaturation(context.Background(), wa)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+	assert.True(t, wa.Status.HPASaturation.ScaleUp)
+	assert.True(t, wa.Status.HPASaturation.Since.Equal(&metav1.Time{Time: start}))
+	assertCondition(metav1.ConditionTrue, ReasonSaturationScaleUp)
+	assert.Len(t, recorder.Events, 1)
+
+	// the CPU changes are handed back to the HPA below its maxReplicas
+	hpa.Status.CurrentReplicas = 7
+	require.NoError(t, c.Update(context.Background(), hpa))
+	_, err = r.updateHPASaturation(context.Background(), wa)
+	require.NoError(t, err)
+	assert.Nil(t, wa.Status.HPASaturation)
+	assertCondition(metav1.ConditionFalse, ReasonHPANotSaturated)
+	assert.Len(t, recorder.Events, 2)
+
+	stored := &vwav1.VerticalWorkloadAutoscaler{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(wa), stored))
+	assert.Nil(t, stored.Status.HPASaturation)
+}
+
+func TestHandleVWAChangeWithSaturatedHPA(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference: vwav1.VPAReference{Name: "vpa1"},
+			HPAPolicy: &vwav1.HPAPolicy{SaturationScaleUp: &vwav1.SaturationScaleUp{
+				After:  &metav1.Duration{Duration: 5 * time.Minute},
+				MaxCPU: ptr.To(resource.MustParse("400m")),
+			}},
+		},
+		Status: vwav1.VerticalWorkloadAutoscalerStatus{
+			// the HPA has been saturated long enough
+			HPASaturation: &vwav1.HPASaturation{HPA: "web-hpa", Metrics: []string{"cpu"}, Since: metav1.NewTime(now.Add(-time.Hour))},
+		},
+	}
+	vpa := conflictTestVPA(vpav1.UpdateModeOff)
+	vpa.Status.Recommendation = &vpav1.RecommendedPodResources{ContainerRecommendations: []vpav1.RecommendedContainerResources{
+		{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+	}}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "web:1.0", Resources: cpuRequests("250m")},
+		}}}},
+	}
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment, saturatedHPA(10, 95)).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(100)}
+
+	_, err := r.handleVWAChange(context.Background(), vwa)
+	require.NoError(t, err)
+
+	// the CPU recommendation ignored for the HPA is applied up to the cap
+	assert.Nil(t, vwa.Status.EffectiveIgnore)
+	assert.True(t, vwa.Status.HPASaturation.ScaleUp)
+	updated := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), updated))
+	assert.Equal(t, resource.MustParse("400m"), updated.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU])
+	condition := findCondition(vwa.Status.Conditions, ConditionTypeHPASaturated)
+	require.NotNil(t, condition)
+	assert.Equal(t, ReasonSaturationScaleUp, condition.Reason)
+}
+
+func TestEffectiveIgnoreWithSaturatedKEDAHPA(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	addKEDAToScheme(scheme)
+	// KEDA scales the workload through the HPA it creates for the ScaledObject
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		saturatedHPA(10, 95),
+		kedaObject(scaledObjectGVK, "web-keda", "Deployment", "cpu", "cpu:app", "memory"),
+	).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Status: vwav1.VerticalWorkloadAutoscalerStatus{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+	}
+
+	ignore, err := r.effectiveIgnore(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.Equal(t, &vwav1.EffectiveIgnore{
+		CPU:        true,
+		Memory:     true,
+		Containers: []vwav1.ContainerIgnore{{Name: "app", CPU: true}},
+		Source:     "HorizontalPodAutoscaler/web-hpa,ScaledObject/web-keda",
+	}, ignore)
+
+	// the CPU increases are applied while the HPA is saturated
+	vwa.Status.HPASaturation = &vwav1.HPASaturation{HPA: "web-hpa", Metrics: []string{"cpu"}, ScaleUp: true}
+	ignore, err = r.effectiveIgnore(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.Equal(t, &vwav1.EffectiveIgnore{Memory: true, Source: "ScaledObject/web-keda"}, ignore)
+}
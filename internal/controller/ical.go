@@ -0,0 +1,538 @@
+package controller
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecurrences limits the number of recurrence periods expanded for a single recurring event
+const maxRecurrences = 100000
+
+// icalEvent is a VEVENT parsed from an iCalendar file
+type icalEvent struct {
+	summary string
+	start   time.Time
+	end     time.Time
+	rule    *icalRecurrence
+}
+
+// icalRecurrence is a subset of the RRULE property: FREQ, INTERVAL, COUNT, UNTIL, BYMONTH, BYMONTHDAY, BYDAY
+// and WKST
+type icalRecurrence struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byMonth    []int
+	byMonthDay []int
+	byDay      []icalWeekday
+	weekStart  time.Weekday
+}
+
+// icalWeekday is a BYDAY rule part value, like MO or 4TH; n is the occurrence of the day within the month or
+// the year, counted from the end if negative, or 0 for every such day
+type icalWeekday struct {
+	n   int
+	day time.Weekday
+}
+
+var icalWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseICalendar parses VEVENT components from iCalendar (RFC 5545) data.
+// Only DTSTART, DTEND, DURATION, SUMMARY and RRULE properties are interpreted; components nested in events,
+// like VALARM, are ignored. Events with unsupported or invalid properties are skipped and returned as messages.
+func parseICalendar(data string) ([]icalEvent, []string, error) {
+	var events []icalEvent
+	var skipped []string
+	var current *icalEvent
+	var nested []string
+	var invalid error
+	var duration time.Duration
+	var allDay bool
+
+	for _, line := range unfoldICalLines(data) {
+		name, params, value := splitICalProperty(line)
+		switch {
+		case current != nil && name == "BEGIN":
+			nested = append(nested, strings.ToUpper(value))
+		case len(nested) > 0:
+			if name == "END" && strings.EqualFold(value, nested[len(nested)-1]) {
+				nested = nested[:len(nested)-1]
+			}
+		case name == "BEGIN" && value == "VEVENT":
+			current, invalid, duration, allDay = &icalEvent{}, nil, 0, false
+		case name == "END" && value == "VEVENT":
+			if current == nil {
+				return nil, nil, fmt.Errorf("unexpected END:VEVENT")
+			}
+			if invalid == nil && current.start.IsZero() {
+				invalid = fmt.Errorf("no DTSTART")
+			}
+			if invalid != nil {
+				skipped = append(skipped, fmt.Sprintf("%q: %v", current.summary, invalid))
+				current = nil
+				continue
+			}
+			if current.end.IsZero() {
+				switch {
+				case duration > 0:
+					current.end = current.start.Add(duration)
+				case allDay:
+					current.end = current.start.AddDate(0, 0, 1)
+				default:
+					current.end = current.start
+				}
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			continue
+		case name == "SUMMARY":
+			current.summary = unescapeICalText(value)
+		case invalid != nil:
+			continue
+		case name == "DTSTART":
+			t, date, err := parseICalTime(value, params)
+			if err != nil {
+				invalid = fmt.Errorf("invalid DTSTART %q: %w", value, err)
+				continue
+			}
+			current.start, allDay = t, date
+		case name == "DTEND":
+			t, _, err := parseICalTime(value, params)
+			if err != nil {
+				invalid = fmt.Errorf("invalid DTEND %q: %w", value, err)
+				continue
+			}
+			current.end = t
+		case name == "DURATION":
+			d, err := parseICalDuration(value)
+			if err != nil {
+				invalid = fmt.Errorf("invalid DURATION %q: %w", value, err)
+				continue
+			}
+			duration = d
+		case name == "RRULE":
+			rule, err := parseICalRecurrence(value)
+			if err != nil {
+				invalid = fmt.Errorf("invalid RRULE %q: %w", value, err)
+				continue
+			}
+			current.rule = rule
+		}
+	}
+	if current != nil {
+		return nil, nil, fmt.Errorf("unterminated VEVENT %q", current.summary)
+	}
+	return events, skipped, nil
+}
+
+// occurrenceAt returns the start and end of the event occurrence that contains now, if any
+func (e icalEvent) occurrenceAt(now time.Time) (time.Time, time.Time, bool) {
+	length := e.end.Sub(e.start)
+	if e.rule == nil {
+		return e.start, e.end, !now.Before(e.start) && now.Before(e.end)
+	}
+
+	var start time.Time
+	e.rule.each(e.start, now, func(next time.Time) bool {
+		if next.After(now) {
+			return false
+		}
+		start = next
+		return true
+	})
+	if start.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+	end := start.Add(length)
+	return start, end, now.Before(end)
+}
+
+// each calls yield with the occurrence starts of a recurring event in order, beginning with DTSTART, until
+// yield returns false, the COUNT or UNTIL of the rule is reached or the recurrence periods pass the limit
+func (r icalRecurrence) each(dtstart, limit time.Time, yield func(time.Time) bool) {
+	n := 0
+	emit := func(t time.Time) bool {
+		if (r.count > 0 && n >= r.count) || (!r.until.IsZero() && t.After(r.until)) {
+			return false
+		}
+		n++
+		return yield(t)
+	}
+	if !emit(dtstart) {
+		return
+	}
+
+	for period := 0; period < maxRecurrences; period++ {
+		first, days := r.period(dtstart, period)
+		if first.AddDate(0, 0, -1).After(limit) {
+			return
+		}
+		for i := 0; i < days; i++ {
+			// the dates are calculated at noon UTC, so daylight saving time changes don't shift the day
+			day := first.AddDate(0, 0, i)
+			t := time.Date(day.Year(), day.Month(), day.Day(), dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+			if !t.After(dtstart) || !r.matches(dtstart, day) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the first day, at noon UTC, and the number of days of the n-th recurrence period of the rule
+func (r icalRecurrence) period(dtstart time.Time, n int) (time.Time, int) {
+	step := n * r.interval
+	switch r.freq {
+	case "YEARLY":
+		first := time.Date(dtstart.Year()+step, time.January, 1, 12, 0, 0, 0, time.UTC)
+		return first, int(first.AddDate(1, 0, 0).Sub(first).Hours() / 24)
+	case "MONTHLY":
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1, 12, 0, 0, 0, time.UTC)
+		return first, daysIn(first)
+	case "WEEKLY":
+		offset := (int(dtstart.Weekday()) - int(r.weekStart) + 7) % 7
+		return time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+7*step, 12, 0, 0, 0, time.UTC), 7
+	default:
+		return time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day()+step, 12, 0, 0, 0, time.UTC), 1
+	}
+}
+
+// matches checks whether the day is an occurrence of the rule; without BYMONTHDAY and BYDAY, occurrences
+// repeat the day of DTSTART within the period, so a day that doesn't exist in a month is skipped
+func (r icalRecurrence) matches(dtstart, day time.Time) bool {
+	if len(r.byMonth) > 0 && !slices.Contains(r.byMonth, int(day.Month())) {
+		return false
+	}
+	if len(r.byMonthDay) > 0 && !slices.ContainsFunc(r.byMonthDay, func(d int) bool { return monthDay(day, d) }) {
+		return false
+	}
+	if len(r.byDay) > 0 && !slices.ContainsFunc(r.byDay, func(wd icalWeekday) bool { return r.weekday(day, wd) }) {
+		return false
+	}
+
+	byDate := len(r.byMonthDay) > 0 || len(r.byDay) > 0
+	switch r.freq {
+	case "YEARLY":
+		if len(r.byMonth) == 0 && !byDate && day.Month() != dtstart.Month() {
+			return false
+		}
+		return byDate || day.Day() == dtstart.Day()
+	case "MONTHLY":
+		return byDate || day.Day() == dtstart.Day()
+	case "WEEKLY":
+		return len(r.byDay) > 0 || day.Weekday() == dtstart.Weekday()
+	}
+	return true
+}
+
+// weekday checks whether the day is the BYDAY weekday; its occurrence is counted within the month for monthly
+// rules and yearly rules with BYMONTH, and within the year otherwise
+func (r icalRecurrence) weekday(day time.Time, wd icalWeekday) bool {
+	if day.Weekday() != wd.day {
+		return false
+	}
+	if wd.n == 0 {
+		return true
+	}
+	index, days := day.Day(), daysIn(day)
+	if r.freq == "YEARLY" && len(r.byMonth) == 0 {
+		index, days = day.YearDay(), time.Date(day.Year(), time.December, 31, 12, 0, 0, 0, time.UTC).YearDay()
+	}
+	if wd.n > 0 {
+		return (index-1)/7+1 == wd.n
+	}
+	return (days-index)/7+1 == -wd.n
+}
+
+// monthDay checks whether the day is the BYMONTHDAY value, counted from the end of the month if negative
+func monthDay(day time.Time, d int) bool {
+	if d < 0 {
+		return day.Day() == daysIn(day)+1+d
+	}
+	return day.Day() == d
+}
+
+// daysIn returns the number of days in the month of t
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 12, 0, 0, 0, time.UTC).Day()
+}
+
+// unfoldICalLines splits iCalendar data into logical lines, joining folded continuation lines
+func unfoldICalLines(data string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// splitICalProperty splits a content line into its name, parameters and value
+func splitICalProperty(line string) (string, map[string]string, string) {
+	inQuotes := false
+	sep := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+
+	parts := strings.Split(line[:sep], ";")
+	params := make(map[string]string)
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[sep+1:]
+}
+
+// parseICalTime parses a DATE or DATE-TIME value; it reports whether the value is a date
+func parseICalTime(value string, params map[string]string) (time.Time, bool, error) {
+	loc := time.UTC
+	if tzid, ok := params["TZID"]; ok {
+		l, err := loadICalLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		loc = l
+	}
+
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// windowsTimeZones maps the Windows time zone names used by Outlook and Exchange exports to IANA time zones
+var windowsTimeZones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time":          "America/Denver",
+	"Central Standard Time":           "America/Chicago",
+	"Central America Standard Time":   "America/Guatemala",
+	"Canada Central Standard Time":    "America/Regina",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Eastern Standard Time":           "America/New_York",
+	"US Eastern Standard Time":        "America/Indiana/Indianapolis",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Atlantic Standard Time":          "America/Halifax",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Argentina/Buenos_Aires",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"UTC":                             "Etc/UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"FLE Standard Time":               "Europe/Kiev",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Russian Standard Time":           "Europe/Moscow",
+	"Arab Standard Time":              "Asia/Riyadh",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Iran Standard Time":              "Asia/Tehran",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"India Standard Time":             "Asia/Kolkata",
+	"Nepal Standard Time":             "Asia/Kathmandu",
+	"Bangladesh Standard Time":        "Asia/Dhaka",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"AUS Central Standard Time":       "Australia/Darwin",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+}
+
+// loadICalLocation returns the location of a TZID parameter, an IANA or a Windows time zone name
+func loadICalLocation(tzid string) (*time.Location, error) {
+	if name, ok := windowsTimeZones[tzid]; ok {
+		tzid = name
+	}
+	loc, err := time.LoadLocation(tzid)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", tzid)
+	}
+	return loc, nil
+}
+
+var icalDurationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICalDuration parses a DURATION value like "P1D", "PT4H" or "P1DT12H"
+func parseICalDuration(value string) (time.Duration, error) {
+	m := icalDurationPattern.FindStringSubmatch(value)
+	if m == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("unsupported duration format")
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		return 0, fmt.Errorf("negative durations are not supported")
+	}
+	return d, nil
+}
+
+var icalWeekdayPattern = regexp.MustCompile(`^([+-]?\d{1,2})?(SU|MO|TU|WE|TH|FR|SA)$`)
+
+// parseICalRecurrence parses an RRULE value; BYSETPOS, BYWEEKNO, BYYEARDAY and the time of day rule parts
+// are not supported
+func parseICalRecurrence(value string) (*icalRecurrence, error) {
+	rule := &icalRecurrence{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed rule part %q", part)
+		}
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			switch v {
+			case "YEARLY", "MONTHLY", "WEEKLY", "DAILY":
+				rule.freq = v
+			default:
+				return nil, fmt.Errorf("unsupported frequency %q", v)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid interval %q", v)
+			}
+			rule.interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid count %q", v)
+			}
+			rule.count = n
+		case "UNTIL":
+			t, _, err := parseICalTime(v, nil)
+			if err != nil {
+				return nil, fmt.Errorf("invalid until %q: %w", v, err)
+			}
+			rule.until = t
+		case "BYMONTH":
+			months, err := parseICalNumbers(v, 1, 12, false)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BYMONTH %q: %w", v, err)
+			}
+			rule.byMonth = months
+		case "BYMONTHDAY":
+			days, err := parseICalNumbers(v, 1, 31, true)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BYMONTHDAY %q: %w", v, err)
+			}
+			rule.byMonthDay = days
+		case "BYDAY":
+			for _, d := range strings.Split(v, ",") {
+				m := icalWeekdayPattern.FindStringSubmatch(d)
+				if m == nil {
+					return nil, fmt.Errorf("invalid BYDAY %q", v)
+				}
+				wd := icalWeekday{day: icalWeekdays[m[2]]}
+				if m[1] != "" {
+					n, err := strconv.Atoi(m[1])
+					if err != nil || n == 0 || n > 53 || n < -53 {
+						return nil, fmt.Errorf("invalid BYDAY %q", v)
+					}
+					wd.n = n
+				}
+				rule.byDay = append(rule.byDay, wd)
+			}
+		case "WKST":
+			day, ok := icalWeekdays[v]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", v)
+			}
+			rule.weekStart = day
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", k)
+		}
+	}
+	if rule.freq == "" {
+		return nil, fmt.Errorf("missing FREQ")
+	}
+	if rule.freq != "YEARLY" && rule.freq != "MONTHLY" && slices.ContainsFunc(rule.byDay, func(wd icalWeekday) bool { return wd.n != 0 }) {
+		return nil, fmt.Errorf("numbered BYDAY values require a MONTHLY or YEARLY frequency")
+	}
+	return rule, nil
+}
+
+// parseICalNumbers parses a comma separated list of numbers between lowest and highest, also negated if negative
+// numbers are allowed
+func parseICalNumbers(value string, lowest, highest int, negative bool) ([]int, error) {
+	var numbers []int
+	for _, v := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		if abs := max(n, -n); abs < lowest || abs > highest || (n < 0 && !negative) {
+			return nil, fmt.Errorf("%d is out of range", n)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// unescapeICalText unescapes a TEXT value
+func unescapeICalText(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
@@ -0,0 +1,402 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseICalendar(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	tests := []struct {
+		name            string
+		data            string
+		expected        []icalEvent
+		expectedSkipped []string
+		expectError     bool
+	}{
+		{
+			name: "UTC date-time event",
+			data: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nSUMMARY:Black Friday\r\nDTSTART:20241129T000000Z\r\nDTEND:20241202T000000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			expected: []icalEvent{
+				{
+					summary: "Black Friday",
+					start:   time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC),
+					end:     time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "All-day event without DTEND",
+			data: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Christmas\nDTSTART;VALUE=DATE:20241225\nEND:VEVENT\nEND:VCALENDAR\n",
+			expected: []icalEvent{
+				{
+					summary: "Christmas",
+					start:   time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC),
+					end:     time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "Event with TZID, DURATION and folded summary",
+			data: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Quarter-end\n  close\nDTSTART;TZID=America/New_York:20240930T180000\nDURATION:P1DT6H\nEND:VEVENT\nEND:VCALENDAR\n",
+			expected: []icalEvent{
+				{
+					summary: "Quarter-end close",
+					start:   time.Date(2024, 9, 30, 18, 0, 0, 0, newYork),
+					end:     time.Date(2024, 10, 2, 0, 0, 0, 0, newYork),
+				},
+			},
+		},
+		{
+			name: "Recurring event",
+			data: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Release freeze\nDTSTART:20240101T000000Z\nDTEND:20240102T000000Z\nRRULE:FREQ=MONTHLY;INTERVAL=3;COUNT=4\nEND:VEVENT\nEND:VCALENDAR\n",
+			expected: []icalEvent{
+				{
+					summary: "Release freeze",
+					start:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					end:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+					rule:    &icalRecurrence{freq: "MONTHLY", interval: 3, count: 4, weekStart: time.Monday},
+				},
+			},
+		},
+		{
+			name: "Recurrence rule with BYMONTH and BYDAY",
+			data: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Thanksgiving\nDTSTART;VALUE=DATE:20241128\nRRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH\nEND:VEVENT\nEND:VCALENDAR\n",
+			expected: []icalEvent{
+				{
+					summary: "Thanksgiving",
+					start:   time.Date(2024, 11, 28, 0, 0, 0, 0, time.UTC),
+					end:     time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC),
+					rule: &icalRecurrence{freq: "YEARLY", interval: 1, weekStart: time.Monday, byMonth: []int{11},
+						byDay: []icalWeekday{{n: 4, day: time.Thursday}}},
+				},
+			},
+		},
+		{
+			name: "Alarm properties ignored",
+			data: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Black Friday\nDTSTART:20241129T000000Z\nDURATION:P3D\nBEGIN:VALARM\nSUMMARY:Reminder\nDURATION:PT5M\nEND:VALARM\nEND:VEVENT\nEND:VCALENDAR\n",
+			expected: []icalEvent{
+				{
+					summary: "Black Friday",
+					start:   time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC),
+					end:     time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "Windows time zone",
+			data: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Quarter-end close\nDTSTART;TZID=\"Eastern Standard Time\":20240930T180000\nDTEND;TZID=\"Eastern Standard Time\":20241001T060000\nEND:VEVENT\nEND:VCALENDAR\n",
+			expected: []icalEvent{
+				{
+					summary: "Quarter-end close",
+					start:   time.Date(2024, 9, 30, 18, 0, 0, 0, newYork),
+					end:     time.Date(2024, 10, 1, 6, 0, 0, 0, newYork),
+				},
+			},
+		},
+		{
+			name:            "Unsupported events skipped",
+			data:            "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Last workday\nDTSTART;VALUE=DATE:20241031\nRRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1\nEND:VEVENT\nBEGIN:VEVENT\nSUMMARY:Offsite\nDTSTART;TZID=Mars/Olympus_Mons:20241101T090000\nEND:VEVENT\nBEGIN:VEVENT\nSUMMARY:Broken\nEND:VEVENT\nEND:VCALENDAR\n",
+			expectedSkipped: []string{`"Last workday": invalid RRULE`, `"Offsite": invalid DTSTART`, `"Broken": no DTSTART`},
+		},
+		{
+			name:        "Unterminated event",
+			data:        "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Broken\nDTSTART:20241129T000000Z\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, skipped, err := parseICalendar(tt.data)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, skipped, len(tt.expectedSkipped))
+			for i := range tt.expectedSkipped {
+				assert.Contains(t, skipped[i], tt.expectedSkipped[i])
+			}
+			assert.Equal(t, len(tt.expected), len(events))
+			for i := range tt.expected {
+				assert.Equal(t, tt.expected[i].summary, events[i].summary)
+				assert.True(t, tt.expected[i].start.Equal(events[i].start), "start %s != %s", tt.expected[i].start, events[i].start)
+				assert.True(t, tt.expected[i].end.Equal(events[i].end), "end %s != %s", tt.expected[i].end, events[i].end)
+				assert.Equal(t, tt.expected[i].rule, events[i].rule)
+			}
+		})
+	}
+}
+
+func TestICalEventOccurrenceAt(t *testing.T) {
+	freeze := icalEvent{
+		summary: "Release freeze",
+		start:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		end:     time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+	}
+	quarterly := freeze
+	quarterly.rule = &icalRecurrence{freq: "MONTHLY", interval: 3, count: 4, weekStart: time.Monday}
+
+	tests := []struct {
+		name          string
+		event         icalEvent
+		now           time.Time
+		expectedStart time.Time
+		expectedOK    bool
+	}{
+		{
+			name:          "Within single event",
+			event:         freeze,
+			now:           time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+			expectedStart: freeze.start,
+			expectedOK:    true,
+		},
+		{
+			name:       "After single event",
+			event:      freeze,
+			now:        time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			expectedOK: false,
+		},
+		{
+			name:          "Within third occurrence",
+			event:         quarterly,
+			now:           time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC),
+			expectedStart: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+			expectedOK:    true,
+		},
+		{
+			name:       "Between occurrences",
+			event:      quarterly,
+			now:        time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			expectedOK: false,
+		},
+		{
+			name:       "After last counted occurrence",
+			event:      quarterly,
+			now:        time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+			expectedOK: false,
+		},
+		{
+			name:       "Before first occurrence",
+			event:      quarterly,
+			now:        time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+			expectedOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, _, ok := tt.event.occurrenceAt(tt.now)
+			assert.Equal(t, tt.expectedOK, ok)
+			if tt.expectedOK {
+				assert.Equal(t, tt.expectedStart, start)
+			}
+		})
+	}
+}
+
+func TestICalRecurrenceOccurrences(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    time.Time
+		rule     string
+		expected []time.Time
+	}{
+		{
+			name:  "Fourth Thursday of November",
+			start: time.Date(2023, 11, 23, 0, 0, 0, 0, time.UTC),
+			rule:  "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			expected: []time.Time{
+				time.Date(2023, 11, 23, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 11, 28, 0, 0, 0, 0, time.UTC),
+				time.Date(2025, 11, 27, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "Last Monday of May",
+			start: time.Date(2024, 5, 27, 0, 0, 0, 0, time.UTC),
+			rule:  "FREQ=YEARLY;BYMONTH=5;BYDAY=-1MO",
+			expected: []time.Time{
+				time.Date(2024, 5, 27, 0, 0, 0, 0, time.UTC),
+				time.Date(2025, 5, 26, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, 5, 25, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "Days missing from a month are skipped",
+			start: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			rule:  "FREQ=MONTHLY",
+			expected: []time.Time{
+				time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "Last day of the month",
+			start: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			expected: []time.Time{
+				time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "Weekdays every other week",
+			start: time.Date(2024, 10, 10, 9, 0, 0, 0, time.UTC),
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			expected: []time.Time{
+				time.Date(2024, 10, 10, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, 10, 22, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, 10, 24, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "Daily within months",
+			start: time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC),
+			rule:  "FREQ=DAILY;BYMONTH=12,1",
+			expected: []time.Time{
+				time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "Count and until",
+			start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			rule:  "FREQ=DAILY;COUNT=5;UNTIL=20240102T000000Z",
+			expected: []time.Time{
+				time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseICalRecurrence(tt.rule)
+			assert.NoError(t, err)
+			var occurrences []time.Time
+			rule.each(tt.start, tt.start.AddDate(10, 0, 0), func(next time.Time) bool {
+				occurrences = append(occurrences, next)
+				return len(occurrences) < 3
+			})
+			assert.Equal(t, tt.expected, occurrences)
+		})
+	}
+}
+
+func TestParseICalRecurrenceErrors(t *testing.T) {
+	for _, rule := range []string{
+		"FREQ=HOURLY",
+		"FREQ=MONTHLY;BYSETPOS=-1;BYDAY=FR",
+		"FREQ=YEARLY;BYWEEKNO=20",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"BYMONTH=1",
+	} {
+		t.Run(rule, func(t *testing.T) {
+			_, err := parseICalRecurrence(rule)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseExportedCalendar(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "release-blackouts.ics"))
+	require.NoError(t, err)
+	pacific, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+
+	events, skipped, err := parseICalendar(string(data))
+	require.NoError(t, err)
+	require.Len(t, skipped, 1)
+	assert.Contains(t, skipped[0], `"Last workday": invalid RRULE`)
+	require.Len(t, events, 3)
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		expectedEvent string
+		expectedStart time.Time
+	}{
+		{
+			name:          "Thanksgiving weekend, not overwritten by the alarm",
+			now:           time.Date(2025, 11, 29, 12, 0, 0, 0, time.UTC),
+			expectedEvent: "Thanksgiving weekend",
+			expectedStart: time.Date(2025, 11, 27, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "Month-end close in the Windows time zone",
+			now:           time.Date(2024, 3, 1, 3, 0, 0, 0, time.UTC),
+			expectedEvent: "Month-end close",
+			expectedStart: time.Date(2024, 2, 29, 18, 0, 0, 0, pacific),
+		},
+		{
+			name:          "Billing run skips February",
+			now:           time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC),
+			expectedEvent: "Billing run",
+			expectedStart: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "No event",
+			now:  time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var active []string
+			for _, event := range events {
+				if start, end, ok := event.occurrenceAt(tt.now); ok {
+					active = append(active, event.summary)
+					assert.True(t, tt.expectedStart.Equal(start), "start %s != %s", tt.expectedStart, start)
+					assert.Equal(t, event.end.Sub(event.start), end.Sub(start))
+				}
+			}
+			if tt.expectedEvent == "" {
+				assert.Empty(t, active)
+				return
+			}
+			assert.Equal(t, []string{tt.expectedEvent}, active)
+		})
+	}
+}
+
+func TestParseICalDuration(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    time.Duration
+		expectError bool
+	}{
+		{value: "P1D", expected: 24 * time.Hour},
+		{value: "PT4H30M", expected: 4*time.Hour + 30*time.Minute},
+		{value: "P1W", expected: 7 * 24 * time.Hour},
+		{value: "P1DT12H", expected: 36 * time.Hour},
+		{value: "-P1D", expectError: true},
+		{value: "P", expectError: true},
+		{value: "1D", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			d, err := parseICalDuration(tt.value)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, d)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// The KEDA kinds are read as unstructured to avoid depending on KEDA
+var (
+	// scaledObjectGVK is the KEDA ScaledObject kind, scaling the replicas of a workload
+	scaledObjectGVK = schema.GroupVersionKind{Group: "keda.sh", Version: "v1alpha1", Kind: "ScaledObject"}
+	// scaledJobGVK is the KEDA ScaledJob kind, creating Jobs from its own template
+	scaledJobGVK = schema.GroupVersionKind{Group: "keda.sh", Version: "v1alpha1", Kind: "ScaledJob"}
+)
+
+// kedaScaler is a KEDA ScaledObject or ScaledJob scaling the target workload and its CPU and memory triggers
+type kedaScaler struct {
+	kind, name  string
+	cpu, memory bool
+	// containers lists the containers of the CPU and memory triggers with a containerName
+	containers []vwav1.ContainerIgnore
+}
+
+// ref returns the kind and name of the scaler
+func (s kedaScaler) ref() string {
+	return s.kind + "/" + s.name
+}
+
+// findKEDAScalers returns the KEDA ScaledObjects scaling the workload and the ScaledJob controlling the Job;
+// there are none when KEDA isn't installed
+func (r *VerticalWorkloadAutoscalerReconciler) findKEDAScalers(ctx context.Context, namespace, kind, name string) ([]kedaScaler, error) {
+	var scalers []kedaScaler
+
+	scaledObjects, err := r.listKEDA(ctx, scaledObjectGVK, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, scaledObject := range scaledObjects {
+		if targetKind, targetName := scaledObjectTarget(&scaledObject); targetKind == kind && targetName == name {
+			scalers = append(scalers, newKEDAScaler(&scaledObject))
+		}
+	}
+
+	if kind != "Job" {
+		return scalers, nil
+	}
+	scaledJob, err := r.jobScaledJob(ctx, namespace, name)
+	if err != nil || scaledJob == "" {
+		return scalers, err
+	}
+	scaledJobs, err := r.listKEDA(ctx, scaledJobGVK, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range scaledJobs {
+		if obj.GetName() == scaledJob {
+			scalers = append(scalers, newKEDAScaler(&obj))
+		}
+	}
+	return scalers, nil
+}
+
+// listKEDA lists the KEDA objects of the kind in the namespace; there are none when the kind isn't installed
+func (r *VerticalWorkloadAutoscalerReconciler) listKEDA(ctx context.Context, gvk schema.GroupVersionKind, namespace string) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			log.FromContext(ctx).V(1).Info("KEDA not installed, skipping", "kind", gvk.Kind)
+			return nil, nil
+		}
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// jobScaledJob returns the name of the ScaledJob controlling the Job, if any
+func (r *VerticalWorkloadAutoscalerReconciler) jobScaledJob(ctx context.Context, namespace, name string) (string, error) {
+	job := &batchv1.Job{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, job); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+	if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == scaledJobGVK.Kind {
+		return owner.Name, nil
+	}
+	return "", nil
+}
+
+// scaledObjectTarget returns the kind and name of the workload scaled by the ScaledObject
+func scaledObjectTarget(scaledObject *unstructured.Unstructured) (string, string) {
+	kind, _, _ := unstructured.NestedString(scaledObject.Object, "spec", "scaleTargetRef", "kind")
+	name, _, _ := unstructured.NestedString(scaledObject.Object, "spec", "scaleTargetRef", "name")
+	if kind == "" {
+		kind = "Deployment"
+	}
+	return kind, name
+}
+
+// newKEDAScaler returns the scaler of the ScaledObject or ScaledJob with its CPU and memory triggers
+func newKEDAScaler(obj *unstructured.Unstructured) kedaScaler {
+	scaler := kedaScaler{kind: obj.GetKind(), name: obj.GetName()}
+	triggers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "triggers")
+	for _, item := range triggers {
+		trigger, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		triggerType, _, _ := unstructured.NestedString(trigger, "type")
+		if triggerType != string(corev1.ResourceCPU) && triggerType != string(corev1.ResourceMemory) {
+			continue
+		}
+		container, _, _ := unstructured.NestedString(trigger, "metadata", "containerName")
+		if container == "" {
+			scaler.cpu = scaler.cpu || triggerType == string(corev1.ResourceCPU)
+			scaler.memory = scaler.memory || triggerType == string(corev1.ResourceMemory)
+			continue
+		}
+		scaler.containers = mergeContainerIgnores(scaler.containers, []vwav1.ContainerIgnore{{
+			Name:   container,
+			CPU:    triggerType == string(corev1.ResourceCPU),
+			Memory: triggerType == string(corev1.ResourceMemory),
+		}})
+	}
+	return scaler
+}
+
+// mergeContainerIgnores returns the ignores of both lists, merged per container and sorted by name
+func mergeContainerIgnores(ignores, other []vwav1.ContainerIgnore) []vwav1.ContainerIgnore {
+	for _, ignore := range other {
+		i := slices.IndexFunc(ignores, func(c vwav1.ContainerIgnore) bool { return c.Name == ignore.Name })
+		if i < 0 {
+			ignores = append(ignores, ignore)
+			continue
+		}
+		ignores[i].CPU = ignores[i].CPU || ignore.CPU
+		ignores[i].Memory = ignores[i].Memory || ignore.Memory
+	}
+	slices.SortFunc(ignores, func(a, b vwav1.ContainerIgnore) int { return strings.Compare(a.Name, b.Name) })
+	return ignores
+}
+
+// findVWAForKEDAScaler maps a ScaledObject to the VWAs of the workload it scales and a ScaledJob to the VWAs
+// of the Jobs it controls
+func (r *VerticalWorkloadAutoscalerReconciler) findVWAForKEDAScaler(ctx context.Context, obj client.Object) []reconcile.Request {
+	scaler, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	var vwaList vwav1.VerticalWorkloadAutoscalerList
+	if err := r.List(ctx, &vwaList, client.InNamespace(scaler.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, vwa := range vwaList.Items {
+		target := vwa.Status.ScaleTargetRef
+		var matches bool
+		switch scaler.GetKind() {
+		case scaledObjectGVK.Kind:
+			kind, name := scaledObjectTarget(scaler)
+			matches = target.Kind == kind && target.Name == name
+		case scaledJobGVK.Kind:
+			if target.Kind == "Job" {
+				scaledJob, err := r.jobScaledJob(ctx, vwa.Namespace, target.Name)
+				matches = err == nil && scaledJob == scaler.GetName()
+			}
+		}
+		if matches {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{Namespace: vwa.Namespace, Name: vwa.Name}})
+		}
+	}
+	return requests
+}
+
+// kedaInstalled checks whether the KEDA kind is served by the API server
+func kedaInstalled(mapper meta.RESTMapper, gvk schema.GroupVersionKind) (bool, error) {
+	if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) || errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
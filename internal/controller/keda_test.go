@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// scaledJobObjects returns the report-keda ScaledJob with the triggers and the Job it controls
+func scaledJobObjects(triggers ...string) []client.Object {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:            "report-x7k2p",
+		Namespace:       "default",
+		OwnerReferences: []metav1.OwnerReference{{Kind: scaledJobGVK.Kind, Name: "report-keda", Controller: ptr.To(true)}},
+	}}
+	return []client.Object{kedaObject(scaledJobGVK, "report-keda", "", triggers...), job}
+}
+
+func TestFindKEDAScalers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	kedaScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(kedaScheme)
+	addKEDAToScheme(kedaScheme)
+
+	tests := []struct {
+		name     string
+		scheme   *runtime.Scheme
+		objects  []client.Object
+		kind     string
+		target   string
+		expected []kedaScaler
+	}{
+		{
+			name:   "KEDA not installed",
+			scheme: scheme,
+			kind:   "Deployment",
+			target: "web",
+		},
+		{
+			name:   "ScaledObject with CPU and container memory triggers",
+			scheme: kedaScheme,
+			objects: []client.Object{
+				kedaObject(scaledObjectGVK, "web-keda", "", "cpu", "memory:app", "prometheus"),
+				kedaObject(scaledObjectGVK, "api-keda", "StatefulSet", "cpu"),
+			},
+			kind:   "Deployment",
+			target: "web",
+			expected: []kedaScaler{{
+				kind:       "ScaledObject",
+				name:       "web-keda",
+				cpu:        true,
+				containers: []vwav1.ContainerIgnore{{Name: "app", Memory: true}},
+			}},
+		},
+		{
+			name:     "ScaledJob controlling the Job",
+			scheme:   kedaScheme,
+			objects:  scaledJobObjects("memory"),
+			kind:     "Job",
+			target:   "report-x7k2p",
+			expected: []kedaScaler{{kind: "ScaledJob", name: "report-keda", memory: true}},
+		},
+		{
+			name:    "Job without a ScaledJob",
+			scheme:  kedaScheme,
+			objects: scaledJobObjects("memory"),
+			kind:    "Job",
+			target:  "backup",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(tt.scheme).WithObjects(tt.objects...).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+			scalers, err := r.findKEDAScalers(context.Background(), "default", tt.kind, tt.target)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, scalers)
+		})
+	}
+}
+
+func TestFindVWAForKEDAScaler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	addKEDAToScheme(scheme)
+
+	vwa := func(name, kind, target string) *vwav1.VerticalWorkloadAutoscaler {
+		return &vwav1.VerticalWorkloadAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status: vwav1.VerticalWorkloadAutoscalerStatus{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: kind, Name: target},
+			},
+		}
+	}
+	objects := append(scaledJobObjects(), vwa("web-vwa", "Deployment", "web"), vwa("report-vwa", "Job", "report-x7k2p"))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+	tests := []struct {
+		name     string
+		scaler   client.Object
+		expected []reconcile.Request
+	}{
+		{
+			name:     "ScaledObject",
+			scaler:   kedaObject(scaledObjectGVK, "web-keda", "Deployment"),
+			expected: []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: "default", Name: "web-vwa"}}},
+		},
+		{
+			name:     "ScaledJob",
+			scaler:   kedaObject(scaledJobGVK, "report-keda", ""),
+			expected: []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: "default", Name: "report-vwa"}}},
+		},
+		{
+			name:   "Unmanaged ScaledJob",
+			scaler: kedaObject(scaledJobGVK, "cleanup-keda", ""),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, r.findVWAForKEDAScaler(context.Background(), tt.scaler))
+		})
+	}
+}
+
+func TestKEDAInstalled(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(scaledObjectGVK, meta.RESTScopeNamespace)
+
+	installed, err := kedaInstalled(mapper, scaledObjectGVK)
+	require.NoError(t, err)
+	assert.True(t, installed)
+	installed, err = kedaInstalled(mapper, scaledJobGVK)
+	require.NoError(t, err)
+	assert.False(t, installed)
+}
+
+func TestEffectiveIgnoreWithKEDA(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	addKEDAToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(scaledJobObjects("cpu", "memory:app")...).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "report-vwa", Namespace: "default"},
+		Status: vwav1.VerticalWorkloadAutoscalerStatus{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Job", Name: "report-x7k2p"},
+		},
+	}
+
+	ignore, err := r.effectiveIgnore(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.Equal(t, &vwav1.EffectiveIgnore{
+		CPU:        true,
+		Containers: []vwav1.ContainerIgnore{{Name: "app", Memory: true}},
+		Source:     "ScaledJob/report-keda",
+	}, ignore)
+}
@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// changeFreezeActive reports whether a ChangeFreeze is active (1) or expired (0)
+	changeFreezeActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vwa_change_freeze_active",
+		Help: "Whether a ChangeFreeze is active (1) or expired (0).",
+	}, []string{"name"})
+
+	// vwaFrozen reports whether a VWA is held by an active ChangeFreeze
+	vwaFrozen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vwa_frozen",
+		Help: "Whether a VerticalWorkloadAutoscaler is held by an active ChangeFreeze (1) or not (0).",
+	}, []string{"namespace", "name", "freeze"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(changeFreezeActive, vwaFrozen)
+}
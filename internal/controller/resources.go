@@ -15,38 +15,51 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-const (
-	defaultCPUTolerance    = 0.10
-	defaultMemoryTolerance = 0.10
-)
-
 func (r *VerticalWorkloadAutoscalerReconciler) fetchTargetObject(ctx context.Context, vpa *vpav1.VerticalPodAutoscaler) (client.Object, error) {
 	if vpa.Spec.TargetRef == nil {
 		return nil, fmt.Errorf("targetRef is not set")
 	}
 
-	var targetObject client.Object
+	targetObject, err := newTargetObject(vpa.Spec.TargetRef.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.Get(ctx, client.ObjectKey{Name: vpa.Spec.TargetRef.Name, Namespace: vpa.Namespace}, targetObject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target resource %s/%s: %w", vpa.Namespace, vpa.Spec.TargetRef.Name, err)
+	}
+	return targetObject, nil
+}
 
-	switch vpa.Spec.TargetRef.Kind {
+// newTargetObject returns an empty target object of the kind
+func newTargetObject(kind string) (client.Object, error) {
+	switch kind {
 	case "Deployment":
-		targetObject = &appsv1.Deployment{}
+		return &appsv1.Deployment{}, nil
 	case "StatefulSet":
-		targetObject = &appsv1.StatefulSet{}
+		return &appsv1.StatefulSet{}, nil
 	case "CronJob":
-		targetObject = &batchv1.CronJob{}
+		return &batchv1.CronJob{}, nil
 	case "ReplicaSet":
-		targetObject = &appsv1.ReplicaSet{}
+		return &appsv1.ReplicaSet{}, nil
 	case "DaemonSet":
-		targetObject = &appsv1.DaemonSet{}
+		return &appsv1.DaemonSet{}, nil
 	default:
-		return nil, fmt.Errorf("unsupported target resource kind: %s", vpa.Spec.TargetRef.Kind)
+		return nil, fmt.Errorf("unsupported target resource kind: %s", kind)
 	}
+}
 
-	err := r.Get(ctx, client.ObjectKey{Name: vpa.Spec.TargetRef.Name, Namespace: vpa.Namespace}, targetObject)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get target resource %s/%s: %w", vpa.Namespace, vpa.Spec.TargetRef.Name, err)
+// ensureTargetRef checks that the VPA targets the workload pinned by the VWA targetRef, if any
+func ensureTargetRef(wa *vwav1.VerticalWorkloadAutoscaler, vpa *vpav1.VerticalPodAutoscaler) error {
+	targetRef, err := wa.TargetRef()
+	if err != nil || targetRef == nil {
+		return err
 	}
-	return targetObject, nil
+	if vpa.Spec.TargetRef == nil || vpa.Spec.TargetRef.Kind != targetRef.Kind || vpa.Spec.TargetRef.Name != targetRef.Name {
+		return fmt.Errorf("VPA '%s' doesn't target %s '%s' referenced by the VWA targetRef", vpa.Name, targetRef.Kind, targetRef.Name)
+	}
+	return nil
 }
 
 // calculateNewResources calculates the new resource requirements based on the VPA recommendations
@@ -54,32 +67,26 @@ func (r *VerticalWorkloadAutoscalerReconciler) fetchTargetObject(ctx context.Con
 func (r *VerticalWorkloadAutoscalerReconciler) calculateNewResources(wa *vwav1.VerticalWorkloadAutoscaler, currentResources map[string]corev1.ResourceRequirements, recommendations *vpav1.RecommendedPodResources) map[string]corev1.ResourceRequirements {
 	newResources := make(map[string]corev1.ResourceRequirements)
 
-	cpuTolerance, memoryTolerance := getTolerances(wa) // Dereference to get the value
-
-	// Default QualityOfService to Guaranteed if not set
-	if wa.Spec.QualityOfService == "" {
-		wa.Spec.QualityOfService = vwav1.GuaranteedQualityOfService
-	}
+	spec := r.effectiveSpec(wa)
+	cpuTolerance, memoryTolerance := getTolerances(spec.UpdateTolerance)
 
 	for _, containerRec := range recommendations.ContainerRecommendations {
 		var newReq *corev1.ResourceRequirements
 		currentReq := currentResources[containerRec.ContainerName]
 
-		if wa.Spec.QualityOfService == vwav1.GuaranteedQualityOfService {
-			newReq = updateGuaranteedResources(currentReq, containerRec, cpuTolerance, memoryTolerance, wa.Spec.AvoidCPULimit)
-		} else if wa.Spec.QualityOfService == vwav1.BurstableQualityOfService {
-			newReq = updateBurstableResources(currentReq, containerRec, cpuTolerance, memoryTolerance, wa.Spec.AvoidCPULimit)
+		if spec.QualityOfService == vwav1.GuaranteedQualityOfService {
+			newReq = updateGuaranteedResources(currentReq, containerRec, cpuTolerance, memoryTolerance, spec.AvoidCPULimit)
+		} else if spec.QualityOfService == vwav1.BurstableQualityOfService {
+			newReq = updateBurstableResources(currentReq, containerRec, cpuTolerance, memoryTolerance, spec.AvoidCPULimit)
 		}
 
-		// If the IgnoreCPURecommendations or IgnoreMemoryRecommendations is set to true, keep the current value
-		if wa.Spec.IgnoreCPURecommendations {
-			newReq.Requests[corev1.ResourceCPU] = currentReq.Requests[corev1.ResourceCPU]
-			newReq.Limits[corev1.ResourceCPU] = currentReq.Limits[corev1.ResourceCPU]
+		// Keep the current values of the ignored resources
+		ignoreCPU, ignoreMemory := ignoredResources(wa.Status.EffectiveIgnore, containerRec.ContainerName)
+		if ignoreCPU {
+			keepCurrentResource(newReq, currentReq, corev1.ResourceCPU)
 		}
-		// If the IgnoreCPURecommendations or IgnoreMemoryRecommendations is set to true, keep the current value
-		if wa.Spec.IgnoreMemoryRecommendations {
-			newReq.Requests[corev1.ResourceMemory] = currentReq.Requests[corev1.ResourceMemory]
-			newReq.Limits[corev1.ResourceMemory] = currentReq.Limits[corev1.ResourceMemory]
+		if ignoreMemory {
+			keepCurrentResource(newReq, currentReq, corev1.ResourceMemory)
 		}
 
 		newResources[containerRec.ContainerName] = *newReq
@@ -88,16 +95,30 @@ func (r *VerticalWorkloadAutoscalerReconciler) calculateNewResources(wa *vwav1.V
 	return newResources
 }
 
-// getTolerances returns the CPU and memory tolerances based on the VWA configuration
-func getTolerances(wa *vwav1.VerticalWorkloadAutoscaler) (cpuTolerance, memoryTolerance float64) {
-	cpuTolerance, memoryTolerance = defaultCPUTolerance, defaultMemoryTolerance
+// keepCurrentResource sets the requests and limits of the resource back to the current ones, leaving them unset
+// when they aren't set currently
+func keepCurrentResource(newReq *corev1.ResourceRequirements, currentReq corev1.ResourceRequirements, name corev1.ResourceName) {
+	for _, lists := range [][2]corev1.ResourceList{{newReq.Requests, currentReq.Requests}, {newReq.Limits, currentReq.Limits}} {
+		if current, ok := lists[1][name]; ok {
+			lists[0][name] = current
+		} else {
+			delete(lists[0], name)
+		}
+	}
+}
+
+// getTolerances returns the CPU and memory tolerances as fractions; unset tolerances use the built-in default,
+// an explicit 0 means any change is applied
+func getTolerances(tolerance *vwav1.UpdateTolerance) (cpuTolerance, memoryTolerance float64) {
+	cpuTolerance = float64(vwav1.DefaultTolerance) / 100
+	memoryTolerance = float64(vwav1.DefaultTolerance) / 100
 
-	if wa.Spec.UpdateTolerance != nil {
-		if wa.Spec.UpdateTolerance.CPU > 0 {
-			cpuTolerance = float64(wa.Spec.UpdateTolerance.CPU) / 100
+	if tolerance != nil {
+		if tolerance.CPU != nil {
+			cpuTolerance = float64(*tolerance.CPU) / 100
 		}
-		if wa.Spec.UpdateTolerance.Memory > 0 {
-			memoryTolerance = float64(wa.Spec.UpdateTolerance.Memory) / 100
+		if tolerance.Memory != nil {
+			memoryTolerance = float64(*tolerance.Memory) / 100
 		}
 	}
 	return
@@ -109,6 +130,9 @@ func applyUpdate(current, recommended resource.Quantity, tolerance float64) bool
 		return true
 	}
 	change := float64(recommended.MilliValue()-current.MilliValue()) / float64(current.MilliValue())
+	if change == 0 {
+		return false
+	}
 	return change >= tolerance || change <= -tolerance
 }
 
@@ -187,6 +211,70 @@ func updateBurstableResources(currentReq corev1.ResourceRequirements, containerR
 	return newReq
 }
 
+// changeDirection returns 1 if the resource is increased, -1 if it is decreased and 0 if it is unchanged;
+// the request decides the direction, the limit only if the request is unchanged (no limit means unbounded)
+func changeDirection(current, recommended corev1.ResourceRequirements, name corev1.ResourceName) int {
+	if cmp := recommended.Requests.Name(name, resource.DecimalSI).Cmp(*current.Requests.Name(name, resource.DecimalSI)); cmp != 0 {
+		return cmp
+	}
+	currentLimit, hasCurrentLimit := current.Limits[name]
+	recommendedLimit, hasRecommendedLimit := recommended.Limits[name]
+	switch {
+	case hasCurrentLimit && hasRecommendedLimit:
+		return recommendedLimit.Cmp(currentLimit)
+	case hasCurrentLimit:
+		return 1
+	case hasRecommendedLimit:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// deferBlockedChanges keeps the current CPU and memory values of containers whose change goes in a direction
+// that is outside its update window; it reports whether any change was deferred
+func deferBlockedChanges(currentResources, newResources map[string]corev1.ResourceRequirements, deferIncrease, deferDecrease bool) (map[string]corev1.ResourceRequirements, bool) {
+	if !deferIncrease && !deferDecrease {
+		return newResources, false
+	}
+
+	deferred := false
+	result := make(map[string]corev1.ResourceRequirements, len(newResources))
+	for name, newReq := range newResources {
+		currentReq := currentResources[name]
+		req := *newReq.DeepCopy()
+		for _, res := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			direction := changeDirection(currentReq, req, res)
+			if (direction > 0 && deferIncrease) || (direction < 0 && deferDecrease) {
+				restoreResource(&req, currentReq, res)
+				deferred = true
+			}
+		}
+		result[name] = req
+	}
+	return result, deferred
+}
+
+// restoreResource sets the request and limit of the resource back to the current values
+func restoreResource(req *corev1.ResourceRequirements, current corev1.ResourceRequirements, name corev1.ResourceName) {
+	if value, ok := current.Requests[name]; ok {
+		if req.Requests == nil {
+			req.Requests = corev1.ResourceList{}
+		}
+		req.Requests[name] = value
+	} else {
+		delete(req.Requests, name)
+	}
+	if value, ok := current.Limits[name]; ok {
+		if req.Limits == nil {
+			req.Limits = corev1.ResourceList{}
+		}
+		req.Limits[name] = value
+	} else {
+		delete(req.Limits, name)
+	}
+}
+
 func resourceRequirementsEqual(a, b corev1.ResourceRequirements) bool {
 	return a.Requests.Cpu().Equal(*b.Requests.Cpu()) &&
 		a.Requests.Memory().Equal(*b.Requests.Memory()) &&
@@ -255,23 +343,28 @@ func checkChangeRequirement(current, recommended *resource.Quantity, requirement
 	}
 }
 
-func (r *VerticalWorkloadAutoscalerReconciler) updateTargetObject(ctx context.Context, targetObject client.Object, vwa *vwav1.VerticalWorkloadAutoscaler, newResources map[string]corev1.ResourceRequirements, updatePolicy *vpav1.PodUpdatePolicy) (bool, error) {
-	needsUpdate := false
+// plannedResources returns the current resources of the containers with recommendations, the resources to apply
+// to them and whether any container needs an update. The resources to apply include every managed container:
+// the VWA field manager must apply all the fields it owns, otherwise server-side apply removes the fields it leaves out.
+func plannedResources(targetObject client.Object, newResources map[string]corev1.ResourceRequirements, updatePolicy *vpav1.PodUpdatePolicy) (currentResources, appliedResources map[string]corev1.ResourceRequirements, needsUpdate bool, err error) {
+	currentResources = make(map[string]corev1.ResourceRequirements)
+	appliedResources = make(map[string]corev1.ResourceRequirements)
 
 	updateContainers := func(containers []corev1.Container) {
-		for i := range containers {
+		for _, container := range containers {
 			// Update the container resources if they are different from the recommended resources
 			// and the container is present in the recommendations
-			// use reference to avoid closure variable capture
-			container := &containers[i]
-			if recommendedResources, ok := newResources[container.Name]; ok {
-				if !resourceRequirementsEqual(container.Resources, recommendedResources) {
-					// Check eviction requirements before updating
-					if meetsEvictionRequirements(container.Resources, recommendedResources, updatePolicy) {
-						recommendedResources.Requests.DeepCopyInto(&container.Resources.Requests)
-						recommendedResources.Limits.DeepCopyInto(&container.Resources.Limits)
-						needsUpdate = true
-					}
+			recommendedResources, ok := newResources[container.Name]
+			if !ok {
+				continue
+			}
+			appliedResources[container.Name] = container.Resources
+			currentResources[container.Name] = container.Resources
+			if !resourceRequirementsEqual(container.Resources, recommendedResources) {
+				// Check eviction requirements before updating
+				if meetsEvictionRequirements(container.Resources, recommendedResources, updatePolicy) {
+					appliedResources[container.Name] = recommendedResources
+					needsUpdate = true
 				}
 			}
 		}
@@ -291,14 +384,40 @@ func (r *VerticalWorkloadAutoscalerReconciler) updateTargetObject(ctx context.Co
 	case *appsv1.DaemonSet:
 		updateContainers(resource.Spec.Template.Spec.Containers)
 	default:
-		return false, errors.NewBadRequest(fmt.Sprintf("unsupported target object type: %T", targetObject))
+		return nil, nil, false, errors.NewBadRequest(fmt.Sprintf("unsupported target object type: %T", targetObject))
+	}
+	return currentResources, appliedResources, needsUpdate, nil
+}
+
+func (r *VerticalWorkloadAutoscalerReconciler) updateTargetObject(ctx context.Context, targetObject client.Object, vwa *vwav1.VerticalWorkloadAutoscaler, newResources map[string]corev1.ResourceRequirements, updatePolicy *vpav1.PodUpdatePolicy) (bool, error) {
+	currentResources, appliedResources, needsUpdate, err := plannedResources(targetObject, newResources, updatePolicy)
+	if err != nil {
+		return false, err
 	}
 
 	if needsUpdate {
-		r.setAnnotations(targetObject, vwa)
-		if err := r.Update(ctx, targetObject); err != nil {
-			return false, errors.NewInternalError(fmt.Errorf("failed to update target object: %w", err))
+		// the GitOps tool syncing the repository updates the target object
+		if vwa.Spec.GitWriteback != nil {
+			return r.writeBackResources(ctx, targetObject, vwa, appliedResources)
+		}
+		// the pod mutating webhook sets the resources on new pods
+		if vwa.Spec.ApplyMethod == vwav1.ApplyMethodAdmission {
+			vwa.Status.AppliedResources = appliedResources
+			return true, nil
+		}
+		// snapshot the resources of containers the VWA modifies for the first time, to restore them on deletion
+		for name, resources := range currentResources {
+			if _, ok := vwa.Status.OriginalResources[name]; !ok {
+				if vwa.Status.OriginalResources == nil {
+					vwa.Status.OriginalResources = make(map[string]corev1.ResourceRequirements)
+				}
+				vwa.Status.OriginalResources[name] = resources
+			}
+		}
+		if err := r.applyTargetResources(ctx, targetObject, vwa, appliedResources); err != nil {
+			return false, errors.NewInternalError(err)
 		}
+		vwa.Status.AppliedResources = appliedResources
 	}
 	return needsUpdate, nil
 }
@@ -314,8 +433,8 @@ func (r *VerticalWorkloadAutoscalerReconciler) setAnnotations(targetObject clien
 		targetAnnotations[k] = v
 	}
 	// add VWA specific annotations
-	targetAnnotations["verticalworkloadautoscaler.kubernetes.io/lastUpdated"] = timeNow().Format(time.RFC3339)
-	targetAnnotations["verticalworkloadautoscaler.kubernetes.io/updatedBy"] = vwa.Name
+	targetAnnotations[vwav1.LastUpdatedAnnotation] = timeNow().Format(time.RFC3339)
+	targetAnnotations[vwav1.UpdatedByAnnotation] = vwa.Name
 	// set the target object annotations
 	targetObject.SetAnnotations(targetAnnotations)
 }
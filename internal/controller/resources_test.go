@@ -14,6 +14,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"k8s.io/utils/ptr"
 	_client "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -27,7 +28,7 @@ func TestUpdateTargetObject(t *testing.T) {
 	s.AddKnownTypes(appsv1.SchemeGroupVersion, &corev1.Pod{})
 
 	// Create a fake client with a sample Deployment
-	client := fake.NewClientBuilder().WithScheme(s).Build()
+	client := withApplyPatches(fake.NewClientBuilder()).WithScheme(s).Build()
 
 	// Create a reconciler with the fake client
 	r := &VerticalWorkloadAutoscalerReconciler{
@@ -506,7 +507,7 @@ func TestGetTolerances(t *testing.T) {
 			wa: vwav1.VerticalWorkloadAutoscaler{
 				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
 					UpdateTolerance: &vwav1.UpdateTolerance{
-						CPU: 20,
+						CPU: ptr.To(20),
 					},
 				},
 			},
@@ -518,7 +519,7 @@ func TestGetTolerances(t *testing.T) {
 			wa: vwav1.VerticalWorkloadAutoscaler{
 				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
 					UpdateTolerance: &vwav1.UpdateTolerance{
-						Memory: 30,
+						Memory: ptr.To(30),
 					},
 				},
 			},
@@ -530,8 +531,8 @@ func TestGetTolerances(t *testing.T) {
 			wa: vwav1.VerticalWorkloadAutoscaler{
 				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
 					UpdateTolerance: &vwav1.UpdateTolerance{
-						CPU:    15,
-						Memory: 25,
+						CPU:    ptr.To(15),
+						Memory: ptr.To(25),
 					},
 				},
 			},
@@ -543,19 +544,19 @@ func TestGetTolerances(t *testing.T) {
 			wa: vwav1.VerticalWorkloadAutoscaler{
 				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
 					UpdateTolerance: &vwav1.UpdateTolerance{
-						CPU:    0,
-						Memory: 0,
+						CPU:    ptr.To(0),
+						Memory: ptr.To(0),
 					},
 				},
 			},
-			expectedCPU:    0.10,
-			expectedMemory: 0.10,
+			expectedCPU:    0,
+			expectedMemory: 0,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cpuTolerance, memoryTolerance := getTolerances(&tt.wa)
+			cpuTolerance, memoryTolerance := getTolerances(tt.wa.Spec.UpdateTolerance)
 			assert.Equal(t, tt.expectedCPU, cpuTolerance)
 			assert.Equal(t, tt.expectedMemory, memoryTolerance)
 		})
@@ -605,6 +606,20 @@ func TestApplyUpdate(t *testing.T) {
 			tolerance:   0.1,
 			expected:    false,
 		},
+		{
+			name:        "Update any change with zero tolerance",
+			current:     resource.MustParse("100m"),
+			recommended: resource.MustParse("101m"),
+			tolerance:   0,
+			expected:    true,
+		},
+		{
+			name:        "No update without change with zero tolerance",
+			current:     resource.MustParse("100m"),
+			recommended: resource.MustParse("100m"),
+			tolerance:   0,
+			expected:    false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1496,8 +1511,10 @@ func TestCalculateNewResources(t *testing.T) {
 			name: "Ignore CPU recommendations",
 			wa: vwav1.VerticalWorkloadAutoscaler{
 				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
-					IgnoreCPURecommendations: true,
-					AvoidCPULimit:            false,
+					AvoidCPULimit: false,
+				},
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{
+					EffectiveIgnore: &vwav1.EffectiveIgnore{CPU: true},
 				},
 			},
 			currentResources: map[string]corev1.ResourceRequirements{
@@ -1540,8 +1557,10 @@ func TestCalculateNewResources(t *testing.T) {
 			name: "Ignore Memory recommendations",
 			wa: vwav1.VerticalWorkloadAutoscaler{
 				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
-					IgnoreMemoryRecommendations: true,
-					AvoidCPULimit:               false,
+					AvoidCPULimit: false,
+				},
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{
+					EffectiveIgnore: &vwav1.EffectiveIgnore{Memory: true},
 				},
 			},
 			currentResources: map[string]corev1.ResourceRequirements{
@@ -1580,6 +1599,37 @@ func TestCalculateNewResources(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Ignore container CPU recommendations",
+			wa: vwav1.VerticalWorkloadAutoscaler{
+				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+					AvoidCPULimit: true,
+				},
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{
+					EffectiveIgnore: &vwav1.EffectiveIgnore{Containers: []vwav1.ContainerIgnore{{Name: "app", CPU: true}}},
+				},
+			},
+			currentResources: map[string]corev1.ResourceRequirements{
+				"app":   {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("128Mi")}},
+				"proxy": {Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("128Mi")}},
+			},
+			recommendations: &vpav1.RecommendedPodResources{
+				ContainerRecommendations: []vpav1.RecommendedContainerResources{
+					{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("300m"), corev1.ResourceMemory: resource.MustParse("128Mi")}},
+					{ContainerName: "proxy", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("300m"), corev1.ResourceMemory: resource.MustParse("128Mi")}},
+				},
+			},
+			expected: map[string]corev1.ResourceRequirements{
+				"app": {
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+					Limits:   corev1.ResourceList{},
+				},
+				"proxy": {
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("300m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+					Limits:   corev1.ResourceList{},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1671,6 +1721,32 @@ func TestSetAnnotations(t *testing.T) {
 				"verticalworkloadautoscaler.kubernetes.io/updatedBy":   "test-vwa",
 			},
 		},
+		{
+			name: "GitOps integration adds no annotations",
+			targetObject: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-deployment",
+					Namespace: "default",
+				},
+			},
+			vwa: &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-vwa",
+					Namespace: "default",
+				},
+				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+					GitOps: &vwav1.GitOpsIntegration{Tool: vwav1.GitOpsToolArgoCD},
+					CustomAnnotations: map[string]string{
+						"argocd.argoproj.io/sync-options": "ServerSideApply=true,Validate=false",
+					},
+				},
+			},
+			expectedAnnotations: map[string]string{
+				"argocd.argoproj.io/sync-options":                      "ServerSideApply=true,Validate=false",
+				"verticalworkloadautoscaler.kubernetes.io/lastUpdated": timeNow().Format(time.RFC3339),
+				"verticalworkloadautoscaler.kubernetes.io/updatedBy":   "test-vwa",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1858,3 +1934,193 @@ func TestMeetsEvictionRequirements(t *testing.T) {
 		})
 	}
 }
+
+func TestDeferBlockedChanges(t *testing.T) {
+	current := map[string]corev1.ResourceRequirements{
+		"container1": {
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			},
+		},
+	}
+	// CPU is raised, memory is lowered
+	recommended := map[string]corev1.ResourceRequirements{
+		"container1": {
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		deferIncrease    bool
+		deferDecrease    bool
+		expected         map[string]corev1.ResourceRequirements
+		expectedDeferred bool
+	}{
+		{
+			name:     "Nothing deferred",
+			expected: recommended,
+		},
+		{
+			name:          "Decrease deferred",
+			deferDecrease: true,
+			expected: map[string]corev1.ResourceRequirements{
+				"container1": {
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("500m"),
+						corev1.ResourceMemory: resource.MustParse("512Mi"),
+					},
+					Limits: corev1.ResourceList{
+						corev1.ResourceMemory: resource.MustParse("512Mi"),
+					},
+				},
+			},
+			expectedDeferred: true,
+		},
+		{
+			name:          "Increase deferred",
+			deferIncrease: true,
+			expected: map[string]corev1.ResourceRequirements{
+				"container1": {
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("250m"),
+						corev1.ResourceMemory: resource.MustParse("256Mi"),
+					},
+					Limits: corev1.ResourceList{
+						corev1.ResourceMemory: resource.MustParse("256Mi"),
+					},
+				},
+			},
+			expectedDeferred: true,
+		},
+		{
+			name:             "Both deferred",
+			deferIncrease:    true,
+			deferDecrease:    true,
+			expected:         current,
+			expectedDeferred: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, deferred := deferBlockedChanges(current, recommended, tt.deferIncrease, tt.deferDecrease)
+			assert.Equal(t, tt.expectedDeferred, deferred)
+			for name, expected := range tt.expected {
+				assert.True(t, resourceRequirementsEqual(expected, result[name]), "expected %v, got %v", expected, result[name])
+			}
+		})
+	}
+}
+
+func TestChangeDirection(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     corev1.ResourceRequirements
+		recommended corev1.ResourceRequirements
+		expected    int
+	}{
+		{
+			name:        "Request increased",
+			current:     corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+			recommended: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")}},
+			expected:    1,
+		},
+		{
+			name:        "Request decreased",
+			current:     corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")}},
+			recommended: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+			expected:    -1,
+		},
+		{
+			name: "Limit removed",
+			current: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			},
+			recommended: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+			expected:    1,
+		},
+		{
+			name: "Limit lowered",
+			current: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+			},
+			recommended: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+			},
+			expected: -1,
+		},
+		{
+			name:        "Unchanged",
+			current:     corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+			recommended: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("0.1")}},
+			expected:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, changeDirection(tt.current, tt.recommended, corev1.ResourceCPU))
+		})
+	}
+}
+
+func TestEnsureTargetRef(t *testing.T) {
+	vpa := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			TargetRef: &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "deployment1", APIVersion: "apps/v1"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expectError bool
+	}{
+		{
+			name: "No targetRef",
+		},
+		{
+			name:        "Matching targetRef",
+			annotations: map[string]string{vwav1.TargetRefAnnotation: `{"kind":"Deployment","name":"deployment1","apiVersion":"apps/v1"}`},
+		},
+		{
+			name:        "VPA targets another workload",
+			annotations: map[string]string{vwav1.TargetRefAnnotation: `{"kind":"StatefulSet","name":"deployment1","apiVersion":"apps/v1"}`},
+			expectError: true,
+		},
+		{
+			name:        "Invalid targetRef annotation",
+			annotations: map[string]string{vwav1.TargetRefAnnotation: `Deployment/deployment1`},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default", Annotations: tt.annotations},
+			}
+			err := ensureTargetRef(wa, vpa)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// recordRevision adds a change of the container resources to the revision history of the VWA, dropping the
+// oldest revisions beyond spec.revisionHistoryLimit except the spec.rollbackTo revision. Containers with unchanged
+// resources are left out.
+func (r *VerticalWorkloadAutoscalerReconciler) recordRevision(wa *vwav1.VerticalWorkloadAutoscaler, reason vwav1.RevisionReason, message string, before, after map[string]corev1.ResourceRequirements, recommendation *vpav1.RecommendedPodResources) {
+	containers := containerChanges(before, after, recommendation)
+	if len(containers) == 0 {
+		return
+	}
+
+	revision := int64(1)
+	if n := len(wa.Status.Revisions); n > 0 {
+		revision = wa.Status.Revisions[n-1].Revision + 1
+	}
+	wa.Status.Revisions = append(wa.Status.Revisions, vwav1.Revision{
+		Revision:   revision,
+		Timestamp:  metav1.NewTime(timeNow()),
+		Reason:     reason,
+		Message:    message,
+		Containers: containers,
+	})
+	if limit := int(*r.effectiveSpec(wa).RevisionHistoryLimit); len(wa.Status.Revisions) > limit {
+		wa.Status.Revisions = trimRevisions(wa.Status.Revisions, limit, wa.Spec.RollbackTo)
+	}
+}
+
+// trimRevisions keeps the newest revisions up to the limit, keeping the pinned revision in place of the oldest of
+// them, so a rollback to it can still be applied
+func trimRevisions(revisions []vwav1.Revision, limit int, pinned *int64) []vwav1.Revision {
+	kept := revisions[len(revisions)-limit:]
+	if pinned == nil || findRevision(kept, *pinned) != nil {
+		return kept
+	}
+	revision := findRevision(revisions, *pinned)
+	if revision == nil {
+		return kept
+	}
+	return append([]vwav1.Revision{*revision}, revisions[len(revisions)-limit+1:]...)
+}
+
+// containerChanges returns the containers with changed resources, with their resources before and after the change
+func containerChanges(before, after map[string]corev1.ResourceRequirements, recommendation *vpav1.RecommendedPodResources) map[string]vwav1.ContainerRevision {
+	containers := make(map[string]vwav1.ContainerRevision)
+	for name, resources := range after {
+		if resourceRequirementsEqual(before[name], resources) {
+			continue
+		}
+		containers[name] = vwav1.ContainerRevision{
+			Before:         before[name],
+			After:          resources,
+			Recommendation: recommendedTarget(recommendation, name),
+		}
+	}
+	return containers
+}
+
+// recommendedTarget returns the VPA target recommendation of the container, if any
+func recommendedTarget(recommendation *vpav1.RecommendedPodResources, container string) corev1.ResourceList {
+	if recommendation == nil {
+		return nil
+	}
+	for _, containerRec := range recommendation.ContainerRecommendations {
+		if containerRec.ContainerName == container {
+			return containerRec.Target
+		}
+	}
+	return nil
+}
+
+// findRevision returns the revision with the number from the revision history
+func findRevision(revisions []vwav1.Revision, number int64) *vwav1.Revision {
+	for i := range revisions {
+		if revisions[i].Revision == number {
+			return &revisions[i]
+		}
+	}
+	return nil
+}
+
+// rollbackHold returns what holds a rollback back and when to check it again: a rollback waits for change
+// freezes, suspensions and blackout windows like any other change, but not for the update windows and frequency
+func rollbackHold(wa *vwav1.VerticalWorkloadAutoscaler, freeze *vwav1.ChangeFreeze, blackout *blackoutPeriod) (string, time.Duration) {
+	switch {
+	case freeze != nil:
+		var delay time.Duration
+		if freeze.Spec.ExpiresAt != nil {
+			delay = freeze.Spec.ExpiresAt.Sub(timeNow())
+		}
+		return fmt.Sprintf("changes frozen by ChangeFreeze '%s'", freeze.Name), delay
+	case suspendReason(wa) != "":
+		return suspendReason(wa), 0
+	case blackout != nil:
+		return fmt.Sprintf("blackout window '%s' until %s", blackout.name, blackout.end.Format(time.RFC3339)), blackout.end.Sub(timeNow())
+	default:
+		return "", 0
+	}
+}
+
+// handleRollbackPending reports a rollback held back in the Reconciled condition, leaving the target object untouched
+func (r *VerticalWorkloadAutoscalerReconciler) handleRollbackPending(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, hold string, delay time.Duration) (ctrl.Result, error) {
+	msg := fmt.Sprintf("rollback to revision %d pending: %s", *wa.Spec.RollbackTo, hold)
+	if condition := findCondition(wa.Status.Conditions, ConditionTypeReconciled); condition == nil || condition.Reason != ReasonRollbackPending || condition.Message != msg {
+		r.recordEvent(wa, "Normal", ReasonRollbackPending, msg)
+	}
+	if err := r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionFalse, ReasonRollbackPending, msg); err != nil {
+		return r.handleError(ctx, wa, err, "failed to update VerticalWorkloadAutoscaler status", ReasonAPIError, "failed to update VerticalWorkloadAutoscaler status")
+	}
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// handleRollback keeps the target object at the resources of the spec.rollbackTo revision instead of
+// applying the VPA recommendations, until spec.rollbackTo is unset
+func (r *VerticalWorkloadAutoscalerReconciler) handleRollback(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	number := *wa.Spec.RollbackTo
+	revision := findRevision(wa.Status.Revisions, number)
+	if revision == nil {
+		msg := fmt.Sprintf("revision %d is not in the revision history", number)
+		r.recordEvent(wa, "Warning", ReasonRevisionNotFound, msg)
+		r.updateStatusCondition(ctx, wa, ConditionTypeError, metav1.ConditionTrue, ReasonRevisionNotFound, msg) //nolint:errcheck
+		return ctrl.Result{}, nil
+	}
+
+	targetObject, err := newTargetObject(wa.Status.ScaleTargetRef.Kind)
+	if err != nil {
+		return r.handleError(ctx, wa, err, "failed to roll back target object", ReasonAPIError, err.Error())
+	}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: wa.Namespace, Name: wa.Status.ScaleTargetRef.Name}, targetObject); err != nil {
+		if errors.IsNotFound(err) {
+			return r.handleNotFound(ctx, wa, "target object not found", ReasonTargetObjectNotFound)
+		}
+		return r.handleError(ctx, wa, err, "failed to fetch target object", ReasonAPIError, "failed to fetch target object")
+	}
+	admission := r.effectiveSpec(wa).ApplyMethod == vwav1.ApplyMethodAdmission
+	if admission {
+		if targetObject, err = admissionTarget(targetObject, wa.Status.AppliedResources); err != nil {
+			return r.handleError(ctx, wa, err, "failed to fetch current resources", ReasonAPIError, "failed to fetch current resources")
+		}
+	}
+	currentResources, err := r.fetchCurrentResources(targetObject)
+	if err != nil {
+		return r.handleError(ctx, wa, err, "failed to fetch current resources", ReasonAPIError, "failed to fetch current resources")
+	}
+
+	// apply every managed container, with the resources of the containers changed by the revision rolled back
+	resources := make(map[string]corev1.ResourceRequirements)
+	for name := range wa.Status.AppliedResources {
+		if current, ok := currentResources[name]; ok {
+			resources[name] = current
+		}
+	}
+	changed := false
+	for name, container := range revision.Containers {
+		if current, ok := currentResources[name]; ok {
+			resources[name] = container.After
+			changed = changed || !resourceRequirementsEqual(current, container.After)
+		}
+	}
+
+	updated := false
+	if wa.Spec.GitWriteback != nil {
+		updated, err = r.writeBackResources(ctx, targetObject, wa, resources)
+	} else if changed && admission {
+		wa.Status.AppliedResources = resources
+		updated = true
+	} else if changed {
+		if err = r.applyTargetResources(ctx, targetObject, wa, resources); err == nil {
+			wa.Status.AppliedResources = resources
+			updated = true
+		}
+	}
+	if err != nil {
+		return r.handleError(ctx, wa, err, "failed to roll back target resources", ReasonAPIError, "failed to roll back target resources")
+	}
+
+	if updated {
+		logger.Info("rolled back resources", "revision", number, "target", targetObject.GetName())
+		r.recordRevision(wa, vwav1.RevisionReasonRollback, fmt.Sprintf("rolled back to revision %d", number), currentResources, resources, nil)
+		now := metav1.NewTime(timeNow())
+		wa.Status.LastUpdated = &now
+		wa.Status.UpdateCount++
+		r.recordEvent(wa, "Normal", ReasonRolledBack, fmt.Sprintf("rolled back resources of '%s' to revision %d", targetObject.GetName(), number))
+	}
+	// the HPA targets follow the rolled back requests, also after a failed patch
+	if wa.Spec.GitWriteback == nil {
+		if _, err := r.coordinateHPA(ctx, wa, currentResources, resources); err != nil {
+			return r.handleError(ctx, wa, err, "failed to coordinate HPA targets", ReasonAPIError, "failed to coordinate HPA targets")
+		}
+	}
+	msg := fmt.Sprintf("rolled back to revision %d; automatic updates are paused until spec.rollbackTo is unset", number)
+	if err := r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionFalse, ReasonRolledBack, msg); err != nil {
+		return r.handleError(ctx, wa, err, "failed to update VerticalWorkloadAutoscaler status", ReasonAPIError, "failed to update VerticalWorkloadAutoscaler status")
+	}
+	return ctrl.Result{}, nil
+}
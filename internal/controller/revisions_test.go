@@ -0,0 +1,466 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRecordRevision(t *testing.T) {
+	now := time.Date(2024, 9, 21, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	recommendation := &vpav1.RecommendedPodResources{ContainerRecommendations: []vpav1.RecommendedContainerResources{
+		{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("210m")}},
+	}}
+	before := map[string]corev1.ResourceRequirements{"app": cpuRequests("100m"), "sidecar": cpuRequests("50m")}
+	after := map[string]corev1.ResourceRequirements{"app": cpuRequests("200m"), "sidecar": cpuRequests("50m")}
+
+	tests := []struct {
+		name              string
+		limit             *int32
+		rollbackTo        *int64
+		revisions         []vwav1.Revision
+		after             map[string]corev1.ResourceRequirements
+		expectedRevisions []int64
+	}{
+		{
+			name:              "First revision",
+			after:             after,
+			expectedRevisions: []int64{1},
+		},
+		{
+			name:              "Next revision",
+			revisions:         []vwav1.Revision{{Revision: 1}, {Revision: 2}},
+			after:             after,
+			expectedRevisions: []int64{1, 2, 3},
+		},
+		{
+			name:              "Oldest revisions dropped beyond the limit",
+			limit:             ptr.To(int32(2)),
+			revisions:         []vwav1.Revision{{Revision: 4}, {Revision: 5}},
+			after:             after,
+			expectedRevisions: []int64{5, 6},
+		},
+		{
+			name:              "Rollback revision kept beyond the limit",
+			limit:             ptr.To(int32(3)),
+			rollbackTo:        ptr.To(int64(4)),
+			revisions:         []vwav1.Revision{{Revision: 4}, {Revision: 5}, {Revision: 6}},
+			after:             after,
+			expectedRevisions: []int64{4, 6, 7},
+		},
+		{
+			name:              "Rollback revision within the limit",
+			limit:             ptr.To(int32(2)),
+			rollbackTo:        ptr.To(int64(5)),
+			revisions:         []vwav1.Revision{{Revision: 4}, {Revision: 5}},
+			after:             after,
+			expectedRevisions: []int64{5, 6},
+		},
+		{
+			name:              "Unchanged resources",
+			revisions:         []vwav1.Revision{{Revision: 1}},
+			after:             before,
+			expectedRevisions: []int64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &VerticalWorkloadAutoscalerReconciler{}
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				Spec:   vwav1.VerticalWorkloadAutoscalerSpec{RevisionHistoryLimit: tt.limit, RollbackTo: tt.rollbackTo},
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{Revisions: tt.revisions},
+			}
+
+			r.recordRevision(wa, vwav1.RevisionReasonRecommendation, "", before, tt.after, recommendation)
+
+			var numbers []int64
+			for _, revision := range wa.Status.Revisions {
+				numbers = append(numbers, revision.Revision)
+			}
+			assert.Equal(t, tt.expectedRevisions, numbers)
+			if len(tt.expectedRevisions) > len(tt.revisions) {
+				// only the changed containers are recorded
+				assert.Equal(t, vwav1.Revision{
+					Revision:  tt.expectedRevisions[len(tt.expectedRevisions)-1],
+					Timestamp: metav1.NewTime(now),
+					Reason:    vwav1.RevisionReasonRecommendation,
+					Containers: map[string]vwav1.ContainerRevision{
+						"app": {
+							Before:         cpuRequests("100m"),
+							After:          cpuRequests("200m"),
+							Recommendation: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("210m")},
+						},
+					},
+				}, wa.Status.Revisions[len(wa.Status.Revisions)-1])
+			}
+		})
+	}
+}
+
+func TestHandleRollback(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+
+	revisions := []vwav1.Revision{
+		{Revision: 1, Reason: vwav1.RevisionReasonRecommendation, Containers: map[string]vwav1.ContainerRevision{
+			"app": {Before: cpuRequests("100m"), After: cpuRequests("200m")},
+		}},
+		{Revision: 2, Reason: vwav1.RevisionReasonRecommendation, Containers: map[string]vwav1.ContainerRevision{
+			"app":     {Before: cpuRequests("200m"), After: cpuRequests("400m")},
+			"sidecar": {Before: cpuRequests("50m"), After: cpuRequests("100m")},
+		}},
+	}
+
+	tests := []struct {
+		name              string
+		rollbackTo        int64
+		appResources      corev1.ResourceRequirements
+		expectedResources map[string]corev1.ResourceRequirements
+		expectedRevisions int
+		expectedReason    string
+		expectedEvents    int
+	}{
+		{
+			name:              "Rollback to an earlier revision",
+			rollbackTo:        1,
+			appResources:      cpuRequests("400m"),
+			expectedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("200m"), "sidecar": cpuRequests("100m")},
+			expectedRevisions: 3,
+			expectedReason:    ReasonRolledBack,
+			expectedEvents:    1,
+		},
+		{
+			name:              "Already rolled back",
+			rollbackTo:        1,
+			appResources:      cpuRequests("200m"),
+			expectedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("200m"), "sidecar": cpuRequests("100m")},
+			expectedRevisions: 2,
+			expectedReason:    ReasonRolledBack,
+		},
+		{
+			name:              "Revision not in the history",
+			rollbackTo:        7,
+			appResources:      cpuRequests("400m"),
+			expectedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("400m"), "sidecar": cpuRequests("100m")},
+			expectedRevisions: 2,
+			expectedReason:    ReasonRevisionNotFound,
+			expectedEvents:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "app", Image: "web:1.0", Resources: tt.appResources},
+					{Name: "sidecar", Image: "proxy:1.0", Resources: cpuRequests("100m")},
+				}}}},
+			}
+			vwa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-vwa", Namespace: "default"},
+				Spec:       vwav1.VerticalWorkloadAutoscalerSpec{RollbackTo: ptr.To(tt.rollbackTo)},
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{
+					ScaleTargetRef:   autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web", APIVersion: "apps/v1"},
+					AppliedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("400m"), "sidecar": cpuRequests("100m")},
+					Revisions:        revisions,
+				},
+			}
+			c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithObjects(vwa, deployment).WithStatusSubresource(vwa).Build()
+			recorder := record.NewFakeRecorder(10)
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+
+			_, err := r.handleRollback(context.Background(), vwa)
+			require.NoError(t, err)
+
+			updated := &appsv1.Deployment{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), updated))
+			resources := make(map[string]corev1.ResourceRequirements)
+			for _, container := range updated.Spec.Template.Spec.Containers {
+				resources[container.Name] = container.Resources
+			}
+			assert.Equal(t, tt.expectedResources, resources)
+			assert.Len(t, recorder.Events, tt.expectedEvents)
+
+			stored := &vwav1.VerticalWorkloadAutoscaler{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vwa), stored))
+			require.Len(t, stored.Status.Revisions, tt.expectedRevisions)
+			if tt.expectedRevisions > len(revisions) {
+				last := stored.Status.Revisions[len(stored.Status.Revisions)-1]
+				assert.Equal(t, vwav1.RevisionReasonRollback, last.Reason)
+				assert.Equal(t, "rolled back to revision 1", last.Message)
+				assert.Equal(t, map[string]vwav1.ContainerRevision{
+					"app": {Before: cpuRequests("400m"), After: cpuRequests("200m")},
+				}, last.Containers)
+				assert.Equal(t, tt.expectedResources, stored.Status.AppliedResources)
+			}
+			var reasons []string
+			for _, condition := range stored.Status.Conditions {
+				reasons = append(reasons, condition.Reason)
+			}
+			assert.Contains(t, reasons, tt.expectedReason)
+		})
+	}
+}
+
+func TestHandleRollbackWithFullHistory(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "web:1.0", Resources: cpuRequests("400m")},
+		}}}},
+	}
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-vwa", Namespace: "default"},
+		Spec:       vwav1.VerticalWorkloadAutoscalerSpec{RollbackTo: ptr.To(int64(1)), RevisionHistoryLimit: ptr.To(int32(2))},
+		Status: vwav1.VerticalWorkloadAutoscalerStatus{
+			ScaleTargetRef:   autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web", APIVersion: "apps/v1"},
+			AppliedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("400m")},
+			Revisions: []vwav1.Revision{
+				{Revision: 1, Reason: vwav1.RevisionReasonRecommendation, Containers: map[string]vwav1.ContainerRevision{
+					"app": {Before: cpuRequests("100m"), After: cpuRequests("200m")},
+				}},
+				{Revision: 2, Reason: vwav1.RevisionReasonRecommendation, Containers: map[string]vwav1.ContainerRevision{
+					"app": {Before: cpuRequests("200m"), After: cpuRequests("400m")},
+				}},
+			},
+		},
+	}
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithObjects(vwa, deployment).WithStatusSubresource(vwa).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+	// the rollback is re-applied after every manual change of the resources, without losing its revision
+	for _, cpu := range []string{"300m", "600m"} {
+		_, err := r.handleRollback(context.Background(), vwa)
+		require.NoError(t, err)
+
+		stored := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+		assert.Equal(t, cpuRequests("200m"), stored.Spec.Template.Spec.Containers[0].Resources)
+		stored.Spec.Template.Spec.Containers[0].Resources = cpuRequests(cpu)
+		require.NoError(t, c.Update(context.Background(), stored))
+	}
+	_, err := r.handleRollback(context.Background(), vwa)
+	require.NoError(t, err)
+
+	var numbers []int64
+	for _, revision := range vwa.Status.Revisions {
+		numbers = append(numbers, revision.Revision)
+	}
+	assert.Equal(t, []int64{1, 5}, numbers)
+	assert.Equal(t, ReasonRolledBack, findCondition(vwa.Status.Conditions, ConditionTypeReconciled).Reason)
+}
+
+func TestHandleVWAChangeRollback(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	updateModeOff := vpav1.UpdateModeOff
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference: vwav1.VPAReference{Name: "vpa1"},
+		},
+	}
+	vpa := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &updateModeOff},
+			TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "web:1.0", Resources: cpuRequests("250m")},
+		}}}},
+	}
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+	// every reconcile happens after the update frequency passed, with a new recommendation
+	reconcile := func(recommendedCPU string) {
+		now = now.Add(10 * time.Minute)
+		stored := &vpav1.VerticalPodAutoscaler{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vpa), stored))
+		stored.Status.Recommendation = &vpav1.RecommendedPodResources{ContainerRecommendations: []vpav1.RecommendedContainerResources{
+			{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(recommendedCPU)}},
+		}}
+		require.NoError(t, c.Update(context.Background(), stored))
+		_, err := r.handleVWAChange(context.Background(), vwa)
+		require.NoError(t, err)
+	}
+	cpu := func() string {
+		stored := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+		return stored.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String()
+	}
+
+	reconcile("500m")
+	reconcile("1")
+	assert.Equal(t, "1", cpu())
+	require.Len(t, vwa.Status.Revisions, 2)
+	first := vwa.Status.Revisions[0].Containers["app"]
+	assert.Equal(t, cpuRequests("250m"), first.Before)
+	assert.Equal(t, "500m", first.After.Requests.Cpu().String())
+	assert.Equal(t, corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}, first.Recommendation)
+
+	// the rollback re-applies revision 1 and pauses the automatic updates
+	vwa.Spec.RollbackTo = ptr.To(int64(1))
+	require.NoError(t, c.Update(context.Background(), vwa))
+	reconcile("2")
+	assert.Equal(t, "500m", cpu())
+	reconcile("2")
+	assert.Equal(t, "500m", cpu())
+	require.Len(t, vwa.Status.Revisions, 3)
+	assert.Equal(t, vwav1.RevisionReasonRollback, vwa.Status.Revisions[2].Reason)
+	assert.Equal(t, ReasonRolledBack, findCondition(vwa.Status.Conditions, ConditionTypeReconciled).Reason)
+
+	// unsetting the rollback resumes them
+	vwa.Spec.RollbackTo = nil
+	require.NoError(t, c.Update(context.Background(), vwa))
+	reconcile("2")
+	assert.Equal(t, "2", cpu())
+	require.Len(t, vwa.Status.Revisions, 4)
+}
+
+func TestHandleVWAChangeRollbackPending(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	start := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	now := start
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	expiresAt := metav1.NewTime(start.Add(2 * time.Hour))
+	freeze := &vwav1.ChangeFreeze{
+		ObjectMeta: metav1.ObjectMeta{Name: "incident-42"},
+		Spec:       vwav1.ChangeFreezeSpec{Reason: "INC-42", ExpiresAt: &expiresAt},
+	}
+
+	tests := []struct {
+		name            string
+		freeze          bool
+		suspend         bool
+		blackouts       []vwav1.BlackoutWindow
+		expectedHold    string
+		expectedRequeue time.Duration
+	}{
+		{
+			name:            "Change freeze",
+			freeze:          true,
+			expectedHold:    "rollback to revision 1 pending: changes frozen by ChangeFreeze 'incident-42'",
+			expectedRequeue: 2 * time.Hour,
+		},
+		{
+			name:         "Suspended",
+			suspend:      true,
+			expectedHold: "rollback to revision 1 pending: updates suspended by spec.suspend",
+		},
+		{
+			name: "Blackout window",
+			blackouts: []vwav1.BlackoutWindow{
+				{Name: "release", Start: metav1.NewTime(start.Add(-time.Hour)), End: metav1.NewTime(start.Add(time.Hour))},
+			},
+			expectedHold:    "rollback to revision 1 pending: blackout window 'release' until 2023-10-10T11:00:00Z",
+			expectedRequeue: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = start
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "app", Image: "web:1.0", Resources: cpuRequests("400m")},
+				}}}},
+			}
+			vwa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-vwa", Namespace: "default"},
+				Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+					VPAReference:    vwav1.VPAReference{Name: "vpa1"},
+					RollbackTo:      ptr.To(int64(1)),
+					Suspend:         tt.suspend,
+					BlackoutWindows: tt.blackouts,
+				},
+				Status: vwav1.VerticalWorkloadAutoscalerStatus{
+					ScaleTargetRef:   autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web", APIVersion: "apps/v1"},
+					AppliedResources: map[string]corev1.ResourceRequirements{"app": cpuRequests("400m")},
+					Revisions: []vwav1.Revision{{Revision: 1, Reason: vwav1.RevisionReasonRecommendation, Containers: map[string]vwav1.ContainerRevision{
+						"app": {Before: cpuRequests("100m"), After: cpuRequests("200m")},
+					}}},
+				},
+			}
+			builder := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithObjects(vwa, deployment).WithStatusSubresource(vwa)
+			if tt.freeze {
+				builder = builder.WithObjects(freeze.DeepCopy())
+			}
+			c := builder.Build()
+			recorder := record.NewFakeRecorder(20)
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder, Timeout: time.Minute}
+			cpu := func() string {
+				stored := &appsv1.Deployment{}
+				require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+				return stored.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String()
+			}
+
+			// the rollback waits behind the hold and is reported as pending
+			result, err := r.handleVWAChange(context.Background(), vwa)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedRequeue, result.RequeueAfter)
+			assert.Equal(t, "400m", cpu())
+			require.Len(t, vwa.Status.Revisions, 1)
+			condition := findCondition(vwa.Status.Conditions, ConditionTypeReconciled)
+			require.NotNil(t, condition)
+			assert.Equal(t, ReasonRollbackPending, condition.Reason)
+			assert.Equal(t, tt.expectedHold, condition.Message)
+
+			// once the hold is over, the rollback is applied
+			if tt.freeze {
+				require.NoError(t, c.Delete(context.Background(), freeze.DeepCopy()))
+			}
+			vwa.Spec.Suspend = false
+			require.NoError(t, c.Update(context.Background(), vwa))
+			now = start.Add(2 * time.Hour)
+			_, err = r.handleVWAChange(context.Background(), vwa)
+			require.NoError(t, err)
+			assert.Equal(t, "200m", cpu())
+			require.Len(t, vwa.Status.Revisions, 2)
+			assert.Equal(t, ReasonRolledBack, findCondition(vwa.Status.Conditions, ConditionTypeReconciled).Reason)
+		})
+	}
+}
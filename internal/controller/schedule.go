@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// updateSchedule is the effective update schedule of a VWA, merged from its inline
+// update windows and blackouts and the ones of all referenced schedules
+type updateSchedule struct {
+	windows   []vwav1.UpdateWindow
+	blackouts []blackoutPeriod
+	// skippedEvents are the unsupported calendar events left out of the blackouts
+	skippedEvents []string
+}
+
+// resolveSchedule merges the inline schedule of the VWA with the referenced UpdateSchedule
+// and ClusterUpdateSchedule objects
+func (r *VerticalWorkloadAutoscalerReconciler) resolveSchedule(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, now time.Time) (updateSchedule, error) {
+	var schedule updateSchedule
+
+	if err := r.mergeSchedule(ctx, &schedule, wa.Namespace, vwav1.UpdateScheduleSpec{
+		AllowedUpdateWindows: wa.Spec.AllowedUpdateWindows,
+		BlackoutWindows:      wa.Spec.BlackoutWindows,
+		BlackoutCalendars:    wa.Spec.BlackoutCalendars,
+	}, now); err != nil {
+		return updateSchedule{}, err
+	}
+
+	for _, ref := range wa.Spec.UpdateSchedules {
+		spec, namespace, err := r.fetchUpdateSchedule(ctx, wa.Namespace, ref)
+		if err != nil {
+			return updateSchedule{}, err
+		}
+		if err := r.mergeSchedule(ctx, &schedule, namespace, spec, now); err != nil {
+			return updateSchedule{}, fmt.Errorf("%s '%s': %w", scheduleKind(ref), ref.Name, err)
+		}
+	}
+	return schedule, nil
+}
+
+// mergeSchedule appends the update windows and blackouts of spec to the schedule
+func (r *VerticalWorkloadAutoscalerReconciler) mergeSchedule(ctx context.Context, schedule *updateSchedule, namespace string, spec vwav1.UpdateScheduleSpec, now time.Time) error {
+	blackouts, skipped, err := r.resolveBlackouts(ctx, namespace, spec.BlackoutWindows, spec.BlackoutCalendars, now)
+	if err != nil {
+		return err
+	}
+	schedule.windows = append(schedule.windows, spec.AllowedUpdateWindows...)
+	schedule.blackouts = append(schedule.blackouts, blackouts...)
+	schedule.skippedEvents = append(schedule.skippedEvents, skipped...)
+	return nil
+}
+
+// fetchUpdateSchedule returns the spec of the referenced schedule and the namespace to read its calendars from
+func (r *VerticalWorkloadAutoscalerReconciler) fetchUpdateSchedule(ctx context.Context, namespace string, ref vwav1.UpdateScheduleReference) (vwav1.UpdateScheduleSpec, string, error) {
+	if scheduleKind(ref) == vwav1.ClusterUpdateScheduleKind {
+		var schedule vwav1.ClusterUpdateSchedule
+		if err := r.Get(ctx, client.ObjectKey{Name: ref.Name}, &schedule); err != nil {
+			return vwav1.UpdateScheduleSpec{}, "", fmt.Errorf("failed to get ClusterUpdateSchedule '%s': %w", ref.Name, err)
+		}
+		return schedule.Spec, "", nil
+	}
+
+	var schedule vwav1.UpdateSchedule
+	if err := r.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, &schedule); err != nil {
+		return vwav1.UpdateScheduleSpec{}, "", fmt.Errorf("failed to get UpdateSchedule %s/%s: %w", namespace, ref.Name, err)
+	}
+	return schedule.Spec, namespace, nil
+}
+
+// scheduleKind returns the kind of the referenced schedule, defaulting to UpdateSchedule
+func scheduleKind(ref vwav1.UpdateScheduleReference) string {
+	if ref.Kind == "" {
+		return vwav1.UpdateScheduleKind
+	}
+	return ref.Kind
+}
+
+// findVWAForUpdateSchedule maps an UpdateSchedule or ClusterUpdateSchedule to the VWAs referencing it
+func (r *VerticalWorkloadAutoscalerReconciler) findVWAForUpdateSchedule(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := make([]reconcile.Request, 0)
+
+	var kind string
+	var opts []client.ListOption
+	switch obj.(type) {
+	case *vwav1.UpdateSchedule:
+		kind = vwav1.UpdateScheduleKind
+		opts = append(opts, client.InNamespace(obj.GetNamespace()))
+	case *vwav1.ClusterUpdateSchedule:
+		kind = vwav1.ClusterUpdateScheduleKind
+	default:
+		return requests
+	}
+
+	var vwaList vwav1.VerticalWorkloadAutoscalerList
+	if err := r.List(ctx, &vwaList, opts...); err != nil {
+		log.Log.Error(err, "failed to list VerticalWorkloadAutoscaler objects")
+		return requests
+	}
+
+	for _, vwa := range vwaList.Items {
+		for _, ref := range vwa.Spec.UpdateSchedules {
+			if scheduleKind(ref) == kind && ref.Name == obj.GetName() {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: client.ObjectKey{Namespace: vwa.Namespace, Name: vwa.Name},
+				})
+				break
+			}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestResolveSchedule(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	nightly := vwav1.UpdateWindow{DayOfWeek: "Monday", StartTime: "01:00", EndTime: "05:00", TimeZone: "UTC"}
+	weekend := vwav1.UpdateWindow{DayOfWeek: "Saturday", StartTime: "00:00", EndTime: "23:59", TimeZone: "UTC"}
+	inline := vwav1.UpdateWindow{DayOfWeek: "Friday", StartTime: "09:00", EndTime: "12:00", TimeZone: "UTC"}
+
+	objs := []client.Object{
+		&vwav1.UpdateSchedule{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "default"},
+			Spec: vwav1.UpdateScheduleSpec{
+				AllowedUpdateWindows: []vwav1.UpdateWindow{nightly},
+			},
+		},
+		&vwav1.ClusterUpdateSchedule{
+			ObjectMeta: metav1.ObjectMeta{Name: "fleet"},
+			Spec: vwav1.UpdateScheduleSpec{
+				AllowedUpdateWindows: []vwav1.UpdateWindow{weekend},
+				BlackoutWindows: []vwav1.BlackoutWindow{
+					{
+						Name:  "Release freeze",
+						Start: metav1.NewTime(time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC)),
+						End:   metav1.NewTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+					},
+				},
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "holidays", Namespace: "calendars"}},
+			},
+		},
+		&vwav1.ClusterUpdateSchedule{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-calendar-namespace"},
+			Spec: vwav1.UpdateScheduleSpec{
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "holidays"}},
+			},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "holidays", Namespace: "calendars"},
+			Data:       map[string]string{vwav1.DefaultCalendarKey: testCalendar},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		spec              vwav1.VerticalWorkloadAutoscalerSpec
+		expectedWindows   []vwav1.UpdateWindow
+		expectedBlackouts []string
+		expectError       bool
+	}{
+		{
+			name:            "Inline schedule only",
+			spec:            vwav1.VerticalWorkloadAutoscalerSpec{AllowedUpdateWindows: []vwav1.UpdateWindow{inline}},
+			expectedWindows: []vwav1.UpdateWindow{inline},
+		},
+		{
+			name: "Inline schedule merged with referenced schedules",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				AllowedUpdateWindows: []vwav1.UpdateWindow{inline},
+				UpdateSchedules: []vwav1.UpdateScheduleReference{
+					{Name: "team"},
+					{Kind: vwav1.ClusterUpdateScheduleKind, Name: "fleet"},
+				},
+			},
+			expectedWindows:   []vwav1.UpdateWindow{inline, nightly, weekend},
+			expectedBlackouts: []string{"Release freeze", "Quarter-end close"},
+		},
+		{
+			name: "Missing UpdateSchedule",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				UpdateSchedules: []vwav1.UpdateScheduleReference{{Name: "missing"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "Missing ClusterUpdateSchedule",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				UpdateSchedules: []vwav1.UpdateScheduleReference{{Kind: vwav1.ClusterUpdateScheduleKind, Name: "team"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "ClusterUpdateSchedule calendar without a namespace",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				UpdateSchedules: []vwav1.UpdateScheduleReference{{Kind: vwav1.ClusterUpdateScheduleKind, Name: "no-calendar-namespace"}},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Spec:       tt.spec,
+			}
+
+			schedule, err := r.resolveSchedule(context.Background(), wa, time.Date(2023, 12, 30, 0, 0, 0, 0, time.UTC))
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedWindows, schedule.windows)
+			names := make([]string, 0, len(schedule.blackouts))
+			for _, b := range schedule.blackouts {
+				names = append(names, b.name)
+			}
+			if tt.expectedBlackouts == nil {
+				tt.expectedBlackouts = []string{}
+			}
+			assert.Equal(t, tt.expectedBlackouts, names)
+		})
+	}
+}
+
+func TestFindVWAForUpdateSchedule(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+
+	vwas := []client.Object{
+		&vwav1.VerticalWorkloadAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+			Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				UpdateSchedules: []vwav1.UpdateScheduleReference{{Name: "nightly"}},
+			},
+		},
+		&vwav1.VerticalWorkloadAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "vwa2", Namespace: "other"},
+			Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				UpdateSchedules: []vwav1.UpdateScheduleReference{
+					{Name: "nightly"},
+					{Kind: vwav1.ClusterUpdateScheduleKind, Name: "nightly"},
+				},
+			},
+		},
+		&vwav1.VerticalWorkloadAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "vwa3", Namespace: "default"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		schedule client.Object
+		expected []reconcile.Request
+	}{
+		{
+			name:     "UpdateSchedule maps to VWAs in the same namespace",
+			schedule: &vwav1.UpdateSchedule{ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default"}},
+			expected: []reconcile.Request{{NamespacedName: client.ObjectKey{Name: "vwa1", Namespace: "default"}}},
+		},
+		{
+			name:     "ClusterUpdateSchedule maps to VWAs in all namespaces",
+			schedule: &vwav1.ClusterUpdateSchedule{ObjectMeta: metav1.ObjectMeta{Name: "nightly"}},
+			expected: []reconcile.Request{{NamespacedName: client.ObjectKey{Name: "vwa2", Namespace: "other"}}},
+		},
+		{
+			name:     "Unreferenced schedule",
+			schedule: &vwav1.UpdateSchedule{ObjectMeta: metav1.ObjectMeta{Name: "weekly", Namespace: "default"}},
+			expected: []reconcile.Request{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vwas...).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+			assert.Equal(t, tt.expected, r.findVWAForUpdateSchedule(context.Background(), tt.schedule))
+		})
+	}
+}
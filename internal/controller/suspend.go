@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"context"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// suspendReason returns why the updates of the VWA are suspended, or an empty string if they are not
+func suspendReason(wa *vwav1.VerticalWorkloadAutoscaler) string {
+	if wa.Spec.Suspend {
+		return "updates suspended by spec.suspend"
+	}
+	if wa.Annotations[vwav1.SuspendAnnotation] == "true" {
+		return "updates suspended by annotation " + vwav1.SuspendAnnotation
+	}
+	return ""
+}
+
+// updateSuspendStatus sets the Suspended condition of the VWA; the condition is only
+// reset when the VWA was suspended before
+func (r *VerticalWorkloadAutoscalerReconciler) updateSuspendStatus(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) error {
+	condition := findCondition(wa.Status.Conditions, ConditionTypeSuspended)
+	msg := suspendReason(wa)
+	if msg == "" {
+		if condition == nil || condition.Status == metav1.ConditionFalse {
+			return nil
+		}
+		r.recordEvent(wa, "Normal", ReasonResumed, "updates resumed")
+		return r.updateStatusCondition(ctx, wa, ConditionTypeSuspended, metav1.ConditionFalse, ReasonResumed, "updates resumed")
+	}
+
+	if condition != nil && condition.Status == metav1.ConditionTrue && condition.Message == msg {
+		return nil
+	}
+	r.recordEvent(wa, "Normal", ReasonSuspended, msg)
+	return r.updateStatusCondition(ctx, wa, ConditionTypeSuspended, metav1.ConditionTrue, ReasonSuspended, msg)
+}
+
+// handleSuspend records the new recommendations in the VWA status without applying them
+func (r *VerticalWorkloadAutoscalerReconciler) handleSuspend(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, newResources map[string]corev1.ResourceRequirements) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("updates suspended, recording recommendations only", "VWA", wa.Name)
+
+	wa.Status.RecommendedRequests = newResources
+	wa.Status.SkippedUpdates = true
+	wa.Status.SkipReason = suspendReason(wa)
+	if err := r.Status().Update(ctx, wa); err != nil {
+		return r.handleError(ctx, wa, err, "failed to record recommendations", ReasonAPIError, "failed to record recommendations")
+	}
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSuspendReason(t *testing.T) {
+	tests := []struct {
+		name        string
+		suspend     bool
+		annotations map[string]string
+		expected    string
+	}{
+		{
+			name:     "Not suspended",
+			expected: "",
+		},
+		{
+			name:     "Suspended by spec",
+			suspend:  true,
+			expected: "updates suspended by spec.suspend",
+		},
+		{
+			name:        "Suspended by annotation",
+			annotations: map[string]string{vwav1.SuspendAnnotation: "true"},
+			expected:    "updates suspended by annotation " + vwav1.SuspendAnnotation,
+		},
+		{
+			name:        "Annotation not set to true",
+			annotations: map[string]string{vwav1.SuspendAnnotation: "false"},
+			expected:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations},
+				Spec:       vwav1.VerticalWorkloadAutoscalerSpec{Suspend: tt.suspend},
+			}
+			assert.Equal(t, tt.expected, suspendReason(wa))
+		})
+	}
+}
+
+func TestUpdateSuspendStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	wa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(wa).WithObjects(wa).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+
+	// a VWA that was never suspended has no Suspended condition
+	require.NoError(t, r.updateSuspendStatus(context.Background(), wa))
+	assert.Nil(t, findCondition(wa.Status.Conditions, ConditionTypeSuspended))
+
+	wa.Spec.Suspend = true
+	require.NoError(t, c.Update(context.Background(), wa))
+	require.NoError(t, r.updateSuspendStatus(context.Background(), wa))
+	condition := findCondition(wa.Status.Conditions, ConditionTypeSuspended)
+	require.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, ReasonSuspended, condition.Reason)
+	// the event is recorded once
+	require.NoError(t, r.updateSuspendStatus(context.Background(), wa))
+	assert.Len(t, recorder.Events, 1)
+	<-recorder.Events
+
+	wa.Spec.Suspend = false
+	require.NoError(t, c.Update(context.Background(), wa))
+	require.NoError(t, r.updateSuspendStatus(context.Background(), wa))
+	condition = findCondition(wa.Status.Conditions, ConditionTypeSuspended)
+	require.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, ReasonResumed, condition.Reason)
+	require.NoError(t, r.updateSuspendStatus(context.Background(), wa))
+	assert.Len(t, recorder.Events, 1)
+}
+
+func TestHandleVWAChangeSuspendAndForceApply(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	updateModeOff := vpav1.UpdateModeOff
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference: vwav1.VPAReference{Name: "vpa1"},
+			Suspend:      true,
+		},
+	}
+	vpa := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &updateModeOff},
+			TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "web:1.0", Resources: cpuRequests("250m")},
+		}}}},
+	}
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment).Build()
+	recorder := record.NewFakeRecorder(100)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: recorder}
+
+	recommend := func(cpu string) {
+		stored := &vpav1.VerticalPodAutoscaler{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vpa), stored))
+		stored.Status.Recommendation = &vpav1.RecommendedPodResources{ContainerRecommendations: []vpav1.RecommendedContainerResources{
+			{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+		}}
+		require.NoError(t, c.Update(context.Background(), stored))
+	}
+	reconcile := func(advance time.Duration) []string {
+		now = now.Add(advance)
+		_, err := r.handleVWAChange(context.Background(), vwa)
+		require.NoError(t, err)
+		var reasons []string
+		for len(recorder.Events) > 0 {
+			event := <-recorder.Events
+			if reason := strings.Fields(event)[1]; reason == ReasonSuspended || reason == ReasonResumed || reason == ReasonForceApplied {
+				reasons = append(reasons, reason)
+			}
+		}
+		return reasons
+	}
+	cpu := func() string {
+		stored := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+		return stored.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String()
+	}
+
+	// a suspended VWA tracks the recommendations without applying them
+	recommend("500m")
+	assert.Equal(t, []string{ReasonSuspended}, reconcile(10*time.Minute))
+	assert.Equal(t, "250m", cpu())
+	assert.Equal(t, resource.MustParse("500m"), vwa.Status.RecommendedRequests["app"].Requests[corev1.ResourceCPU])
+	assert.True(t, vwa.Status.SkippedUpdates)
+	assert.Equal(t, metav1.ConditionTrue, findCondition(vwa.Status.Conditions, ConditionTypeSuspended).Status)
+
+	// resuming applies the recommendation
+	vwa.Spec.Suspend = false
+	require.NoError(t, c.Update(context.Background(), vwa))
+	assert.Equal(t, []string{ReasonResumed}, reconcile(10*time.Minute))
+	assert.Equal(t, "500m", cpu())
+	assert.Equal(t, metav1.ConditionFalse, findCondition(vwa.Status.Conditions, ConditionTypeSuspended).Status)
+
+	// a newer recommendation waits for the update frequency
+	recommend("600m")
+	assert.Empty(t, reconcile(time.Minute))
+	assert.Equal(t, "500m", cpu())
+
+	// force-apply of a suspended VWA waits for the resume
+	vwa.Annotations = map[string]string{vwav1.ForceApplyAnnotation: "true"}
+	vwa.Spec.Suspend = true
+	require.NoError(t, c.Update(context.Background(), vwa))
+	assert.Equal(t, []string{ReasonSuspended}, reconcile(time.Minute))
+	assert.Equal(t, "500m", cpu())
+	stored := &vwav1.VerticalWorkloadAutoscaler{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vwa), stored))
+	assert.Contains(t, stored.Annotations, vwav1.ForceApplyAnnotation)
+
+	// force-apply bypasses the update frequency once
+	vwa.Spec.Suspend = false
+	require.NoError(t, c.Update(context.Background(), vwa))
+	assert.Equal(t, []string{ReasonResumed, ReasonForceApplied}, reconcile(time.Minute))
+	assert.Equal(t, "600m", cpu())
+	assert.Equal(t, ReasonForceApplied, findCondition(vwa.Status.Conditions, ConditionTypeReconciled).Reason)
+
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vwa), stored))
+	assert.NotContains(t, stored.Annotations, vwav1.ForceApplyAnnotation)
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// templateHashLength is the number of hex digits of a pod template hash
+const templateHashLength = 16
+
+// podTemplate returns the pod template of the target object
+func podTemplate(targetObject client.Object) (*corev1.PodTemplateSpec, error) {
+	switch resource := targetObject.(type) {
+	case *appsv1.Deployment:
+		return &resource.Spec.Template, nil
+	case *appsv1.StatefulSet:
+		return &resource.Spec.Template, nil
+	case *appsv1.DaemonSet:
+		return &resource.Spec.Template, nil
+	case *appsv1.ReplicaSet:
+		return &resource.Spec.Template, nil
+	case *batchv1.Job:
+		return &resource.Spec.Template, nil
+	case *batchv1.CronJob:
+		return &resource.Spec.JobTemplate.Spec.Template, nil
+	default:
+		return nil, fmt.Errorf("unsupported target resource type: %T", targetObject)
+	}
+}
+
+// templateHash returns the hash of the pod template of the target object without the container resources,
+// so the resources applied by the VWA don't change it
+func templateHash(targetObject client.Object) (string, error) {
+	template, err := podTemplate(targetObject)
+	if err != nil {
+		return "", err
+	}
+	template = template.DeepCopy()
+	for i := range template.Spec.Containers {
+		template.Spec.Containers[i].Resources = corev1.ResourceRequirements{}
+	}
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash pod template: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:templateHashLength], nil
+}
+
+// checkUpdateMode checks whether the update mode of the VWA allows updating the target object now. When it doesn't,
+// the new recommendations are recorded in the VWA status without applying them. With the OnRollout update mode, it
+// also returns the pod template hash to record once the rollout is handled.
+func (r *VerticalWorkloadAutoscalerReconciler) checkUpdateMode(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, targetObject client.Object, newResources map[string]corev1.ResourceRequirements) (bool, string, error) {
+	var hash, reason, msg string
+	status := metav1.ConditionFalse
+	switch r.effectiveSpec(wa).UpdateMode {
+	case vwav1.UpdateModeOnce:
+		if wa.Status.LastUpdateMode != vwav1.UpdateModeOnce {
+			return true, "", nil
+		}
+		status, reason, msg = metav1.ConditionTrue, ReasonAppliedOnce, "resources applied once; updates stopped by the Once update mode"
+	case vwav1.UpdateModeOnRollout:
+		var err error
+		if hash, err = templateHash(targetObject); err != nil {
+			return false, "", err
+		}
+		// the first template seen is the current rollout, which has already happened
+		if wa.Status.ObservedTemplateHash != "" && wa.Status.ObservedTemplateHash != hash {
+			return true, hash, nil
+		}
+		wa.Status.ObservedTemplateHash = hash
+		reason, msg = ReasonWaitingForRollout, "waiting for a rollout of the target workload"
+	default:
+		return true, "", nil
+	}
+
+	log.FromContext(ctx).Info("update mode holds updates, recording recommendations only", "VWA", wa.Name, "reason", reason)
+	wa.Status.RecommendedRequests = newResources
+	wa.Status.SkippedUpdates = true
+	wa.Status.SkipReason = msg
+	return false, "", r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, status, reason, msg)
+}
+
+// workloadTemplateChanged checks whether an update of a workload changed its pod template other than the
+// container resources, e.g. with a new rollout
+func workloadTemplateChanged(e event.UpdateEvent) bool {
+	oldHash, err := templateHash(e.ObjectOld)
+	if err != nil {
+		return false
+	}
+	newHash, err := templateHash(e.ObjectNew)
+	if err != nil {
+		return false
+	}
+	return oldHash != newHash
+}
+
+// findVWAForRollout maps a workload rollout to the VWAs with the OnRollout update mode targeting the workload
+func (r *VerticalWorkloadAutoscalerReconciler) findVWAForRollout(ctx context.Context, workload client.Object) []reconcile.Request {
+	requests := make([]reconcile.Request, 0)
+	var vwaList vwav1.VerticalWorkloadAutoscalerList
+	if err := r.List(ctx, &vwaList, client.InNamespace(workload.GetNamespace())); err != nil {
+		log.Log.Error(err, "failed to list VerticalWorkloadAutoscaler objects")
+		return requests
+	}
+	gvk, err := apiutil.GVKForObject(workload, r.Client.Scheme())
+	if err != nil {
+		log.Log.Error(err, "failed to get workload kind", "workload", workload.GetName())
+		return requests
+	}
+	for _, vwa := range vwaList.Items {
+		if vwa.Spec.UpdateMode != vwav1.UpdateModeOnRollout || vwa.Status.ScaleTargetRef.Kind != gvk.Kind || vwa.Status.ScaleTargetRef.Name != workload.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKey{Namespace: vwa.Namespace, Name: vwa.Name},
+		})
+	}
+	return requests
+}
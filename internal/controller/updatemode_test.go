@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTemplateHash(t *testing.T) {
+	deployment := func(image, cpu string) *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: image, Resources: cpuRequests(cpu)},
+		}}}}}
+	}
+
+	tests := []struct {
+		name     string
+		oldObj   client.Object
+		newObj   client.Object
+		expected bool
+	}{
+		{
+			name:     "Resources changed",
+			oldObj:   deployment("web:1.0", "100m"),
+			newObj:   deployment("web:1.0", "200m"),
+			expected: false,
+		},
+		{
+			name:     "Image changed",
+			oldObj:   deployment("web:1.0", "100m"),
+			newObj:   deployment("web:1.1", "100m"),
+			expected: true,
+		},
+		{
+			name:     "Unsupported kind",
+			oldObj:   &corev1.Pod{},
+			newObj:   &corev1.Pod{},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, workloadTemplateChanged(event.UpdateEvent{ObjectOld: tt.oldObj, ObjectNew: tt.newObj}))
+		})
+	}
+
+	hash, err := templateHash(deployment("web:1.0", "100m"))
+	require.NoError(t, err)
+	assert.Len(t, hash, templateHashLength)
+}
+
+func TestFindVWAForRollout(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	vwa := func(name string, mode vwav1.UpdateMode, kind, target string) *vwav1.VerticalWorkloadAutoscaler {
+		return &vwav1.VerticalWorkloadAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       vwav1.VerticalWorkloadAutoscalerSpec{UpdateMode: mode},
+			Status: vwav1.VerticalWorkloadAutoscalerStatus{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: kind, Name: target},
+			},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		vwa("on-rollout", vwav1.UpdateModeOnRollout, "Deployment", "web"),
+		vwa("auto", vwav1.UpdateModeAuto, "Deployment", "web"),
+		vwa("other-kind", vwav1.UpdateModeOnRollout, "StatefulSet", "web"),
+		vwa("other-target", vwav1.UpdateModeOnRollout, "Deployment", "api"),
+	).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c}
+
+	requests := r.findVWAForRollout(context.Background(), &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}})
+	assert.Equal(t, []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: "default", Name: "on-rollout"}}}, requests)
+}
+
+func TestHandleVWAChangeUpdateModes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+
+	updateModeOff := vpav1.UpdateModeOff
+	setup := func(t *testing.T, mode vwav1.UpdateMode) (reconcileCPU func(cpu string) string, rollout func(), forceApply func(cpu string) (string, bool)) {
+		now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+		timeNow = func() time.Time { return now }
+		t.Cleanup(func() { timeNow = time.Now })
+
+		vwa := &vwav1.VerticalWorkloadAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+			Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference: vwav1.VPAReference{Name: "vpa1"},
+				UpdateMode:   mode,
+			},
+		}
+		vpa := &vpav1.VerticalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+			Spec: vpav1.VerticalPodAutoscalerSpec{
+				UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &updateModeOff},
+				TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			},
+		}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app", Image: "web:1.0", Resources: cpuRequests("250m")},
+			}}}},
+		}
+		c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+			WithObjects(vwa, vpa, deployment).Build()
+		r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(100)}
+
+		// reconcileCPU recommends the CPU and returns the CPU requests of the deployment
+		reconcileCPU = func(cpu string) string {
+			stored := &vpav1.VerticalPodAutoscaler{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vpa), stored))
+			stored.Status.Recommendation = &vpav1.RecommendedPodResources{ContainerRecommendations: []vpav1.RecommendedContainerResources{
+				{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+			}}
+			require.NoError(t, c.Update(context.Background(), stored))
+
+			now = now.Add(10 * time.Minute)
+			_, err := r.handleVWAChange(context.Background(), vwa)
+			require.NoError(t, err)
+			updated := &appsv1.Deployment{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), updated))
+			return updated.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String()
+		}
+		rollout = func() {
+			updated := &appsv1.Deployment{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), updated))
+			updated.Spec.Template.Spec.Containers[0].Image = "web:1.1"
+			require.NoError(t, c.Update(context.Background(), updated))
+		}
+		// forceApply reconciles the CPU with the force-apply annotation and returns whether the annotation is kept
+		forceApply = func(cpu string) (string, bool) {
+			vwa.Annotations = map[string]string{vwav1.ForceApplyAnnotation: "true"}
+			require.NoError(t, c.Update(context.Background(), vwa))
+			requests := reconcileCPU(cpu)
+			stored := &vwav1.VerticalWorkloadAutoscaler{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vwa), stored))
+			_, kept := stored.Annotations[vwav1.ForceApplyAnnotation]
+			return requests, kept
+		}
+		t.Cleanup(func() {
+			stored := &vwav1.VerticalWorkloadAutoscaler{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vwa), stored))
+			assert.Equal(t, mode, stored.Status.LastUpdateMode)
+		})
+		return reconcileCPU, rollout, forceApply
+	}
+
+	t.Run("Once", func(t *testing.T) {
+		reconcileCPU, rollout, forceApply := setup(t, vwav1.UpdateModeOnce)
+		assert.Equal(t, "500m", reconcileCPU("500m"))
+		// later recommendations and rollouts are ignored
+		assert.Equal(t, "500m", reconcileCPU("600m"))
+		rollout()
+		assert.Equal(t, "500m", reconcileCPU("700m"))
+		// the update mode holds and consumes force-apply
+		requests, kept := forceApply("800m")
+		assert.Equal(t, "500m", requests)
+		assert.False(t, kept)
+	})
+
+	t.Run("OnRollout", func(t *testing.T) {
+		reconcileCPU, rollout, forceApply := setup(t, vwav1.UpdateModeOnRollout)
+		// the current template has already been rolled out
+		assert.Equal(t, "250m", reconcileCPU("500m"))
+		assert.Equal(t, "250m", reconcileCPU("600m"))
+		// the update mode holds and consumes force-apply
+		requests, kept := forceApply("650m")
+		assert.Equal(t, "250m", requests)
+		assert.False(t, kept)
+		// the next rollout picks up the latest recommendation, once
+		rollout()
+		assert.Equal(t, "700m", reconcileCPU("700m"))
+		assert.Equal(t, "700m", reconcileCPU("800m"))
+	})
+}
@@ -23,10 +23,16 @@ import (
 	"time"
 
 	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/alexei-led/vertical-workload-autoscaler/internal/gitwriteback"
+	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -44,18 +50,39 @@ type VerticalWorkloadAutoscalerReconciler struct {
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
 	Timeout  time.Duration
+	// Defaults are applied to unset VWA spec fields; the built-in defaults are used if nil
+	Defaults *vwav1.SpecDefaults
+	// GitWriter commits the resources of VWAs in Git write-back mode
+	GitWriter gitwriteback.Writer
+	// APIReader reads uncached objects, like Git credentials Secrets; the client is used if nil
+	APIReader client.Reader
+}
+
+// uncachedReader returns the reader for objects the controller doesn't cache
+func (r *VerticalWorkloadAutoscalerReconciler) uncachedReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
 }
 
 // +kubebuilder:rbac:groups=autoscaling.workload.io,resources=verticalworkloadautoscalers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=autoscaling.workload.io,resources=verticalworkloadautoscalers/finalizers,verbs=update
 // +kubebuilder:rbac:groups=autoscaling.workload.io,resources=verticalworkloadautoscalers/status,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=autoscaling.workload.io,resources=updateschedules;clusterupdateschedules,verbs=get;list;watch
+// +kubebuilder:rbac:groups=autoscaling.workload.io,resources=changefreezes,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch;update
-// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers/status,verbs=get;list;watch
 // +kubebuilder:rbac:groups=autoscaling.k8s.io,resources=verticalpodautoscalers,verbs=get;list;watch
 // +kubebuilder:rbac:groups=autoscaling.k8s.io,resources=verticalpodautoscalers/status,verbs=get;list;watch
-// +kubebuilder:rbac:groups=apps,resources=deployments;replicasets;statefulsets;daemonsets,verbs=get;list;watch;update
-// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=apps,resources=deployments;replicasets;statefulsets;daemonsets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=keda.sh,resources=scaledobjects;scaledjobs,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -70,9 +97,19 @@ func (r *VerticalWorkloadAutoscalerReconciler) Reconcile(ctx context.Context, re
 	}
 	if vwa == nil {
 		logger.Info("VerticalWorkloadAutoscaler not found, skipping", "namespacedName", req.NamespacedName)
+		vwaFrozen.DeletePartialMatch(map[string]string{"namespace": req.Namespace, "name": req.Name})
 		return ctrl.Result{}, nil
 	}
 
+	// Clean up the target object of a deleted VWA
+	if !vwa.DeletionTimestamp.IsZero() {
+		return r.handleVWADeletion(ctx, vwa)
+	}
+	if err := r.ensureFinalizer(ctx, vwa); err != nil {
+		logger.Error(err, "failed to add finalizer", "namespacedName", req.NamespacedName)
+		return ctrl.Result{}, err
+	}
+
 	// Handle the VWA reconciliation
 	return r.handleVWAChange(ctx, vwa)
 }
@@ -93,7 +130,7 @@ func (r *VerticalWorkloadAutoscalerReconciler) getVWA(ctx context.Context, names
 func (r *VerticalWorkloadAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager, timeout time.Duration) error {
 	r.Recorder = mgr.GetEventRecorderFor("vwa-controller-manager")
 	r.Timeout = timeout
-	if err := ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&vwav1.VerticalWorkloadAutoscaler{}, builder.WithPredicates(predicate.Funcs{
 			UpdateFunc: func(e event.UpdateEvent) bool {
 				oldVWA, okOld := e.ObjectOld.(*vwav1.VerticalWorkloadAutoscaler)
@@ -101,8 +138,9 @@ func (r *VerticalWorkloadAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager
 				if !okOld || !okNew {
 					return false
 				}
-				// Trigger only if VWA spec changed, ignore status updates
-				return !reflect.DeepEqual(oldVWA.Spec, newVWA.Spec)
+				// Trigger only if VWA spec changed, the VWA is being deleted or a control annotation changed, ignore status updates
+				return !reflect.DeepEqual(oldVWA.Spec, newVWA.Spec) || oldVWA.DeletionTimestamp.IsZero() != newVWA.DeletionTimestamp.IsZero() ||
+					controlAnnotationsChanged(oldVWA, newVWA)
 			},
 			CreateFunc:  func(e event.CreateEvent) bool { return true },   // Trigger on create
 			DeleteFunc:  func(e event.DeleteEvent) bool { return false },  // Ignore delete
@@ -118,13 +156,33 @@ func (r *VerticalWorkloadAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager
 					if !okOld || !okNew {
 						return false
 					}
-					// Trigger only on updates to the status recommendation field
-					return !reflect.DeepEqual(oldVPA.Status.Recommendation, newVPA.Status.Recommendation)
+					// Trigger only on updates to the status recommendation field or the update policy
+					return !reflect.DeepEqual(oldVPA.Status.Recommendation, newVPA.Status.Recommendation) ||
+						!reflect.DeepEqual(oldVPA.Spec.UpdatePolicy, newVPA.Spec.UpdatePolicy)
 				},
 				CreateFunc:  func(e event.CreateEvent) bool { return false },  // Ignore create
 				DeleteFunc:  func(e event.DeleteEvent) bool { return false },  // Ignore delete
 				GenericFunc: func(e event.GenericEvent) bool { return false }, // Ignore generic
 			})).
+		// Map shared update schedule changes to the VWAs referencing them
+		Watches(
+			&vwav1.UpdateSchedule{},
+			handler.EnqueueRequestsFromMapFunc(r.findVWAForUpdateSchedule),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&vwav1.ClusterUpdateSchedule{},
+			handler.EnqueueRequestsFromMapFunc(r.findVWAForUpdateSchedule),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// Map change freezes to the VWAs they select
+		Watches(
+			&vwav1.ChangeFreeze{},
+			handler.EnqueueRequestsFromMapFunc(r.findVWAForChangeFreeze),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc:  func(e event.UpdateEvent) bool { return true },   // Trigger on updates
+				CreateFunc:  func(e event.CreateEvent) bool { return true },   // Trigger on create
+				DeleteFunc:  changeFreezeDeleted,                              // Trigger on delete
+				GenericFunc: func(e event.GenericEvent) bool { return false }, // Ignore generic
+			})).
 		// Map HPA updates to VWA reconciliation
 		Watches(
 			&autoscalingv2.HorizontalPodAutoscaler{},
@@ -134,19 +192,72 @@ func (r *VerticalWorkloadAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager
 				CreateFunc:  func(e event.CreateEvent) bool { return true },   // Trigger on create
 				DeleteFunc:  func(e event.DeleteEvent) bool { return true },   // Trigger on delete
 				GenericFunc: func(e event.GenericEvent) bool { return false }, // Ignore generic
-			})).
-		Complete(r); err != nil {
+			}))
+	// Map resources changes of managed workloads to their VWA to detect drift
+	for _, workload := range []client.Object{
+		&appsv1.Deployment{}, &appsv1.StatefulSet{}, &appsv1.DaemonSet{}, &appsv1.ReplicaSet{}, &batchv1.Job{}, &batchv1.CronJob{},
+	} {
+		b = b.Watches(workload, handler.EnqueueRequestsFromMapFunc(r.findVWAForWorkload),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc:  r.workloadResourcesChanged,                       // Trigger on resources changes
+				CreateFunc:  func(e event.CreateEvent) bool { return false },  // Ignore create
+				DeleteFunc:  func(e event.DeleteEvent) bool { return false },  // Ignore delete
+				GenericFunc: func(e event.GenericEvent) bool { return false }, // Ignore generic
+			}))
+		// Map workload rollouts to the VWAs waiting for them with the OnRollout update mode
+		b = b.Watches(workload, handler.EnqueueRequestsFromMapFunc(r.findVWAForRollout),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc:  workloadTemplateChanged,                          // Trigger on pod template changes
+				CreateFunc:  func(e event.CreateEvent) bool { return false },  // Ignore create
+				DeleteFunc:  func(e event.DeleteEvent) bool { return false },  // Ignore delete
+				GenericFunc: func(e event.GenericEvent) bool { return false }, // Ignore generic
+			}))
+	}
+	// Map KEDA ScaledObject and ScaledJob changes to VWA reconciliation when KEDA is installed
+	for _, gvk := range []schema.GroupVersionKind{scaledObjectGVK, scaledJobGVK} {
+		installed, err := kedaInstalled(mgr.GetRESTMapper(), gvk)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			log.Log.Info("KEDA kind not installed, not watching it", "kind", gvk.Kind)
+			continue
+		}
+		scaler := &unstructured.Unstructured{}
+		scaler.SetGroupVersionKind(gvk)
+		b = b.Watches(scaler, handler.EnqueueRequestsFromMapFunc(r.findVWAForKEDAScaler),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc:  func(e event.UpdateEvent) bool { return true },   // Trigger on updates
+				CreateFunc:  func(e event.CreateEvent) bool { return true },   // Trigger on create
+				DeleteFunc:  func(e event.DeleteEvent) bool { return true },   // Trigger on delete
+				GenericFunc: func(e event.GenericEvent) bool { return false }, // Ignore generic
+			}))
+	}
+	if err := b.Complete(r); err != nil {
 		log.Log.Error(err, "failed to setup controller with manager")
 		return err
 	}
 	return nil
 }
 
-// checks if there is any other VWA referencing the same VPA
+// controlAnnotations are the VWA annotations that control its updates
+var controlAnnotations = []string{vwav1.ApproveAnnotation, vwav1.SuspendAnnotation, vwav1.ForceApplyAnnotation}
+
+// controlAnnotationsChanged checks if any control annotation of the VWA changed
+func controlAnnotationsChanged(oldVWA, newVWA *vwav1.VerticalWorkloadAutoscaler) bool {
+	for _, key := range controlAnnotations {
+		if oldVWA.Annotations[key] != newVWA.Annotations[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// checks if there is any other VWA in the namespace referencing the same VPA
 func (r *VerticalWorkloadAutoscalerReconciler) ensureNoDuplicateVWA(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) error {
-	// List all VerticalWorkloadAutoscaler objects
+	// List all VerticalWorkloadAutoscaler objects in the VWA namespace
 	var waList vwav1.VerticalWorkloadAutoscalerList
-	if err := r.List(ctx, &waList); err != nil {
+	if err := r.List(ctx, &waList, client.InNamespace(wa.Namespace)); err != nil {
 		return err
 	}
 
@@ -171,11 +282,43 @@ func (r *VerticalWorkloadAutoscalerReconciler) handleVWAChange(ctx context.Conte
 		return r.handleError(ctx, wa, err, "duplicate VWA found", ReasonVPAReferenceConflict, fmt.Sprintf("VPA '%s' is already referenced by another VWA object", wa.Spec.VPAReference.Name))
 	}
 
-	// Check if an update is allowed now or should be delayed
-	if delay, shouldDelay := r.shouldDelayUpdate(*wa); shouldDelay {
-		logger.Info("delaying update", "RequeueAfter", delay)
-		r.recordEvent(wa, "Normal", "UpdateDelayed", fmt.Sprintf("update delayed for %s", delay))
-		return ctrl.Result{RequeueAfter: delay}, nil
+	// Check whether a cluster-wide change freeze holds this VWA
+	freeze, err := r.findActiveChangeFreeze(ctx, wa)
+	if err != nil {
+		return r.handleError(ctx, wa, err, "failed to check change freezes", ReasonAPIError, "failed to check change freezes")
+	}
+	if err := r.updateFreezeStatus(ctx, wa, freeze); err != nil {
+		return r.handleError(ctx, wa, err, "failed to update freeze status", ReasonAPIError, "failed to update freeze status")
+	}
+	if err := r.updateSuspendStatus(ctx, wa); err != nil {
+		return r.handleError(ctx, wa, err, "failed to update suspend status", ReasonAPIError, "failed to update suspend status")
+	}
+
+	// Resolve the effective update schedule: inline and shared update windows, blackout windows and calendars
+	schedule, err := r.resolveSchedule(ctx, wa, timeNow())
+	if err != nil {
+		return r.handleError(ctx, wa, err, "failed to resolve update schedule", ReasonInvalidSchedule, err.Error())
+	}
+	if err := r.updateCalendarWarning(ctx, wa, schedule.skippedEvents); err != nil {
+		return r.handleError(ctx, wa, err, "failed to update calendar warning", ReasonAPIError, "failed to update calendar warning")
+	}
+	blackout := activeBlackout(timeNow(), schedule.blackouts)
+	if err := r.updateBlackoutStatus(ctx, wa, blackout); err != nil {
+		return r.handleError(ctx, wa, err, "failed to update blackout status", ReasonAPIError, "failed to update blackout status")
+	}
+
+	// A rollback to an earlier revision pauses the automatic updates; it waits for freezes, suspensions and blackouts
+	if wa.Spec.RollbackTo != nil {
+		if hold, delay := rollbackHold(wa, freeze, blackout); hold != "" {
+			return r.handleRollbackPending(ctx, wa, hold, delay)
+		}
+		return r.handleRollback(ctx, wa)
+	}
+
+	// The force-apply annotation bypasses the update windows and frequency once; blackouts still apply
+	forceApply := wa.Annotations[vwav1.ForceApplyAnnotation] == "true"
+	if forceApply {
+		schedule.windows = nil
 	}
 
 	// Fetch the associated VPA object
@@ -191,6 +334,11 @@ func (r *VerticalWorkloadAutoscalerReconciler) handleVWAChange(ctx context.Conte
 	r.updateStatusCondition(ctx, wa, ConditionTypeReady, metav1.ConditionTrue, ReasonVPAFound, "VPA found") // nolint:errcheck
 	r.recordEvent(wa, "Normal", "VPAFound", fmt.Sprintf("VPA '%s' found", vpa.Name))
 
+	// Record the conflicts with other controllers scaling the target workload
+	if err := r.updateConflicts(ctx, wa, vpa); err != nil {
+		return r.handleError(ctx, wa, err, "failed to detect conflicts", ReasonAPIError, "failed to detect conflicts")
+	}
+
 	// if VPA has no recommendations, nothing to do
 	if vpa.Status.Recommendation == nil {
 		return r.handleNoRecommendations(ctx, wa, vpa)
@@ -201,6 +349,11 @@ func (r *VerticalWorkloadAutoscalerReconciler) handleVWAChange(ctx context.Conte
 		return r.handleUpdateModeNotOff(ctx, wa, vpa)
 	}
 
+	// The VWA may pin its target workload (spec.targetRef of the v1beta1 API)
+	if err := ensureTargetRef(wa, vpa); err != nil {
+		return r.handleError(ctx, wa, err, "VPA target doesn't match the VWA targetRef", ReasonTargetMismatch, err.Error())
+	}
+
 	// Fetch the target object from the VPA configuration
 	targetObject, err := r.fetchTargetObject(ctx, vpa)
 	if err != nil {
@@ -215,27 +368,134 @@ func (r *VerticalWorkloadAutoscalerReconciler) handleVWAChange(ctx context.Conte
 		return r.handleError(ctx, wa, err, "failed to update VWA status with new ScaleTargetRef", ReasonAPIError, "failed to update VWA status with new ScaleTargetRef")
 	}
 
+	// Report the GitOps object managing the target workload
+	if err := r.updateGitOpsOwner(ctx, wa, targetObject); err != nil {
+		return r.handleError(ctx, wa, err, "failed to update VWA status with GitOps owner", ReasonAPIError, "failed to update VWA status with GitOps owner")
+	}
+
+	// With the Admission apply method, the pods run with the resources set by the pod mutating webhook
+	admission := r.effectiveSpec(wa).ApplyMethod == vwav1.ApplyMethodAdmission
+	if admission {
+		if targetObject, err = admissionTarget(targetObject, wa.Status.AppliedResources); err != nil {
+			return r.handleError(ctx, wa, err, "failed to fetch current resources", ReasonAPIError, "failed to fetch current resources")
+		}
+	}
+
 	// fetch current resources of the target object
 	currentResources, err := r.fetchCurrentResources(targetObject)
 	if err != nil {
 		return r.handleError(ctx, wa, err, "failed to fetch current resources", ReasonAPIError, "failed to fetch current resources")
 	}
 
-	// find the HPA associated with the target object
-	ignoreCPU, ignoreMemory, err := r.getIgnoreFlagsForHPA(ctx, wa)
+	if wa.Spec.GitWriteback != nil {
+		// In Git write-back mode the GitOps tool applies the committed resources
+		if err := r.updateWritebackObserved(ctx, wa, currentResources); err != nil {
+			return r.handleError(ctx, wa, err, "failed to update Git write-back status", ReasonAPIError, "failed to update Git write-back status")
+		}
+	}
+	if r.detectsDrift(wa) {
+		// Stop re-applying resources that another field manager keeps reverting
+		driftDelay, err := r.handleDrift(ctx, wa, targetObject, currentResources)
+		if err != nil {
+			return r.handleError(ctx, wa, err, "failed to update drift status", ReasonAPIError, "failed to update drift status")
+		}
+		if driftDelay > 0 {
+			logger.Info("not re-applying reverted resources", "RequeueAfter", driftDelay)
+			return ctrl.Result{RequeueAfter: driftDelay}, nil
+		}
+	} else if err := r.clearDrift(ctx, wa); err != nil {
+		return r.handleError(ctx, wa, err, "failed to update drift status", ReasonAPIError, "failed to update drift status")
+	}
+
+	// Track the HPA running at its maxReplicas to allow vertical CPU increases
+	saturationDelay, err := r.updateHPASaturation(ctx, wa)
 	if err != nil {
-		return r.handleError(ctx, wa, err, "failed to find HPA", ReasonAPIError, "failed to find HPA")
+		return r.handleError(ctx, wa, err, "failed to update HPA saturation", ReasonAPIError, "failed to update HPA saturation")
 	}
 
-	// requeue if ignore flags were updated
-	if wa.Spec.IgnoreCPURecommendations != ignoreCPU || wa.Spec.IgnoreMemoryRecommendations != ignoreMemory {
-		r.recordEvent(wa, "Normal", "IgnoreFlagsUpdated", "ignore flags updated")
-		r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionTrue, ReasonUpdatedResources, "updated ignore flags") //nolint:errcheck
-		return ctrl.Result{Requeue: true}, nil
+	// ignore the recommendations of the resources the HPA scales the target object on
+	ignore, err := r.effectiveIgnore(ctx, wa)
+	if err != nil {
+		return r.handleError(ctx, wa, err, "failed to find HPA", ReasonAPIError, "failed to find HPA")
+	}
+	if !equality.Semantic.DeepEqual(ignore, wa.Status.EffectiveIgnore) {
+		wa.Status.EffectiveIgnore = ignore
+		if err := r.Status().Update(ctx, wa); err != nil {
+			return r.handleError(ctx, wa, err, "failed to update effective ignore flags", ReasonAPIError, "failed to update effective ignore flags")
+		}
+		r.recordEvent(wa, "Normal", "IgnoreFlagsUpdated", fmt.Sprintf("ignoring CPU recommendations: %t, memory recommendations: %t", ignore != nil && ignore.CPU, ignore != nil && ignore.Memory))
 	}
 
 	// Calculate new resource values based on VPA recommendations and VWA configuration
 	newResources := r.calculateNewResources(wa, currentResources, vpa.Status.Recommendation)
+	if wa.Status.HPASaturation != nil && wa.Status.HPASaturation.ScaleUp {
+		newResources = limitSaturationScaleUp(currentResources, newResources, r.effectiveSpec(wa).HPAPolicy.SaturationScaleUp.MaxCPU)
+	}
+
+	// While a change freeze is active, record the recommendations without applying them
+	if freeze != nil {
+		return r.handleChangeFreeze(ctx, wa, freeze, newResources)
+	}
+
+	// While suspended, record the recommendations without applying them
+	if suspendReason(wa) != "" {
+		return r.handleSuspend(ctx, wa, newResources)
+	}
+
+	// Check if an update is allowed now or should be delayed; reverts, conflicts, the HPA saturation and the
+	// recommendations held by a change freeze or suspension are recorded above even outside the update windows
+	var delay time.Duration
+	var shouldDelay bool
+	if forceApply {
+		delay, shouldDelay = r.shouldDelayUpdateBlackout(schedule.blackouts)
+	} else {
+		delay, shouldDelay = r.shouldDelayUpdate(*wa, schedule)
+	}
+	if shouldDelay {
+		logger.Info("delaying update", "RequeueAfter", delay)
+		r.recordEvent(wa, "Normal", "UpdateDelayed", fmt.Sprintf("update delayed for %s", delay))
+		if saturationDelay > 0 {
+			delay = min(delay, saturationDelay)
+		}
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// The Once and OnRollout update modes hold updates after the first one or until the next rollout
+	allowed, rolloutHash, err := r.checkUpdateMode(ctx, wa, targetObject, newResources)
+	if err != nil {
+		return r.handleError(ctx, wa, err, "failed to check update mode", ReasonAPIError, "failed to check update mode")
+	}
+	if !allowed {
+		if forceApply {
+			if err := r.consumeForceApply(ctx, wa, "the update is held by the update mode"); err != nil {
+				return r.handleError(ctx, wa, err, "failed to remove force-apply annotation", ReasonAPIError, "failed to remove force-apply annotation")
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Defer increases or decreases that are outside their update windows
+	increaseDelay, decreaseDelay := r.shouldDelayUpdateDirection(schedule.windows)
+	newResources, deferred := deferBlockedChanges(currentResources, newResources, increaseDelay > 0, decreaseDelay > 0)
+
+	// With manual approval, propose the change and apply it once approved
+	if r.effectiveSpec(wa).Approval == vwav1.ApprovalManual {
+		approved, err := r.handleApproval(ctx, wa, targetObject, newResources, vpa)
+		if err != nil {
+			return r.handleError(ctx, wa, err, "failed to propose resource change", ReasonAPIError, "failed to propose resource change")
+		}
+		if !approved {
+			if forceApply {
+				if err := r.consumeForceApply(ctx, wa, "the update waits for manual approval"); err != nil {
+					return r.handleError(ctx, wa, err, "failed to remove force-apply annotation", ReasonAPIError, "failed to remove force-apply annotation")
+				}
+			}
+			if deferred {
+				return ctrl.Result{RequeueAfter: max(increaseDelay, decreaseDelay)}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+	}
 
 	// Update the target resource
 	updated, err := r.updateTargetObject(ctx, targetObject, wa, newResources, vpa.Spec.UpdatePolicy)
@@ -243,17 +503,83 @@ func (r *VerticalWorkloadAutoscalerReconciler) handleVWAChange(ctx context.Conte
 		return r.handleError(ctx, wa, err, "failed to update target resource", ReasonAPIError, "failed to update target resource")
 	}
 
+	// The force-apply annotation is kept until the resources are applied, e.g. through a blackout or suspension;
+	// the update mode and manual approval consume it above
+	if forceApply {
+		if err := r.removeAnnotation(ctx, wa, vwav1.ForceApplyAnnotation); err != nil {
+			return r.handleError(ctx, wa, err, "failed to remove force-apply annotation", ReasonAPIError, "failed to remove force-apply annotation")
+		}
+	}
+
+	// The rollout is handled once all its changes are applied
+	if rolloutHash != "" && !deferred {
+		wa.Status.ObservedTemplateHash = rolloutHash
+	}
+
+	// Report the pods not running the resources set by the pod mutating webhook
+	if admission {
+		if wa.Status.OutdatedPods, err = r.countOutdatedPods(ctx, wa, targetObject); err != nil {
+			return r.handleError(ctx, wa, err, "failed to count outdated pods", ReasonAPIError, "failed to count outdated pods")
+		}
+	}
+
+	// Scale the utilization targets of a coordinated HPA with the requests on every reconcile, so the HPA follows
+	// them even after a failed patch
+	if wa.Spec.GitWriteback == nil {
+		applied := newResources
+		if !updated {
+			applied = nil
+		}
+		coordinated, err := r.coordinateHPA(ctx, wa, currentResources, applied)
+		if err != nil {
+			return r.handleError(ctx, wa, err, "failed to coordinate HPA targets", ReasonAPIError, "failed to coordinate HPA targets")
+		}
+		if coordinated && !updated {
+			if err := r.Status().Update(ctx, wa); err != nil {
+				return r.handleError(ctx, wa, err, "failed to update HPA coordination status", ReasonAPIError, "failed to update HPA coordination status")
+			}
+		}
+	}
+
 	if updated {
+		wa.Status.LastUpdateMode = r.effectiveSpec(wa).UpdateMode
+		applied := wa.Status.AppliedResources
+		if wa.Spec.GitWriteback != nil {
+			applied = wa.Status.GitWriteback.Resources
+		}
+		r.recordRevision(wa, vwav1.RevisionReasonRecommendation, "", currentResources, applied, vpa.Status.Recommendation)
 		if err := r.updateStatus(ctx, wa, newResources); err != nil {
 			return r.handleError(ctx, wa, err, "failed to update VerticalWorkloadAutoscaler status", ReasonAPIError, "failed to update VerticalWorkloadAutoscaler status")
 		}
-		r.recordEvent(wa, "Normal", "ResourcesUpdated", "resources updated")
-		r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionTrue, ReasonUpdatedResources, "updated resources") //nolint:errcheck
-	} else {
+		if wa.Spec.GitWriteback != nil {
+			r.updateWaitingForSync(ctx, wa)
+		} else if forceApply {
+			r.recordEvent(wa, "Normal", ReasonForceApplied, "resources force-applied, bypassing update windows and frequency")
+			r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionTrue, ReasonForceApplied, "force-applied resources") //nolint:errcheck
+		} else {
+			r.recordEvent(wa, "Normal", "ResourcesUpdated", "resources updated")
+			r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionTrue, ReasonUpdatedResources, "updated resources") //nolint:errcheck
+		}
+	} else if wa.Spec.GitWriteback != nil && wa.Status.GitWriteback != nil && !wa.Status.GitWriteback.Observed {
+		r.updateWaitingForSync(ctx, wa)
+	} else if !deferred {
 		r.recordEvent(wa, "Normal", "WaitingForRecommendations", "waiting for VPA recommendations")
 		r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionFalse, ReasonWaitingForRecommendations, "waiting for VPA recommendations") //nolint:errcheck
 	}
-	return ctrl.Result{}, nil
+
+	// Requeue deferred changes when their update window opens
+	if deferred {
+		delay := max(increaseDelay, decreaseDelay)
+		msg := fmt.Sprintf("resource changes outside their update window deferred for %s", delay)
+		logger.Info("deferring resource changes", "RequeueAfter", delay)
+		r.recordEvent(wa, "Normal", ReasonChangesDeferred, msg)
+		if !updated {
+			r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionFalse, ReasonChangesDeferred, msg) //nolint:errcheck
+		}
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+	// Requeue a saturated HPA when the CPU increases are allowed
+	return ctrl.Result{RequeueAfter: saturationDelay}, nil
 }
 
 // nolint:unparam
@@ -296,6 +622,9 @@ func (r *VerticalWorkloadAutoscalerReconciler) updateScaleTargetRef(ctx context.
 			Name:       vpa.Spec.TargetRef.Name,
 			APIVersion: vpa.Spec.TargetRef.APIVersion,
 		}
+		// the original resources and the observed pod template belong to the previous target
+		wa.Status.OriginalResources = nil
+		wa.Status.ObservedTemplateHash = ""
 		if err := r.Status().Update(ctx, wa); err != nil {
 			return err
 		}
@@ -304,17 +633,3 @@ func (r *VerticalWorkloadAutoscalerReconciler) updateScaleTargetRef(ctx context.
 	}
 	return nil
 }
-
-func (r *VerticalWorkloadAutoscalerReconciler) getIgnoreFlagsForHPA(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) (bool, bool, error) {
-	hpa, err := r.findHPAForVWA(ctx, wa)
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			return false, false, err
-		}
-	}
-	if hpa != nil {
-		ignoreCPU, ignoreMemory := r.getIgnoreFlags(hpa)
-		return ignoreCPU, ignoreMemory, nil
-	}
-	return false, false, nil
-}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
@@ -59,6 +60,20 @@ func TestEnsureNoDuplicateVWA(t *testing.T) {
 			},
 			expected: fmt.Errorf("VPA 'vpa1' is already referenced by another VWA object 'vwa2'"),
 		},
+		{
+			name: "Same VPA name in another namespace",
+			vwa: vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Spec:       vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "vpa1"}},
+			},
+			vwaList: []vwav1.VerticalWorkloadAutoscaler{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "vwa2", Namespace: "other"},
+					Spec:       vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "vpa1"}},
+				},
+			},
+			expected: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -67,7 +82,7 @@ func TestEnsureNoDuplicateVWA(t *testing.T) {
 			for _, vwa := range tt.vwaList {
 				objs = append(objs, &vwa)
 			}
-			client := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).WithObjects(objs...).Build()
+			client := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).WithObjects(objs...).Build()
 			r := &VerticalWorkloadAutoscalerReconciler{Client: client}
 
 			err := r.ensureNoDuplicateVWA(context.Background(), &tt.vwa)
@@ -355,7 +370,7 @@ func TestHandleVWAChange(t *testing.T) {
 			if tt.deployment != nil {
 				objs = append(objs, tt.deployment)
 			}
-			client := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).WithObjects(objs...).Build()
+			client := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).WithObjects(objs...).Build()
 			r := &VerticalWorkloadAutoscalerReconciler{Client: client}
 
 			result, err := r.handleVWAChange(context.Background(), &tt.vwa) // Pass by reference
@@ -378,3 +393,79 @@ func TestHandleVWAChange(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleVWAChangeDefersDecreases(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	_ = autoscalingv2.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	// Tuesday 10:00 UTC, decreases are allowed at night only
+	timeNow = func() time.Time { return time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC) }
+
+	updateModeOff := vpav1.UpdateModeOff
+	vwa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference: vwav1.VPAReference{Name: "vpa1"},
+			AllowedUpdateWindows: []vwav1.UpdateWindow{
+				{DayOfWeek: "Tuesday", StartTime: "22:00", EndTime: "23:59", TimeZone: "UTC", Direction: vwav1.UpdateDirectionDecrease},
+			},
+		},
+	}
+	vpa := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &updateModeOff},
+			TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "deployment1"},
+		},
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Recommendation: &vpav1.RecommendedPodResources{
+				ContainerRecommendations: []vpav1.RecommendedContainerResources{
+					{
+						ContainerName: "container1",
+						Target: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("500m"),
+							corev1.ResourceMemory: resource.MustParse("128Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployment1", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{
+					Name: "container1",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("250m"),
+							corev1.ResourceMemory: resource.MustParse("512Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("250m"),
+							corev1.ResourceMemory: resource.MustParse("512Mi"),
+						},
+					},
+				}}},
+			},
+		},
+	}
+
+	client := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).WithObjects(vwa, vpa, deployment).Build()
+	r := &VerticalWorkloadAutoscalerReconciler{Client: client}
+
+	result, err := r.handleVWAChange(context.Background(), vwa)
+	assert.NoError(t, err)
+	assert.Equal(t, 12*time.Hour, result.RequeueAfter)
+
+	updatedDeployment := &appsv1.Deployment{}
+	assert.NoError(t, client.Get(context.Background(), types.NamespacedName{Name: "deployment1", Namespace: "default"}, updatedDeployment))
+	resources := updatedDeployment.Spec.Template.Spec.Containers[0].Resources
+	// CPU increase is applied now, memory decrease waits for its window
+	assert.Equal(t, resource.MustParse("500m"), resources.Requests[corev1.ResourceCPU])
+	assert.Equal(t, resource.MustParse("512Mi"), resources.Requests[corev1.ResourceMemory])
+	assert.Equal(t, resource.MustParse("512Mi"), resources.Limits[corev1.ResourceMemory])
+}
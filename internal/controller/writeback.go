@@ -0,0 +1,233 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/alexei-led/vertical-workload-autoscaler/internal/gitwriteback"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultValuesKey is the Helm values key of the container resources if spec.gitWriteback.valuesKey is empty
+const defaultValuesKey = "resources"
+
+// writebackPathData are the fields of the spec.gitWriteback.path template
+type writebackPathData struct {
+	Namespace string
+	Kind      string
+	Name      string
+	VWA       string
+}
+
+// valuesKeyData are the fields of the spec.gitWriteback.valuesKey template
+type valuesKeyData struct {
+	Container string
+}
+
+// writeBackResources commits the resources of the managed containers to the Git repository of the VWA
+// instead of updating the target object, and records the commit in the VWA status. It returns false
+// if the same resources are already committed and wait for the GitOps tool to sync them.
+func (r *VerticalWorkloadAutoscalerReconciler) writeBackResources(ctx context.Context, targetObject client.Object, vwa *vwav1.VerticalWorkloadAutoscaler, resources map[string]corev1.ResourceRequirements) (bool, error) {
+	writeback := vwa.Spec.GitWriteback
+	if r.GitWriter == nil {
+		return false, fmt.Errorf("git write-back is not configured")
+	}
+	if err := gitwriteback.ValidateRepository(writeback.Repository); err != nil {
+		return false, err
+	}
+
+	gvk, err := apiutil.GVKForObject(targetObject, r.Client.Scheme())
+	if err != nil {
+		return false, fmt.Errorf("failed to get target object kind: %w", err)
+	}
+	path, err := renderTemplate("path", writeback.Path, writebackPathData{
+		Namespace: targetObject.GetNamespace(),
+		Kind:      gvk.Kind,
+		Name:      targetObject.GetName(),
+		VWA:       vwa.Name,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if status := vwa.Status.GitWriteback; status != nil && status.Path == path && resourcesMapEqual(status.Resources, resources) {
+		return false, nil
+	}
+
+	content, err := r.writebackContent(targetObject, vwa, resources)
+	if err != nil {
+		return false, err
+	}
+	credentials, err := r.gitCredentials(ctx, vwa)
+	if err != nil {
+		return false, err
+	}
+	sha, err := r.GitWriter.Write(ctx, gitwriteback.Commit{
+		Repository:  writeback.Repository,
+		Branch:      writeback.Branch,
+		Path:        path,
+		Content:     content,
+		Message:     fmt.Sprintf("Update resources of %s %s/%s\n\nRecommended by VerticalWorkloadAutoscaler %s.", gvk.Kind, targetObject.GetNamespace(), targetObject.GetName(), vwa.Name),
+		Credentials: credentials,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to write resources to Git: %w", err)
+	}
+
+	vwa.Status.GitWriteback = &vwav1.GitWritebackStatus{
+		CommitSHA:   sha,
+		Path:        path,
+		CommittedAt: metav1.NewTime(timeNow()),
+		Resources:   resources,
+	}
+	r.recordEvent(vwa, "Normal", ReasonResourcesCommitted, fmt.Sprintf("committed resources of '%s' to %s (%s)", targetObject.GetName(), path, sha))
+	return true, nil
+}
+
+// writebackContent renders the resources in the spec.gitWriteback.format
+func (r *VerticalWorkloadAutoscalerReconciler) writebackContent(targetObject client.Object, vwa *vwav1.VerticalWorkloadAutoscaler, resources map[string]corev1.ResourceRequirements) ([]byte, error) {
+	var content []byte
+	switch vwa.Spec.GitWriteback.Format {
+	case vwav1.GitWritebackFormatHelmValues:
+		values, err := helmValues(vwa.Spec.GitWriteback.ValuesKey, resources)
+		if err != nil {
+			return nil, err
+		}
+		if content, err = yaml.Marshal(values); err != nil {
+			return nil, fmt.Errorf("failed to render Helm values: %w", err)
+		}
+	default:
+		// a strategic merge patch; the namespace is left to the kustomization
+		patch, err := r.resourcesObject(targetObject, resources)
+		if err != nil {
+			return nil, err
+		}
+		patch.SetNamespace("")
+		if content, err = yaml.Marshal(patch.Object); err != nil {
+			return nil, fmt.Errorf("failed to render kustomize patch: %w", err)
+		}
+	}
+	header := fmt.Sprintf("# Managed by VerticalWorkloadAutoscaler %s/%s; manual changes are overwritten.\n", vwa.Namespace, vwa.Name)
+	return append([]byte(header), content...), nil
+}
+
+// helmValues returns the Helm values holding the resources of every container at its values key
+func helmValues(keyTemplate string, resources map[string]corev1.ResourceRequirements) (map[string]interface{}, error) {
+	if keyTemplate == "" {
+		keyTemplate = defaultValuesKey
+	}
+
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]interface{})
+	for _, name := range names {
+		key, err := renderTemplate("valuesKey", keyTemplate, valuesKeyData{Container: name})
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Split(key, ".")
+		for _, f := range fields {
+			if f == "" {
+				return nil, fmt.Errorf("invalid Helm values key '%s' of container '%s'", key, name)
+			}
+		}
+		if _, found, _ := unstructured.NestedFieldNoCopy(values, fields...); found {
+			return nil, fmt.Errorf("values key '%s' of container '%s' is used by another container", key, name)
+		}
+
+		containerResources := resources[name]
+		unstructuredResources, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&containerResources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert resources of container '%s': %w", name, err)
+		}
+		if err := unstructured.SetNestedField(values, unstructuredResources, fields...); err != nil {
+			return nil, fmt.Errorf("failed to set Helm values key '%s': %w", key, err)
+		}
+	}
+	return values, nil
+}
+
+// gitCredentials reads the repository credentials from the spec.gitWriteback.secretRef Secret
+func (r *VerticalWorkloadAutoscalerReconciler) gitCredentials(ctx context.Context, vwa *vwav1.VerticalWorkloadAutoscaler) (*gitwriteback.Credentials, error) {
+	ref := vwa.Spec.GitWriteback.SecretRef
+	if ref == nil {
+		return nil, nil
+	}
+
+	// Secrets are read uncached, so the controller doesn't watch every Secret of the cluster
+	secret := &corev1.Secret{}
+	if err := r.uncachedReader().Get(ctx, client.ObjectKey{Namespace: vwa.Namespace, Name: ref.Name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get Git credentials Secret '%s': %w", ref.Name, err)
+	}
+	return &gitwriteback.Credentials{
+		Username:   string(secret.Data["username"]),
+		Password:   string(secret.Data["password"]),
+		Identity:   secret.Data["identity"],
+		KnownHosts: secret.Data["known_hosts"],
+	}, nil
+}
+
+// updateWritebackObserved marks the committed resources observed once the GitOps tool synced them to the target object
+func (r *VerticalWorkloadAutoscalerReconciler) updateWritebackObserved(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler, currentResources map[string]corev1.ResourceRequirements) error {
+	status := wa.Status.GitWriteback
+	if status == nil || status.Observed {
+		return nil
+	}
+	for name, committed := range status.Resources {
+		current, ok := currentResources[name]
+		if !ok || !resourceRequirementsEqual(current, committed) {
+			return nil
+		}
+	}
+
+	status.Observed = true
+	r.recordEvent(wa, "Normal", ReasonResourcesSynced, fmt.Sprintf("resources committed in %s are synced to the target object", status.CommitSHA))
+	return r.Status().Update(ctx, wa)
+}
+
+// updateWaitingForSync reports the committed resources the GitOps tool has not synced yet
+func (r *VerticalWorkloadAutoscalerReconciler) updateWaitingForSync(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) {
+	msg := fmt.Sprintf("waiting for the resources committed in %s to be synced", wa.Status.GitWriteback.CommitSHA)
+	r.updateStatusCondition(ctx, wa, ConditionTypeReconciled, metav1.ConditionFalse, ReasonWaitingForSync, msg) //nolint:errcheck
+}
+
+// resourcesMapEqual checks that both maps have the same containers with equal resources
+func resourcesMapEqual(a, b map[string]corev1.ResourceRequirements) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, resources := range a {
+		other, ok := b[name]
+		if !ok || !resourceRequirementsEqual(resources, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// renderTemplate executes the Go template text with the data
+func renderTemplate(name, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return sb.String(), nil
+}
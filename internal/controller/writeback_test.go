@@ -0,0 +1,450 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/alexei-led/vertical-workload-autoscaler/internal/gitwriteback"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeGitWriter records the written commits
+type fakeGitWriter struct {
+	commits []gitwriteback.Commit
+	err     error
+}
+
+func (w *fakeGitWriter) Write(_ context.Context, commit gitwriteback.Commit) (string, error) {
+	if w.err != nil {
+		return "", w.err
+	}
+	w.commits = append(w.commits, commit)
+	return "abc123", nil
+}
+
+func writebackVWA(writeback *vwav1.GitWriteback) *vwav1.VerticalWorkloadAutoscaler {
+	return &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec:       vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "vpa1"}, GitWriteback: writeback},
+	}
+}
+
+func TestWritebackContent(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	r := &VerticalWorkloadAutoscalerReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+	resources := map[string]corev1.ResourceRequirements{
+		"app": {
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("256Mi")},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+		},
+		"sidecar": cpuRequests("50m"),
+	}
+
+	tests := []struct {
+		name      string
+		writeback *vwav1.GitWriteback
+		expected  string
+	}{
+		{
+			name:      "Kustomize patch",
+			writeback: &vwav1.GitWriteback{Format: vwav1.GitWritebackFormatKustomizePatch},
+			expected: `# Managed by VerticalWorkloadAutoscaler default/vwa1; manual changes are overwritten.
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          limits:
+            memory: 256Mi
+          requests:
+            cpu: 500m
+            memory: 256Mi
+      - name: sidecar
+        resources:
+          requests:
+            cpu: 50m
+`,
+		},
+		{
+			name:      "Helm values",
+			writeback: &vwav1.GitWriteback{Format: vwav1.GitWritebackFormatHelmValues, ValuesKey: "{{ .Container }}.resources"},
+			expected: `# Managed by VerticalWorkloadAutoscaler default/vwa1; manual changes are overwritten.
+app:
+  resources:
+    limits:
+      memory: 256Mi
+    requests:
+      cpu: 500m
+      memory: 256Mi
+sidecar:
+  resources:
+    requests:
+      cpu: 50m
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := r.writebackContent(managedDeployment("250m", "kubectl"), writebackVWA(tt.writeback), resources)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+		})
+	}
+}
+
+func TestHelmValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		resources map[string]corev1.ResourceRequirements
+		expected  map[string]interface{}
+		errMsg    string
+	}{
+		{
+			name:      "Default key",
+			resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("100m")},
+			expected:  map[string]interface{}{"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "100m"}}},
+		},
+		{
+			name:      "Nested container key",
+			key:       "containers.{{ .Container }}.resources",
+			resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("100m")},
+			expected: map[string]interface{}{"containers": map[string]interface{}{"app": map[string]interface{}{
+				"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "100m"}},
+			}}},
+		},
+		{
+			name:      "Default key shared by containers",
+			resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("100m"), "sidecar": cpuRequests("10m")},
+			errMsg:    "values key 'resources' of container 'sidecar' is used by another container",
+		},
+		{
+			name:      "Empty key field",
+			key:       "{{ .Container }}..resources",
+			resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("100m")},
+			errMsg:    "invalid Helm values key",
+		},
+		{
+			name:      "Unknown template field",
+			key:       "{{ .Image }}.resources",
+			resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("100m")},
+			errMsg:    "failed to render valuesKey template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := helmValues(tt.key, tt.resources)
+			if tt.errMsg != "" {
+				assert.ErrorContains(t, err, tt.errMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, values)
+		})
+	}
+}
+
+func TestWriteBackResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	resources := map[string]corev1.ResourceRequirements{"app": cpuRequests("500m")}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("git"), "password": []byte("token")},
+	}
+	writeback := &vwav1.GitWriteback{
+		Repository: "https://github.com/org/deploy.git",
+		Branch:     "main",
+		Path:       "apps/{{ .Namespace }}/{{ .Kind }}-{{ .Name }}.yaml",
+		Format:     vwav1.GitWritebackFormatKustomizePatch,
+		SecretRef:  &corev1.LocalObjectReference{Name: "git"},
+	}
+
+	tests := []struct {
+		name            string
+		writeback       *vwav1.GitWriteback
+		status          *vwav1.GitWritebackStatus
+		writer          *fakeGitWriter
+		expectedCommit  bool
+		expectedWritten bool
+		errMsg          string
+	}{
+		{
+			name:            "Commit recommended resources",
+			writeback:       writeback,
+			writer:          &fakeGitWriter{},
+			expectedCommit:  true,
+			expectedWritten: true,
+		},
+		{
+			name:      "Resources already committed",
+			writeback: writeback,
+			status: &vwav1.GitWritebackStatus{
+				CommitSHA: "abc123", Path: "apps/default/Deployment-web.yaml", Resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("0.5")},
+			},
+			writer: &fakeGitWriter{},
+		},
+		{
+			name:      "Other resources committed",
+			writeback: writeback,
+			status: &vwav1.GitWritebackStatus{
+				CommitSHA: "0ld", Path: "apps/default/Deployment-web.yaml", Resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("400m")},
+			},
+			writer:          &fakeGitWriter{},
+			expectedCommit:  true,
+			expectedWritten: true,
+		},
+		{
+			name:      "Missing credentials Secret",
+			writeback: &vwav1.GitWriteback{Repository: "https://github.com/org/deploy.git", Branch: "main", Path: "web.yaml", SecretRef: &corev1.LocalObjectReference{Name: "missing"}},
+			writer:    &fakeGitWriter{},
+			errMsg:    "failed to get Git credentials Secret 'missing'",
+		},
+		{
+			name:      "Local repository",
+			writeback: &vwav1.GitWriteback{Repository: "file:///srv/git/deploy.git", Branch: "main", Path: "web.yaml"},
+			writer:    &fakeGitWriter{},
+			errMsg:    "invalid repository 'file:///srv/git/deploy.git'",
+		},
+		{
+			name:      "Invalid path template",
+			writeback: &vwav1.GitWriteback{Repository: "https://github.com/org/deploy.git", Branch: "main", Path: "{{ .Image }}.yaml"},
+			writer:    &fakeGitWriter{},
+			errMsg:    "failed to render path template",
+		},
+		{
+			name:      "Push failure",
+			writeback: writeback,
+			writer:    &fakeGitWriter{err: errors.New("git push: rejected")},
+			errMsg:    "failed to write resources to Git: git push: rejected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(10), GitWriter: tt.writer}
+			vwa := writebackVWA(tt.writeback)
+			vwa.Status.GitWriteback = tt.status
+
+			committed, err := r.writeBackResources(context.Background(), managedDeployment("250m", "kubectl"), vwa, resources)
+			if tt.errMsg != "" {
+				assert.ErrorContains(t, err, tt.errMsg)
+				assert.Empty(t, tt.writer.commits)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCommit, committed)
+			if !tt.expectedWritten {
+				assert.Empty(t, tt.writer.commits)
+				return
+			}
+			require.Len(t, tt.writer.commits, 1)
+			commit := tt.writer.commits[0]
+			assert.Equal(t, "apps/default/Deployment-web.yaml", commit.Path)
+			assert.Equal(t, "main", commit.Branch)
+			assert.Equal(t, &gitwriteback.Credentials{Username: "git", Password: "token"}, commit.Credentials)
+			assert.True(t, strings.HasPrefix(commit.Message, "Update resources of Deployment default/web"))
+			assert.Equal(t, &vwav1.GitWritebackStatus{
+				CommitSHA:   "abc123",
+				Path:        "apps/default/Deployment-web.yaml",
+				CommittedAt: metav1.NewTime(now),
+				Resources:   resources,
+			}, vwa.Status.GitWriteback)
+		})
+	}
+}
+
+func TestWriteBackResourcesNotConfigured(t *testing.T) {
+	r := &VerticalWorkloadAutoscalerReconciler{Client: fake.NewClientBuilder().Build()}
+	vwa := writebackVWA(&vwav1.GitWriteback{Repository: "https://github.com/org/deploy.git", Path: "web.yaml"})
+	_, err := r.writeBackResources(context.Background(), managedDeployment("250m", "kubectl"), vwa, nil)
+	assert.ErrorContains(t, err, "git write-back is not configured")
+}
+
+func TestUpdateWritebackObserved(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+
+	tests := []struct {
+		name     string
+		status   *vwav1.GitWritebackStatus
+		current  map[string]corev1.ResourceRequirements
+		expected *vwav1.GitWritebackStatus
+	}{
+		{name: "Nothing committed", current: map[string]corev1.ResourceRequirements{"app": cpuRequests("250m")}},
+		{
+			name:     "Committed resources not synced",
+			status:   &vwav1.GitWritebackStatus{CommitSHA: "abc123", Resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("500m")}},
+			current:  map[string]corev1.ResourceRequirements{"app": cpuRequests("250m")},
+			expected: &vwav1.GitWritebackStatus{CommitSHA: "abc123", Resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("500m")}},
+		},
+		{
+			name:     "Committed container missing",
+			status:   &vwav1.GitWritebackStatus{CommitSHA: "abc123", Resources: map[string]corev1.ResourceRequirements{"sidecar": cpuRequests("50m")}},
+			current:  map[string]corev1.ResourceRequirements{"app": cpuRequests("500m")},
+			expected: &vwav1.GitWritebackStatus{CommitSHA: "abc123", Resources: map[string]corev1.ResourceRequirements{"sidecar": cpuRequests("50m")}},
+		},
+		{
+			name:     "Committed resources synced",
+			status:   &vwav1.GitWritebackStatus{CommitSHA: "abc123", Resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("500m")}},
+			current:  map[string]corev1.ResourceRequirements{"app": cpuRequests("0.5"), "sidecar": cpuRequests("50m")},
+			expected: &vwav1.GitWritebackStatus{CommitSHA: "abc123", Resources: map[string]corev1.ResourceRequirements{"app": cpuRequests("500m")}, Observed: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vwa := writebackVWA(&vwav1.GitWriteback{Repository: "https://github.com/org/deploy.git", Path: "web.yaml"})
+			vwa.Status.GitWriteback = tt.status
+			c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(vwa).WithObjects(vwa).Build()
+			r := &VerticalWorkloadAutoscalerReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+			require.NoError(t, r.updateWritebackObserved(context.Background(), vwa, tt.current))
+			stored := &vwav1.VerticalWorkloadAutoscaler{}
+			require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(vwa), stored))
+			assert.Equal(t, tt.expected, stored.Status.GitWriteback)
+		})
+	}
+}
+
+// newBareRepo returns a local bare repository with an initial commit on the main branch
+func newBareRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	work := filepath.Join(dir, "work")
+	gitRun(t, dir, "init", "--quiet", "--bare", "--initial-branch=main", remote)
+	gitRun(t, dir, "init", "--quiet", "--initial-branch=main", work)
+	require.NoError(t, os.WriteFile(filepath.Join(work, "kustomization.yaml"), []byte("resources: []\n"), 0o644))
+	gitRun(t, work, "add", "kustomization.yaml")
+	gitRun(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--message", "initial")
+	gitRun(t, work, "push", "--quiet", remote, "main")
+	return remote
+}
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// localRemoteWriter writes to the local test repository instead of the remote repository of the commit,
+// since only remote repositories pass the VWA validation
+type localRemoteWriter struct {
+	gitwriteback.Writer
+	remote string
+}
+
+func (w localRemoteWriter) Write(ctx context.Context, commit gitwriteback.Commit) (string, error) {
+	commit.Repository = w.remote
+	return w.Writer.Write(ctx, commit)
+}
+
+func TestHandleVWAChangeWritesBackToGit(t *testing.T) {
+	remote := newBareRepo(t)
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+	_ = vpav1.AddToScheme(scheme)
+	now := time.Date(2023, 10, 10, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+
+	updateModeOff := vpav1.UpdateModeOff
+	vwa := writebackVWA(&vwav1.GitWriteback{
+		Repository: "https://git.example.com/deploy.git",
+		Branch:     "main",
+		Path:       "{{ .Name }}-resources.yaml",
+		Format:     vwav1.GitWritebackFormatKustomizePatch,
+	})
+	vpa := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpa1", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &updateModeOff},
+			TargetRef:    &autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+		},
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Recommendation: &vpav1.RecommendedPodResources{
+				ContainerRecommendations: []vpav1.RecommendedContainerResources{
+					{ContainerName: "app", Target: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+				},
+			},
+		},
+	}
+	deployment := managedDeployment("250m", "argocd-controller")
+	deployment.Annotations = nil
+
+	c := withApplyPatches(fake.NewClientBuilder()).WithScheme(scheme).WithStatusSubresource(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithObjects(vwa, vpa, deployment).Build()
+	writer := localRemoteWriter{Writer: gitwriteback.NewCLIWriter("vwa", "vwa@example.com"), remote: remote}
+	r := &VerticalWorkloadAutoscalerReconciler{Client: c, Timeout: time.Minute, GitWriter: writer}
+	cpu := func() string {
+		stored := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+		return stored.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String()
+	}
+
+	// the recommendation is committed instead of applied
+	_, err := r.handleVWAChange(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.Equal(t, "250m", cpu())
+	require.NotNil(t, vwa.Status.GitWriteback)
+	assert.Equal(t, gitRun(t, remote, "rev-parse", "main"), vwa.Status.GitWriteback.CommitSHA)
+	assert.False(t, vwa.Status.GitWriteback.Observed)
+	assert.Equal(t, ReasonWaitingForSync, findCondition(vwa.Status.Conditions, ConditionTypeReconciled).Reason)
+	assert.Contains(t, gitRun(t, remote, "show", "main:web-resources.yaml"), "cpu: 500m")
+
+	// the commit waits for the GitOps tool
+	now = now.Add(10 * time.Minute)
+	_, err = r.handleVWAChange(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.Equal(t, "2", gitRun(t, remote, "rev-list", "--count", "main"))
+	assert.Equal(t, ReasonWaitingForSync, findCondition(vwa.Status.Conditions, ConditionTypeReconciled).Reason)
+
+	// the GitOps tool syncs the commit
+	stored := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(deployment), stored))
+	stored.Spec.Template.Spec.Containers[0].Resources = vwa.Status.GitWriteback.Resources["app"]
+	require.NoError(t, c.Update(context.Background(), stored))
+	now = now.Add(10 * time.Minute)
+	_, err = r.handleVWAChange(context.Background(), vwa)
+	require.NoError(t, err)
+	assert.True(t, vwa.Status.GitWriteback.Observed)
+	assert.Equal(t, "2", gitRun(t, remote, "rev-list", "--count", "main"))
+}
@@ -0,0 +1,233 @@
+// Package gitwriteback commits files to Git repositories, so recommended resources reach the cluster
+// through the GitOps tool syncing the repository instead of direct workload updates.
+package gitwriteback
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// repositoryPattern matches the remote repository URLs the writer accepts: https://, ssh:// and scp-like
+// user@host:path URLs. Local paths and other transports, like file:// and ext::, are rejected.
+var repositoryPattern = regexp.MustCompile(`^(https://|ssh://|[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:).+$`)
+
+// Credentials authenticate to a Git repository
+type Credentials struct {
+	// Username and Password authenticate HTTPS repositories
+	Username string
+	Password string
+	// Identity is the private key authenticating SSH repositories
+	Identity []byte
+	// KnownHosts verifies the SSH host keys; it is required for SSH repositories
+	KnownHosts []byte
+}
+
+// Commit is a file committed to a repository branch
+type Commit struct {
+	Repository  string
+	Branch      string
+	Path        string
+	Content     []byte
+	Message     string
+	Credentials *Credentials
+}
+
+// Writer writes files to Git repositories
+type Writer interface {
+	// Write commits the file to the repository branch and returns the SHA of the branch head.
+	// No commit is made if the file already has the content.
+	Write(ctx context.Context, commit Commit) (string, error)
+}
+
+// CLIWriter is a Writer running the git command line
+type CLIWriter struct {
+	// AuthorName and AuthorEmail identify the commit author
+	AuthorName  string
+	AuthorEmail string
+}
+
+// NewCLIWriter returns a Writer committing as the author
+func NewCLIWriter(authorName, authorEmail string) *CLIWriter {
+	return &CLIWriter{AuthorName: authorName, AuthorEmail: authorEmail}
+}
+
+// ValidateRepository checks that the repository is a remote https://, ssh:// or scp-like user@host:path URL
+func ValidateRepository(repository string) error {
+	if !repositoryPattern.MatchString(repository) {
+		return fmt.Errorf("invalid repository '%s': must be an https://, ssh:// or user@host:path URL", repository)
+	}
+	return nil
+}
+
+// Write clones the repository branch, commits the file and pushes it
+func (w *CLIWriter) Write(ctx context.Context, commit Commit) (string, error) {
+	filePath, err := cleanPath(commit.Path)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "vwa-git-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	env, err := credentialsEnv(dir, commit.Repository, commit.Credentials)
+	if err != nil {
+		return "", err
+	}
+	env = append(env,
+		"GIT_AUTHOR_NAME="+w.AuthorName, "GIT_AUTHOR_EMAIL="+w.AuthorEmail,
+		"GIT_COMMITTER_NAME="+w.AuthorName, "GIT_COMMITTER_EMAIL="+w.AuthorEmail)
+	repoDir := filepath.Join(dir, "repo")
+	git := func(args ...string) (string, error) {
+		return run(ctx, repoDir, env, args...)
+	}
+
+	if _, err := run(ctx, dir, env, "clone", "--depth", "1", "--single-branch", "--branch", commit.Branch, "--", commit.Repository, repoDir); err != nil {
+		return "", err
+	}
+
+	// the repository may link a directory or the file outside of the work directory
+	if err := checkSymlinks(repoDir, filePath); err != nil {
+		return "", err
+	}
+	file := filepath.Join(repoDir, filepath.FromSlash(filePath))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory of '%s': %w", filePath, err)
+	}
+	if err := os.WriteFile(file, commit.Content, 0o644); err != nil { //nolint:gosec
+		return "", fmt.Errorf("failed to write '%s': %w", filePath, err)
+	}
+	if _, err := git("add", "--", filePath); err != nil {
+		return "", err
+	}
+
+	// commit and push only if the file content changed
+	if _, err := git("diff", "--cached", "--quiet"); err != nil {
+		if _, err := git("commit", "--message", commit.Message); err != nil {
+			return "", err
+		}
+		if _, err := git("push", "origin", "HEAD:refs/heads/"+commit.Branch); err != nil {
+			return "", err
+		}
+	}
+
+	sha, err := git("rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sha), nil
+}
+
+// cleanPath returns the slash separated file path relative to the repository root
+func cleanPath(filePath string) (string, error) {
+	cleaned := path.Clean(filepath.ToSlash(filePath))
+	if filePath == "" || path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid file path '%s': must be relative to the repository root", filePath)
+	}
+	return cleaned, nil
+}
+
+// checkSymlinks refuses file paths with a symbolic link in the existing directories or the file itself
+func checkSymlinks(root, filePath string) error {
+	current := root
+	for _, name := range strings.Split(filePath, "/") {
+		current = filepath.Join(current, name)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check '%s': %w", filePath, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("invalid file path '%s': '%s' is a symbolic link", filePath, strings.TrimPrefix(filepath.ToSlash(current), filepath.ToSlash(root)+"/"))
+		}
+	}
+	return nil
+}
+
+// sshPattern matches the ssh:// and scp-like user@host:path repository URLs
+var sshPattern = regexp.MustCompile(`^(ssh://|[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:)`)
+
+// passedEnv are the variables of the controller environment passed to git: the PATH to find ssh and the
+// proxy and CA settings. Nothing else is inherited, so the controller environment can't change how git runs.
+var passedEnv = []string{
+	"PATH", "HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy", "SSL_CERT_FILE", "SSL_CERT_DIR",
+}
+
+// credentialsEnv returns the minimal git environment authenticating with the credentials. The credentials are passed
+// in environment variables and files of the work directory, so they never show up in the process arguments.
+// The work directory is also the home directory, and SSH host keys are always verified against the known hosts.
+func credentialsEnv(dir, repository string, credentials *Credentials) ([]string, error) {
+	env := []string{"HOME=" + dir, "GIT_CONFIG_NOSYSTEM=1", "GIT_TERMINAL_PROMPT=0"}
+	for _, name := range passedEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	if credentials == nil {
+		credentials = &Credentials{}
+	}
+
+	if credentials.Username != "" || credentials.Password != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(credentials.Username + ":" + credentials.Password))
+		env = append(env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth)
+	}
+
+	if !sshPattern.MatchString(repository) {
+		return env, nil
+	}
+	if len(credentials.KnownHosts) == 0 {
+		return nil, fmt.Errorf("SSH repository '%s' requires known_hosts in the credentials to verify the host key", repository)
+	}
+	sshCommand := "ssh"
+	if len(credentials.Identity) > 0 {
+		identityFile := filepath.Join(dir, "identity")
+		if err := os.WriteFile(identityFile, credentials.Identity, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write SSH identity: %w", err)
+		}
+		sshCommand += fmt.Sprintf(" -i %s -o IdentitiesOnly=yes", shellQuote(identityFile))
+	}
+	knownHostsFile := filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(knownHostsFile, credentials.KnownHosts, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write SSH known hosts: %w", err)
+	}
+	sshCommand += fmt.Sprintf(" -o UserKnownHostsFile=%s -o StrictHostKeyChecking=yes", shellQuote(knownHostsFile))
+	return append(env, "GIT_SSH_COMMAND="+sshCommand), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// run runs git in the directory and returns its standard output
+func run(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,227 @@
+package gitwriteback
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBareRepo returns a local bare repository with an initial commit on the main branch
+func newBareRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	work := filepath.Join(dir, "work")
+	gitRun(t, dir, "init", "--quiet", "--bare", "--initial-branch=main", remote)
+	gitRun(t, dir, "init", "--quiet", "--initial-branch=main", work)
+	require.NoError(t, os.WriteFile(filepath.Join(work, "README.md"), []byte("deploy\n"), 0o644))
+	gitRun(t, work, "add", "README.md")
+	gitRun(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--message", "initial")
+	gitRun(t, work, "push", "--quiet", remote, "main")
+	return remote
+}
+
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+func TestCLIWriterWrite(t *testing.T) {
+	remote := newBareRepo(t)
+	writer := NewCLIWriter("vwa", "vwa@example.com")
+	commit := Commit{
+		Repository: remote,
+		Branch:     "main",
+		Path:       "apps/web/resources.yaml",
+		Content:    []byte("resources: {}\n"),
+		Message:    "Update resources of web",
+	}
+
+	sha, err := writer.Write(context.Background(), commit)
+	require.NoError(t, err)
+	assert.Equal(t, gitRun(t, remote, "rev-parse", "main"), sha)
+	assert.Equal(t, "resources: {}", gitRun(t, remote, "show", "main:apps/web/resources.yaml"))
+	assert.Equal(t, "vwa <vwa@example.com> Update resources of web", gitRun(t, remote, "log", "-1", "--format=%an <%ae> %s", "main"))
+
+	// unchanged content doesn't make a commit
+	unchangedSHA, err := writer.Write(context.Background(), commit)
+	require.NoError(t, err)
+	assert.Equal(t, sha, unchangedSHA)
+	assert.Equal(t, "2", gitRun(t, remote, "rev-list", "--count", "main"))
+
+	// changed content makes a new commit
+	commit.Content = []byte("resources:\n  requests:\n    cpu: 500m\n")
+	newSHA, err := writer.Write(context.Background(), commit)
+	require.NoError(t, err)
+	assert.NotEqual(t, sha, newSHA)
+	assert.Equal(t, gitRun(t, remote, "rev-parse", "main"), newSHA)
+	assert.Equal(t, "3", gitRun(t, remote, "rev-list", "--count", "main"))
+}
+
+func TestCLIWriterWriteErrors(t *testing.T) {
+	remote := newBareRepo(t)
+	writer := NewCLIWriter("vwa", "vwa@example.com")
+
+	tests := []struct {
+		name   string
+		commit Commit
+		errMsg string
+	}{
+		{
+			name:   "Missing branch",
+			commit: Commit{Repository: remote, Branch: "release", Path: "resources.yaml"},
+			errMsg: "git clone",
+		},
+		{
+			name:   "Missing repository",
+			commit: Commit{Repository: filepath.Join(t.TempDir(), "missing.git"), Branch: "main", Path: "resources.yaml"},
+			errMsg: "git clone",
+		},
+		{
+			name:   "Path outside the repository",
+			commit: Commit{Repository: remote, Branch: "main", Path: "../resources.yaml"},
+			errMsg: "invalid file path",
+		},
+		{
+			name:   "Absolute path",
+			commit: Commit{Repository: remote, Branch: "main", Path: "/resources.yaml"},
+			errMsg: "invalid file path",
+		},
+		{
+			name:   "Symlinked directory",
+			commit: Commit{Repository: remote, Branch: "main", Path: "linked/resources.yaml"},
+			errMsg: "'linked' is a symbolic link",
+		},
+		{
+			name:   "Symlinked file",
+			commit: Commit{Repository: remote, Branch: "main", Path: "apps/linked.yaml"},
+			errMsg: "'apps/linked.yaml' is a symbolic link",
+		},
+	}
+
+	// the repository links a directory and a file outside of the work directory
+	outside := t.TempDir()
+	work := filepath.Join(t.TempDir(), "work")
+	gitRun(t, filepath.Dir(work), "clone", "--quiet", remote, work)
+	require.NoError(t, os.Symlink(outside, filepath.Join(work, "linked")))
+	require.NoError(t, os.MkdirAll(filepath.Join(work, "apps"), 0o755))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "linked.yaml"), filepath.Join(work, "apps", "linked.yaml")))
+	gitRun(t, work, "add", "linked", "apps/linked.yaml")
+	gitRun(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--message", "links")
+	gitRun(t, work, "push", "--quiet", "origin", "main")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := writer.Write(context.Background(), tt.commit)
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+	entries, err := os.ReadDir(outside)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestValidateRepository(t *testing.T) {
+	tests := []struct {
+		repository string
+		wantErr    bool
+	}{
+		{repository: "https://github.com/org/deploy.git"},
+		{repository: "ssh://git@github.com/org/deploy.git"},
+		{repository: "git@github.com:org/deploy.git"},
+		{repository: "http://github.com/org/deploy.git", wantErr: true},
+		{repository: "file:///srv/git/deploy.git", wantErr: true},
+		{repository: "/srv/git/deploy.git", wantErr: true},
+		{repository: "../deploy.git", wantErr: true},
+		{repository: "ext::sh -c touch% /tmp/pwned", wantErr: true},
+		{repository: "https://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repository, func(t *testing.T) {
+			err := ValidateRepository(tt.repository)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		wantErr  bool
+	}{
+		{path: "apps/web/patch.yaml", expected: "apps/web/patch.yaml"},
+		{path: "./apps//web/../web/patch.yaml", expected: "apps/web/patch.yaml"},
+		{path: "", wantErr: true},
+		{path: ".", wantErr: true},
+		{path: "apps/../..", wantErr: true},
+		{path: "../patch.yaml", wantErr: true},
+		{path: "/etc/patch.yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			cleaned, err := cleanPath(tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, cleaned)
+		})
+	}
+}
+
+func TestCredentialsEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HTTPS_PROXY", "http://proxy:3128")
+	t.Setenv("VWA_TEST_TOKEN", "secret")
+
+	// only the allowed variables of the controller environment are passed
+	env, err := credentialsEnv(dir, "https://github.com/org/deploy.git", nil)
+	require.NoError(t, err)
+	assert.Contains(t, env, "HOME="+dir)
+	assert.Contains(t, env, "HTTPS_PROXY=http://proxy:3128")
+	assert.NotContains(t, env, "VWA_TEST_TOKEN=secret")
+
+	env, err = credentialsEnv(dir, "https://github.com/org/deploy.git", &Credentials{Username: "git", Password: "token"})
+	require.NoError(t, err)
+	assert.Contains(t, env, "GIT_CONFIG_KEY_0=http.extraHeader")
+	assert.Contains(t, env, "GIT_CONFIG_VALUE_0=Authorization: Basic Z2l0OnRva2Vu")
+
+	env, err = credentialsEnv(dir, "git@github.com:org/deploy.git", &Credentials{Identity: []byte("key"), KnownHosts: []byte("github.com ssh-ed25519 AAAA")})
+	require.NoError(t, err)
+	identity := filepath.Join(dir, "identity")
+	knownHosts := filepath.Join(dir, "known_hosts")
+	assert.Contains(t, env, "GIT_SSH_COMMAND=ssh -i '"+identity+"' -o IdentitiesOnly=yes -o UserKnownHostsFile='"+knownHosts+"' -o StrictHostKeyChecking=yes")
+	info, err := os.Stat(identity)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// SSH host keys are never accepted unverified
+	for _, repository := range []string{"git@github.com:org/deploy.git", "ssh://git@github.com/org/deploy.git"} {
+		_, err = credentialsEnv(dir, repository, &Credentials{Identity: []byte("key")})
+		assert.ErrorContains(t, err, "requires known_hosts", repository)
+		_, err = credentialsEnv(dir, repository, nil)
+		assert.ErrorContains(t, err, "requires known_hosts", repository)
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"text/template"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/alexei-led/vertical-workload-autoscaler/internal/gitwriteback"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// minUpdateFrequency is the update frequency below which workloads are restarted too often
+const minUpdateFrequency = time.Minute
+
+// log is for logging in this package.
+var verticalworkloadautoscalerlog = logf.Log.WithName("verticalworkloadautoscaler-resource")
+
+// timeNow is used to check for expired blackout windows; overridden in tests
+var timeNow = time.Now
+
+// SetupVerticalWorkloadAutoscalerWebhookWithManager registers the webhook for VerticalWorkloadAutoscaler in the manager.
+func SetupVerticalWorkloadAutoscalerWebhookWithManager(mgr ctrl.Manager, defaults vwav1.SpecDefaults) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&vwav1.VerticalWorkloadAutoscaler{}).
+		WithValidator(&VerticalWorkloadAutoscalerCustomValidator{Client: mgr.GetClient()}).
+		WithDefaulter(&VerticalWorkloadAutoscalerCustomDefaulter{Defaults: defaults}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-autoscaling-workload-io-v1alpha1-verticalworkloadautoscaler,mutating=true,failurePolicy=fail,sideEffects=None,groups=autoscaling.workload.io,resources=verticalworkloadautoscalers,verbs=create;update,versions=v1alpha1,name=mverticalworkloadautoscaler-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// VerticalWorkloadAutoscalerCustomDefaulter sets every unset field of the VerticalWorkloadAutoscaler spec
+// to its effective default when the object is created or updated.
+type VerticalWorkloadAutoscalerCustomDefaulter struct {
+	Defaults vwav1.SpecDefaults
+}
+
+var _ webhook.CustomDefaulter = &VerticalWorkloadAutoscalerCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type VerticalWorkloadAutoscaler.
+func (d *VerticalWorkloadAutoscalerCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	wa, ok := obj.(*vwav1.VerticalWorkloadAutoscaler)
+	if !ok {
+		return fmt.Errorf("expected a VerticalWorkloadAutoscaler object but got %T", obj)
+	}
+	verticalworkloadautoscalerlog.Info("defaulting for VerticalWorkloadAutoscaler", "name", wa.GetName())
+
+	d.Defaults.Apply(&wa.Spec)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-autoscaling-workload-io-v1alpha1-verticalworkloadautoscaler,mutating=false,failurePolicy=fail,sideEffects=None,groups=autoscaling.workload.io,resources=verticalworkloadautoscalers,verbs=create;update,versions=v1alpha1,name=vverticalworkloadautoscaler-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// VerticalWorkloadAutoscalerCustomValidator validates VerticalWorkloadAutoscaler objects on create and update.
+// It rejects invalid update windows, blackout windows and VPA references used by another VWA,
+// and warns about risky but legal combinations of settings.
+type VerticalWorkloadAutoscalerCustomValidator struct {
+	client.Client
+}
+
+var _ webhook.CustomValidator = &VerticalWorkloadAutoscalerCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type VerticalWorkloadAutoscaler.
+func (v *VerticalWorkloadAutoscalerCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	wa, ok := obj.(*vwav1.VerticalWorkloadAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("expected a VerticalWorkloadAutoscaler object but got %T", obj)
+	}
+	verticalworkloadautoscalerlog.Info("validation for VerticalWorkloadAutoscaler upon creation", "name", wa.GetName())
+
+	return v.validate(ctx, wa, nil)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type VerticalWorkloadAutoscaler.
+// Only the changed fields are validated, so objects valid under earlier rules can still be updated.
+func (v *VerticalWorkloadAutoscalerCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	wa, ok := newObj.(*vwav1.VerticalWorkloadAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("expected a VerticalWorkloadAutoscaler object for the newObj but got %T", newObj)
+	}
+	old, ok := oldObj.(*vwav1.VerticalWorkloadAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("expected a VerticalWorkloadAutoscaler object for the oldObj but got %T", oldObj)
+	}
+	verticalworkloadautoscalerlog.Info("validation for VerticalWorkloadAutoscaler upon update", "name", wa.GetName())
+
+	// the controller removes its finalizer from a deleted VWA, whatever its spec
+	if wa.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	return v.validate(ctx, wa, old)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type VerticalWorkloadAutoscaler.
+func (v *VerticalWorkloadAutoscalerCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns the warnings and the validation errors of the VWA; on update, only the fields changed from
+// the old VWA are validated
+func (v *VerticalWorkloadAutoscalerCustomValidator) validate(ctx context.Context, wa, old *vwav1.VerticalWorkloadAutoscaler) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	var oldSpec vwav1.VerticalWorkloadAutoscalerSpec
+	if old != nil {
+		oldSpec = old.Spec
+	}
+	changed := func(newValue, oldValue interface{}) bool {
+		return old == nil || !equality.Semantic.DeepEqual(newValue, oldValue)
+	}
+
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, validateUpdateWindows(specPath.Child("allowedUpdateWindows"), wa.Spec.AllowedUpdateWindows, oldSpec.AllowedUpdateWindows)...)
+	allErrs = append(allErrs, validateBlackoutWindows(specPath.Child("blackoutWindows"), wa.Spec.BlackoutWindows, oldSpec.BlackoutWindows)...)
+	if wa.Spec.UpdateFrequency != nil && wa.Spec.UpdateFrequency.Duration <= 0 && changed(wa.Spec.UpdateFrequency, oldSpec.UpdateFrequency) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("updateFrequency"), wa.Spec.UpdateFrequency.Duration.String(), "must be positive"))
+	}
+	if wa.Spec.DriftPolicy != nil && wa.Spec.DriftPolicy.Period != nil && wa.Spec.DriftPolicy.Period.Duration <= 0 &&
+		(oldSpec.DriftPolicy == nil || changed(wa.Spec.DriftPolicy.Period, oldSpec.DriftPolicy.Period)) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("driftPolicy", "period"), wa.Spec.DriftPolicy.Period.Duration.String(), "must be positive"))
+	}
+
+	if changed(wa.Spec.GitWriteback, oldSpec.GitWriteback) {
+		allErrs = append(allErrs, validateGitWriteback(specPath.Child("gitWriteback"), wa.Spec.GitWriteback)...)
+	}
+	if wa.Spec.ApplyMethod == vwav1.ApplyMethodAdmission && wa.Spec.GitWriteback != nil &&
+		(changed(wa.Spec.ApplyMethod, oldSpec.ApplyMethod) || changed(wa.Spec.GitWriteback, oldSpec.GitWriteback)) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("applyMethod"), "the Admission apply method can't be combined with gitWriteback"))
+	}
+
+	// a VPA shared with another VWA is only rejected when the reference is set
+	if changed(wa.Spec.VPAReference, oldSpec.VPAReference) {
+		dupErr, err := v.validateVPAReference(ctx, wa)
+		if err != nil {
+			return nil, apierrors.NewInternalError(err)
+		}
+		if dupErr != nil {
+			allErrs = append(allErrs, dupErr)
+		}
+	}
+
+	if len(allErrs) > 0 {
+		return nil, apierrors.NewInvalid(vwav1.GroupVersion.WithKind("VerticalWorkloadAutoscaler").GroupKind(), wa.Name, allErrs)
+	}
+	return warnings(wa), nil
+}
+
+// validateUpdateWindows checks that every update window not in the old windows has a valid IANA time zone and
+// ends after it starts
+func validateUpdateWindows(path *field.Path, windows, oldWindows []vwav1.UpdateWindow) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, window := range windows {
+		if slices.Contains(oldWindows, window) {
+			continue
+		}
+		windowPath := path.Index(i)
+		// "Local" is the time zone of the controller, not a stable IANA zone
+		if window.TimeZone == "Local" {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("timeZone"), window.TimeZone, "must be an IANA time zone, like \"UTC\" or \"America/New_York\""))
+		} else if _, err := time.LoadLocation(window.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("timeZone"), window.TimeZone, fmt.Sprintf("unknown IANA time zone: %v", err)))
+		}
+
+		start, startErr := time.Parse("15:04", window.StartTime)
+		if startErr != nil {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("startTime"), window.StartTime, "must be in HH:MM format"))
+		}
+		end, endErr := time.Parse("15:04", window.EndTime)
+		if endErr != nil {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("endTime"), window.EndTime, "must be in HH:MM format"))
+		}
+		if startErr == nil && endErr == nil && !start.Before(end) {
+			allErrs = append(allErrs, field.Invalid(windowPath.Child("endTime"), window.EndTime,
+				fmt.Sprintf("must be after startTime %s; windows can't span midnight, split them into two windows instead", window.StartTime)))
+		}
+	}
+	return allErrs
+}
+
+// validateBlackoutWindows checks that every blackout window not in the old windows ends after it starts
+func validateBlackoutWindows(path *field.Path, windows, oldWindows []vwav1.BlackoutWindow) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, window := range windows {
+		if slices.ContainsFunc(oldWindows, func(old vwav1.BlackoutWindow) bool { return equality.Semantic.DeepEqual(old, window) }) {
+			continue
+		}
+		if !window.Start.Before(&window.End) {
+			allErrs = append(allErrs, field.Invalid(path.Index(i).Child("end"), window.End.Format(time.RFC3339),
+				fmt.Sprintf("must be after start %s", window.Start.Format(time.RFC3339))))
+		}
+	}
+	return allErrs
+}
+
+// validateGitWriteback checks that the Git write-back repository is a remote URL and that the path and values key
+// templates parse
+func validateGitWriteback(path *field.Path, writeback *vwav1.GitWriteback) field.ErrorList {
+	if writeback == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	if err := gitwriteback.ValidateRepository(writeback.Repository); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("repository"), writeback.Repository, "must be an https://, ssh:// or user@host:path URL"))
+	}
+	if _, err := template.New("path").Parse(writeback.Path); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("path"), writeback.Path, fmt.Sprintf("invalid Go template: %v", err)))
+	}
+	if _, err := template.New("valuesKey").Parse(writeback.ValuesKey); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("valuesKey"), writeback.ValuesKey, fmt.Sprintf("invalid Go template: %v", err)))
+	}
+	return allErrs
+}
+
+// validateVPAReference checks that no other VWA in the namespace references the same VPA
+func (v *VerticalWorkloadAutoscalerCustomValidator) validateVPAReference(ctx context.Context, wa *vwav1.VerticalWorkloadAutoscaler) (*field.Error, error) {
+	var waList vwav1.VerticalWorkloadAutoscalerList
+	if err := v.List(ctx, &waList, client.InNamespace(wa.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list VerticalWorkloadAutoscaler objects: %w", err)
+	}
+
+	for _, existingWA := range waList.Items {
+		if existingWA.Name != wa.Name && existingWA.Spec.VPAReference.Name == wa.Spec.VPAReference.Name {
+			return field.Duplicate(field.NewPath("spec", "vpaReference", "name"), fmt.Sprintf("%s (already referenced by VWA '%s')", wa.Spec.VPAReference.Name, existingWA.Name)), nil
+		}
+	}
+	return nil, nil
+}
+
+// warnings returns the warnings for risky but legal combinations of settings
+func warnings(wa *vwav1.VerticalWorkloadAutoscaler) admission.Warnings {
+	var warns admission.Warnings
+	if wa.Spec.QualityOfService == vwav1.GuaranteedQualityOfService && wa.Spec.AvoidCPULimit {
+		warns = append(warns, "spec.qualityOfService is Guaranteed but spec.avoidCPULimit is true: "+
+			"without CPU limits the pods get the Burstable QoS class")
+	}
+	if wa.Spec.IgnoreCPURecommendations && wa.Spec.IgnoreMemoryRecommendations {
+		warns = append(warns, "spec.ignoreCPURecommendations and spec.ignoreMemoryRecommendations are both true: "+
+			"the VWA will never change resources")
+	}
+	if wa.Spec.UpdateFrequency != nil && wa.Spec.UpdateFrequency.Duration < minUpdateFrequency {
+		warns = append(warns, fmt.Sprintf("spec.updateFrequency %s is below %s: every update restarts the workload pods",
+			wa.Spec.UpdateFrequency.Duration, minUpdateFrequency))
+	}
+	now := timeNow()
+	for i, window := range wa.Spec.BlackoutWindows {
+		if window.Start.Before(&window.End) && !now.Before(window.End.Time) {
+			warns = append(warns, fmt.Sprintf("spec.blackoutWindows[%d] '%s' ended at %s and has no effect",
+				i, window.Name, window.End.Format(time.RFC3339)))
+		}
+	}
+	for i, calendar := range wa.Spec.BlackoutCalendars {
+		if calendar.Namespace != "" && calendar.Namespace != wa.Namespace {
+			warns = append(warns, fmt.Sprintf("spec.blackoutCalendars[%d].namespace is ignored: "+
+				"VWA calendars are always read from the VWA namespace", i))
+		}
+	}
+	return warns
+}
@@ -0,0 +1,414 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestValidateVerticalWorkloadAutoscaler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+	timeNow = func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	existing := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"},
+		Spec:       vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "taken"}},
+	}
+	window := func(start, end, tz string) vwav1.UpdateWindow {
+		return vwav1.UpdateWindow{DayOfWeek: "Monday", StartTime: start, EndTime: end, TimeZone: tz}
+	}
+	blackout := func(name string, start, end time.Time) vwav1.BlackoutWindow {
+		return vwav1.BlackoutWindow{Name: name, Start: metav1.NewTime(start), End: metav1.NewTime(end)}
+	}
+
+	tests := []struct {
+		name             string
+		spec             vwav1.VerticalWorkloadAutoscalerSpec
+		namespace        string
+		expectedErrors   []string
+		expectedWarnings admission.Warnings
+	}{
+		{
+			name: "Valid VWA",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference: vwav1.VPAReference{Name: "vpa1"},
+				AllowedUpdateWindows: []vwav1.UpdateWindow{
+					window("01:00", "05:00", "UTC"),
+					window("22:00", "23:59", "America/Argentina/Buenos_Aires"),
+				},
+				BlackoutWindows: []vwav1.BlackoutWindow{
+					blackout("Black Friday", time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, 12, 3, 0, 0, 0, 0, time.UTC)),
+				},
+			},
+		},
+		{
+			name: "Unknown time zone",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:         vwav1.VPAReference{Name: "vpa1"},
+				AllowedUpdateWindows: []vwav1.UpdateWindow{window("01:00", "05:00", "Mars/Olympus_Mons")},
+			},
+			expectedErrors: []string{"spec.allowedUpdateWindows[0].timeZone"},
+		},
+		{
+			name: "Local time zone",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:         vwav1.VPAReference{Name: "vpa1"},
+				AllowedUpdateWindows: []vwav1.UpdateWindow{window("01:00", "05:00", "Local")},
+			},
+			expectedErrors: []string{"spec.allowedUpdateWindows[0].timeZone"},
+		},
+		{
+			name: "Start time after end time",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:         vwav1.VPAReference{Name: "vpa1"},
+				AllowedUpdateWindows: []vwav1.UpdateWindow{window("22:00", "02:00", "UTC")},
+			},
+			expectedErrors: []string{"spec.allowedUpdateWindows[0].endTime"},
+		},
+		{
+			name: "Empty update window",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:         vwav1.VPAReference{Name: "vpa1"},
+				AllowedUpdateWindows: []vwav1.UpdateWindow{window("02:00", "02:00", "UTC")},
+			},
+			expectedErrors: []string{"spec.allowedUpdateWindows[0].endTime"},
+		},
+		{
+			name: "Blackout window ends before it starts",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference: vwav1.VPAReference{Name: "vpa1"},
+				BlackoutWindows: []vwav1.BlackoutWindow{
+					blackout("Backwards", time.Date(2024, 12, 3, 0, 0, 0, 0, time.UTC), time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)),
+				},
+			},
+			expectedErrors: []string{"spec.blackoutWindows[0].end"},
+		},
+		{
+			name:           "Non-positive update frequency",
+			spec:           vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "vpa1"}, UpdateFrequency: &metav1.Duration{}},
+			expectedErrors: []string{"spec.updateFrequency"},
+		},
+		{
+			name: "Non-positive drift period",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference: vwav1.VPAReference{Name: "vpa1"},
+				DriftPolicy:  &vwav1.DriftPolicy{Period: &metav1.Duration{}},
+			},
+			expectedErrors: []string{"spec.driftPolicy.period"},
+		},
+		{
+			name: "Invalid Git write-back templates",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference: vwav1.VPAReference{Name: "vpa1"},
+				GitWriteback: &vwav1.GitWriteback{Repository: "https://github.com/org/deploy.git", Path: "apps/{{ .Name }", ValuesKey: "{{ .Container"},
+			},
+			expectedErrors: []string{"spec.gitWriteback.path", "spec.gitWriteback.valuesKey"},
+		},
+		{
+			name: "Local Git write-back repository",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference: vwav1.VPAReference{Name: "vpa1"},
+				GitWriteback: &vwav1.GitWriteback{Repository: "/srv/git/deploy.git", Path: "web.yaml"},
+			},
+			expectedErrors: []string{"spec.gitWriteback.repository"},
+		},
+		{
+			name: "Admission apply method with Git write-back",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference: vwav1.VPAReference{Name: "vpa1"},
+				ApplyMethod:  vwav1.ApplyMethodAdmission,
+				GitWriteback: &vwav1.GitWriteback{Repository: "https://github.com/org/deploy.git", Path: "web.yaml"},
+			},
+			expectedErrors: []string{"spec.applyMethod"},
+		},
+		{
+			name:           "VPA already referenced in the namespace",
+			spec:           vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "taken"}},
+			expectedErrors: []string{"spec.vpaReference.name"},
+		},
+		{
+			name:      "VPA with the same name in another namespace",
+			spec:      vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "taken"}},
+			namespace: "other",
+		},
+		{
+			name: "Multiple errors",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:         vwav1.VPAReference{Name: "taken"},
+				AllowedUpdateWindows: []vwav1.UpdateWindow{window("05:00", "01:00", "Nowhere")},
+			},
+			expectedErrors: []string{
+				"spec.allowedUpdateWindows[0].timeZone",
+				"spec.allowedUpdateWindows[0].endTime",
+				"spec.vpaReference.name",
+			},
+		},
+		{
+			name: "Guaranteed QoS without CPU limits",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:     vwav1.VPAReference{Name: "vpa1"},
+				QualityOfService: vwav1.GuaranteedQualityOfService,
+				AvoidCPULimit:    true,
+			},
+			expectedWarnings: admission.Warnings{
+				"spec.qualityOfService is Guaranteed but spec.avoidCPULimit is true: without CPU limits the pods get the Burstable QoS class",
+			},
+		},
+		{
+			name: "Risky settings",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:                vwav1.VPAReference{Name: "vpa1"},
+				IgnoreCPURecommendations:    true,
+				IgnoreMemoryRecommendations: true,
+				UpdateFrequency:             &metav1.Duration{Duration: 10 * time.Second},
+				BlackoutWindows: []vwav1.BlackoutWindow{
+					blackout("Last year", time.Date(2023, 11, 24, 0, 0, 0, 0, time.UTC), time.Date(2023, 11, 28, 0, 0, 0, 0, time.UTC)),
+				},
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "holidays", Namespace: "calendars"}},
+			},
+			expectedWarnings: admission.Warnings{
+				"spec.ignoreCPURecommendations and spec.ignoreMemoryRecommendations are both true: the VWA will never change resources",
+				"spec.updateFrequency 10s is below 1m0s: every update restarts the workload pods",
+				"spec.blackoutWindows[0] 'Last year' ended at 2023-11-28T00:00:00Z and has no effect",
+				"spec.blackoutCalendars[0].namespace is ignored: VWA calendars are always read from the VWA namespace",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing.DeepCopy()).Build()
+			v := &VerticalWorkloadAutoscalerCustomValidator{Client: c}
+			namespace := tt.namespace
+			if namespace == "" {
+				namespace = "default"
+			}
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: namespace},
+				Spec:       tt.spec,
+			}
+
+			warns, err := v.ValidateCreate(context.Background(), wa)
+			if len(tt.expectedErrors) > 0 {
+				assert.Error(t, err)
+				for _, path := range tt.expectedErrors {
+					assert.Contains(t, err.Error(), path)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedWarnings, warns)
+		})
+	}
+}
+
+func TestValidateUpdateKeepsOwnVPAReference(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+
+	wa := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec:       vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "vpa1"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(wa.DeepCopy()).Build()
+	v := &VerticalWorkloadAutoscalerCustomValidator{Client: c}
+
+	updated := wa.DeepCopy()
+	updated.Spec.IgnoreCPURecommendations = true
+	_, err := v.ValidateUpdate(context.Background(), wa, updated)
+	assert.NoError(t, err)
+}
+
+func TestValidateUpdateChangedFields(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = vwav1.AddToScheme(scheme)
+
+	// a VWA valid before the update window and shared VPA rules
+	midnight := vwav1.UpdateWindow{DayOfWeek: "Friday", StartTime: "22:00", EndTime: "02:00", TimeZone: "UTC"}
+	old := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+		Spec: vwav1.VerticalWorkloadAutoscalerSpec{
+			VPAReference:         vwav1.VPAReference{Name: "shared"},
+			AllowedUpdateWindows: []vwav1.UpdateWindow{midnight},
+		},
+	}
+	other := &vwav1.VerticalWorkloadAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+		Spec:       vwav1.VerticalWorkloadAutoscalerSpec{VPAReference: vwav1.VPAReference{Name: "shared"}},
+	}
+
+	tests := []struct {
+		name           string
+		update         func(wa *vwav1.VerticalWorkloadAutoscaler)
+		expectedErrors []string
+	}{
+		{
+			name:   "Unchanged invalid fields",
+			update: func(wa *vwav1.VerticalWorkloadAutoscaler) { wa.Spec.IgnoreCPURecommendations = true },
+		},
+		{
+			name:   "Finalizer removed",
+			update: func(wa *vwav1.VerticalWorkloadAutoscaler) { wa.Finalizers = nil },
+		},
+		{
+			name: "Window added next to an unchanged invalid window",
+			update: func(wa *vwav1.VerticalWorkloadAutoscaler) {
+				wa.Spec.AllowedUpdateWindows = append(wa.Spec.AllowedUpdateWindows,
+					vwav1.UpdateWindow{DayOfWeek: "Monday", StartTime: "01:00", EndTime: "05:00", TimeZone: "UTC"})
+			},
+		},
+		{
+			name: "Invalid window changed",
+			update: func(wa *vwav1.VerticalWorkloadAutoscaler) {
+				wa.Spec.AllowedUpdateWindows[0].EndTime = "03:00"
+			},
+			expectedErrors: []string{"spec.allowedUpdateWindows[0].endTime"},
+		},
+		{
+			name: "Shared VPA referenced again",
+			update: func(wa *vwav1.VerticalWorkloadAutoscaler) {
+				wa.Spec.VPAReference.Name = "vpa1"
+				wa.Spec.AllowedUpdateWindows = nil
+			},
+		},
+		{
+			name: "Deleted VWA with changed invalid fields",
+			update: func(wa *vwav1.VerticalWorkloadAutoscaler) {
+				wa.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				wa.Spec.AllowedUpdateWindows[0].EndTime = "03:00"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(old.DeepCopy(), other.DeepCopy()).Build()
+			v := &VerticalWorkloadAutoscalerCustomValidator{Client: c}
+			updated := old.DeepCopy()
+			updated.Finalizers = []string{"autoscaling.workload.io/finalizer"}
+			tt.update(updated)
+
+			_, err := v.ValidateUpdate(context.Background(), old, updated)
+			if len(tt.expectedErrors) > 0 {
+				assert.Error(t, err)
+				for _, path := range tt.expectedErrors {
+					assert.Contains(t, err.Error(), path)
+				}
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// setting the VPA reference of another VWA is still rejected
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(other.DeepCopy()).Build()
+	v := &VerticalWorkloadAutoscalerCustomValidator{Client: c}
+	unshared := old.DeepCopy()
+	unshared.Spec.VPAReference.Name = "vpa1"
+	updated := unshared.DeepCopy()
+	updated.Spec.VPAReference.Name = "shared"
+	_, err := v.ValidateUpdate(context.Background(), unshared, updated)
+	assert.ErrorContains(t, err, "spec.vpaReference.name")
+}
+
+func TestDefaultVerticalWorkloadAutoscaler(t *testing.T) {
+	defaults := vwav1.SpecDefaults{
+		UpdateFrequency:  10 * time.Minute,
+		QualityOfService: vwav1.BurstableQualityOfService,
+		CPUTolerance:     5,
+		MemoryTolerance:  15,
+	}
+
+	tests := []struct {
+		name     string
+		spec     vwav1.VerticalWorkloadAutoscalerSpec
+		expected vwav1.VerticalWorkloadAutoscalerSpec
+	}{
+		{
+			name: "All defaults",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:         vwav1.VPAReference{Name: "vpa1"},
+				AllowedUpdateWindows: []vwav1.UpdateWindow{{DayOfWeek: "Monday", StartTime: "01:00", EndTime: "05:00", TimeZone: "UTC"}},
+				BlackoutCalendars:    []vwav1.CalendarReference{{Name: "holidays"}},
+				UpdateSchedules:      []vwav1.UpdateScheduleReference{{Name: "nightly"}},
+				GitWriteback:         &vwav1.GitWriteback{Repository: "https://github.com/org/deploy.git", Path: "web.yaml"},
+				HPAPolicy:            &vwav1.HPAPolicy{SaturationScaleUp: &vwav1.SaturationScaleUp{}},
+			},
+			expected: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:         vwav1.VPAReference{Name: "vpa1"},
+				UpdateFrequency:      &metav1.Duration{Duration: 10 * time.Minute},
+				QualityOfService:     vwav1.BurstableQualityOfService,
+				UpdateTolerance:      &vwav1.UpdateTolerance{CPU: ptr.To(5), Memory: ptr.To(15)},
+				DriftPolicy:          &vwav1.DriftPolicy{MaxReverts: ptr.To(int32(3)), Period: &metav1.Duration{Duration: time.Hour}},
+				DeletionPolicy:       vwav1.DeletionPolicyRetain,
+				ApplyMethod:          vwav1.ApplyMethodTemplate,
+				UpdateMode:           vwav1.UpdateModeAuto,
+				Approval:             vwav1.ApprovalAutomatic,
+				RevisionHistoryLimit: ptr.To(int32(vwav1.DefaultRevisionHistoryLimit)),
+				AllowedUpdateWindows: []vwav1.UpdateWindow{
+					{DayOfWeek: "Monday", StartTime: "01:00", EndTime: "05:00", TimeZone: "UTC", Direction: vwav1.UpdateDirectionBoth},
+				},
+				BlackoutCalendars: []vwav1.CalendarReference{{Name: "holidays", Key: vwav1.DefaultCalendarKey}},
+				UpdateSchedules:   []vwav1.UpdateScheduleReference{{Kind: vwav1.UpdateScheduleKind, Name: "nightly"}},
+				GitWriteback: &vwav1.GitWriteback{
+					Repository: "https://github.com/org/deploy.git",
+					Branch:     vwav1.DefaultGitWritebackBranch,
+					Path:       "web.yaml",
+					Format:     vwav1.GitWritebackFormatKustomizePatch,
+				},
+				HPAPolicy: &vwav1.HPAPolicy{
+					CustomMetrics:     vwav1.CustomMetricsAllow,
+					SaturationScaleUp: &vwav1.SaturationScaleUp{After: &metav1.Duration{Duration: vwav1.DefaultSaturationScaleUpAfter}},
+				},
+			},
+		},
+		{
+			name: "Explicit values are kept",
+			spec: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:         vwav1.VPAReference{Name: "vpa1"},
+				UpdateFrequency:      &metav1.Duration{Duration: time.Hour},
+				QualityOfService:     vwav1.GuaranteedQualityOfService,
+				UpdateTolerance:      &vwav1.UpdateTolerance{CPU: ptr.To(0)},
+				DriftPolicy:          &vwav1.DriftPolicy{Period: &metav1.Duration{Duration: 10 * time.Minute}},
+				DeletionPolicy:       vwav1.DeletionPolicyRestore,
+				ApplyMethod:          vwav1.ApplyMethodTemplate,
+				UpdateMode:           vwav1.UpdateModeAuto,
+				Approval:             vwav1.ApprovalAutomatic,
+				RevisionHistoryLimit: ptr.To(int32(3)),
+			},
+			expected: vwav1.VerticalWorkloadAutoscalerSpec{
+				VPAReference:         vwav1.VPAReference{Name: "vpa1"},
+				UpdateFrequency:      &metav1.Duration{Duration: time.Hour},
+				QualityOfService:     vwav1.GuaranteedQualityOfService,
+				UpdateTolerance:      &vwav1.UpdateTolerance{CPU: ptr.To(0), Memory: ptr.To(15)},
+				DriftPolicy:          &vwav1.DriftPolicy{MaxReverts: ptr.To(int32(3)), Period: &metav1.Duration{Duration: 10 * time.Minute}},
+				DeletionPolicy:       vwav1.DeletionPolicyRestore,
+				ApplyMethod:          vwav1.ApplyMethodTemplate,
+				UpdateMode:           vwav1.UpdateModeAuto,
+				Approval:             vwav1.ApprovalAutomatic,
+				RevisionHistoryLimit: ptr.To(int32(3)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &VerticalWorkloadAutoscalerCustomDefaulter{Defaults: defaults}
+			wa := &vwav1.VerticalWorkloadAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "vwa1", Namespace: "default"},
+				Spec:       tt.spec,
+			}
+			assert.NoError(t, d.Default(context.Background(), wa))
+			assert.Equal(t, tt.expected, wa.Spec)
+		})
+	}
+}
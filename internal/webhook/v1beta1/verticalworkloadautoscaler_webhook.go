@@ -0,0 +1,29 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	vwav1beta1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// SetupVerticalWorkloadAutoscalerWebhookWithManager registers the conversion webhook for VerticalWorkloadAutoscaler in the manager.
+// v1beta1 is the conversion hub; v1alpha1 objects are converted through it.
+func SetupVerticalWorkloadAutoscalerWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&vwav1beta1.VerticalWorkloadAutoscaler{}).
+		Complete()
+}
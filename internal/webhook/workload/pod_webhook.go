@@ -0,0 +1,175 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// MutatePodPath is the path the pod mutating webhook is served on
+const MutatePodPath = "/mutate-pod-resources"
+
+// SetupPodWebhookWithManager registers the pod mutating webhook in the manager
+func SetupPodWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(MutatePodPath, &webhook.Admission{Handler: &PodResourcesMutator{
+		Decoder:   admission.NewDecoder(mgr.GetScheme()),
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+	}})
+	return nil
+}
+
+// +kubebuilder:webhook:path=/mutate-pod-resources,mutating=true,failurePolicy=ignore,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod-resources.kb.io,admissionReviewVersions=v1
+
+// PodResourcesMutator sets the container resources of new pods of workloads managed by a VWA with the Admission
+// apply method to the resources applied by the VWA, leaving the workload itself unchanged. The pods are annotated
+// with the applied resources and the VWA name.
+type PodResourcesMutator struct {
+	Decoder admission.Decoder
+	// Client reads the VWAs from the cache
+	Client client.Reader
+	// APIReader reads the pod owners from the API server; they are often too new for the cache
+	APIReader client.Reader
+}
+
+var _ admission.Handler = &PodResourcesMutator{}
+
+// Handle implements admission.Handler; the pod is admitted unchanged when it can't be handled, since the webhook
+// must never block pod creation
+func (m *PodResourcesMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	pod := &corev1.Pod{}
+	if err := m.Decoder.Decode(req, pod); err != nil {
+		return allowOnError(err)
+	}
+	if metav1.GetControllerOf(pod) == nil {
+		return admission.Allowed("")
+	}
+
+	// look for VWAs with the Admission apply method before resolving the pod owner with the API server
+	vwas, err := m.admissionVWAs(ctx, req.Namespace)
+	if err != nil {
+		return allowOnError(err)
+	}
+	if len(vwas) == 0 {
+		return admission.Allowed("")
+	}
+	kind, name, err := m.podWorkload(ctx, req.Namespace, pod)
+	if err != nil {
+		return allowOnError(err)
+	}
+	vwa := matchVWA(vwas, kind, name)
+	if vwa == nil {
+		return admission.Allowed("")
+	}
+
+	applied := make(map[string]corev1.ResourceRequirements)
+	for i, container := range pod.Spec.Containers {
+		if resources, ok := vwa.Status.AppliedResources[container.Name]; ok {
+			pod.Spec.Containers[i].Resources = resources
+			applied[container.Name] = resources
+		}
+	}
+	if len(applied) == 0 {
+		return admission.Allowed("")
+	}
+	data, err := json.Marshal(applied)
+	if err != nil {
+		return allowOnError(err)
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[vwav1.UpdatedByAnnotation] = vwa.Name
+	pod.Annotations[vwav1.AppliedResourcesAnnotation] = string(data)
+
+	marshaled, err := json.Marshal(pod)
+	if err != nil {
+		return allowOnError(err)
+	}
+	workloadlog.V(1).Info("setting pod resources", "namespace", req.Namespace, "kind", kind, "workload", name, "vwa", vwa.Name)
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// allowOnError admits the pod unchanged with a warning; an errored response would deny the pod even with the
+// Ignore failure policy
+func allowOnError(err error) admission.Response {
+	workloadlog.Error(err, "failed to set pod resources, admitting the pod unchanged")
+	return admission.Allowed("").WithWarnings(fmt.Sprintf("pod resources not set by the VerticalWorkloadAutoscaler: %v", err))
+}
+
+// podWorkload returns the kind and name of the workload controlling the pod, resolving the Deployment of
+// a ReplicaSet and the CronJob of a Job; the name is empty for pods without a controller
+func (m *PodResourcesMutator) podWorkload(ctx context.Context, namespace string, pod *corev1.Pod) (string, string, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "", "", nil
+	}
+
+	var parent client.Object
+	switch owner.Kind {
+	case "ReplicaSet":
+		parent = &appsv1.ReplicaSet{}
+	case "Job":
+		parent = &batchv1.Job{}
+	default:
+		return owner.Kind, owner.Name, nil
+	}
+	if err := m.APIReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: owner.Name}, parent); err != nil {
+		return "", "", fmt.Errorf("failed to get %s '%s': %w", owner.Kind, owner.Name, err)
+	}
+	if grandparent := metav1.GetControllerOf(parent); grandparent != nil {
+		return grandparent.Kind, grandparent.Name, nil
+	}
+	return owner.Kind, owner.Name, nil
+}
+
+// admissionVWAs returns the VWAs with the Admission apply method in the namespace
+func (m *PodResourcesMutator) admissionVWAs(ctx context.Context, namespace string) ([]vwav1.VerticalWorkloadAutoscaler, error) {
+	var vwaList vwav1.VerticalWorkloadAutoscalerList
+	if err := m.Client.List(ctx, &vwaList, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list VerticalWorkloadAutoscaler objects: %w", err)
+	}
+	var vwas []vwav1.VerticalWorkloadAutoscaler
+	for _, vwa := range vwaList.Items {
+		if vwa.Spec.ApplyMethod == vwav1.ApplyMethodAdmission && vwa.DeletionTimestamp == nil {
+			vwas = append(vwas, vwa)
+		}
+	}
+	return vwas, nil
+}
+
+// matchVWA returns the VWA targeting the workload, if any
+func matchVWA(vwas []vwav1.VerticalWorkloadAutoscaler, kind, name string) *vwav1.VerticalWorkloadAutoscaler {
+	for i := range vwas {
+		if vwas[i].Status.ScaleTargetRef.Kind == kind && vwas[i].Status.ScaleTargetRef.Name == name {
+			return &vwas[i]
+		}
+	}
+	return nil
+}
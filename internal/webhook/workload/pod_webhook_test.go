@@ -0,0 +1,162 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestPodResourcesMutatorHandle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = vwav1.AddToScheme(scheme)
+
+	cpu := func(value string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(value)}}
+	}
+	controlledBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: ptr.To(true)}}
+	}
+	vwa := func(name string, method vwav1.ApplyMethod, kind, target string) *vwav1.VerticalWorkloadAutoscaler {
+		return &vwav1.VerticalWorkloadAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       vwav1.VerticalWorkloadAutoscalerSpec{ApplyMethod: method},
+			Status: vwav1.VerticalWorkloadAutoscalerStatus{
+				ScaleTargetRef:   autoscalingv2.CrossVersionObjectReference{Kind: kind, Name: target},
+				AppliedResources: map[string]corev1.ResourceRequirements{"app": cpu("500m")},
+			},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		vwa("web-vwa", vwav1.ApplyMethodAdmission, "Deployment", "web"),
+		vwa("report-vwa", vwav1.ApplyMethodAdmission, "CronJob", "report"),
+		vwa("api-vwa", vwav1.ApplyMethodTemplate, "Deployment", "api"),
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f", Namespace: "default", OwnerReferences: controlledBy("Deployment", "web")}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "api-7c9b", Namespace: "default", OwnerReferences: controlledBy("Deployment", "api")}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "report-28790", Namespace: "default", OwnerReferences: controlledBy("CronJob", "report")}},
+	).Build()
+	mutator := &PodResourcesMutator{Decoder: admission.NewDecoder(scheme), Client: c, APIReader: c}
+
+	tests := []struct {
+		name            string
+		namespace       string
+		owners          []metav1.OwnerReference
+		expectedAllowed bool
+		expectedPatched bool
+		expectedWarning bool
+	}{
+		{
+			name:            "Pod of a Deployment with the Admission apply method",
+			owners:          controlledBy("ReplicaSet", "web-5d8f"),
+			expectedAllowed: true,
+			expectedPatched: true,
+		},
+		{
+			name:            "Pod of a CronJob with the Admission apply method",
+			owners:          controlledBy("Job", "report-28790"),
+			expectedAllowed: true,
+			expectedPatched: true,
+		},
+		{
+			name:            "Pod of a Deployment with the Template apply method",
+			owners:          controlledBy("ReplicaSet", "api-7c9b"),
+			expectedAllowed: true,
+		},
+		{
+			name:            "Pod of an unmanaged StatefulSet",
+			owners:          controlledBy("StatefulSet", "db"),
+			expectedAllowed: true,
+		},
+		{
+			name:            "Pod without a controller",
+			expectedAllowed: true,
+		},
+		{
+			name:            "Missing ReplicaSet",
+			owners:          controlledBy("ReplicaSet", "gone"),
+			expectedAllowed: true,
+			expectedWarning: true,
+		},
+		{
+			name:            "Namespace without Admission VWAs",
+			namespace:       "other",
+			owners:          controlledBy("ReplicaSet", "gone"),
+			expectedAllowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace := tt.namespace
+			if namespace == "" {
+				namespace = "default"
+			}
+			pod := &corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+				ObjectMeta: metav1.ObjectMeta{GenerateName: "web-", Namespace: namespace, OwnerReferences: tt.owners},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "app", Image: "web:1.0", Resources: cpu("100m")},
+					{Name: "sidecar", Image: "proxy:1.0", Resources: cpu("50m")},
+				}},
+			}
+			raw, err := json.Marshal(pod)
+			require.NoError(t, err)
+
+			resp := mutator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Namespace: namespace,
+				Object:    runtime.RawExtension{Raw: raw},
+			}})
+			assert.Equal(t, tt.expectedAllowed, resp.Allowed)
+			assert.Equal(t, tt.expectedWarning, len(resp.Warnings) > 0)
+			if !tt.expectedPatched {
+				assert.Empty(t, resp.Patches)
+				return
+			}
+
+			patches := make(map[string]interface{})
+			for _, patch := range resp.Patches {
+				patches[patch.Path] = patch.Value
+			}
+			assert.Equal(t, "500m", patches["/spec/containers/0/resources/requests/cpu"])
+			assert.NotContains(t, patches, "/spec/containers/1/resources/requests/cpu")
+			annotations, ok := patches["/metadata/annotations"].(map[string]interface{})
+			require.True(t, ok)
+			assert.Equal(t, `{"app":{"requests":{"cpu":"500m"}}}`, annotations[vwav1.AppliedResourcesAnnotation])
+			assert.NotEmpty(t, annotations[vwav1.UpdatedByAnnotation])
+		})
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ValidatePath is the path the workload validating webhook is served on
+const ValidatePath = "/validate-workload-resources"
+
+// log is for logging in this package.
+var workloadlog = logf.Log.WithName("workload-resource")
+
+// SetupWorkloadWebhookWithManager registers the workload validating webhook in the manager.
+// controllerUsername is the user the VWA controller authenticates as; its changes are always allowed.
+func SetupWorkloadWebhookWithManager(mgr ctrl.Manager, controllerUsername string, strict bool) error {
+	if controllerUsername == "" {
+		return fmt.Errorf("the controller username is required")
+	}
+	mgr.GetWebhookServer().Register(ValidatePath, &webhook.Admission{Handler: &ResourcesValidator{
+		Decoder:            admission.NewDecoder(mgr.GetScheme()),
+		ControllerUsername: controllerUsername,
+		Strict:             strict,
+	}})
+	return nil
+}
+
+// ServiceAccountUsername returns the user a service account authenticates as
+func ServiceAccountUsername(namespace, name string) string {
+	return fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name)
+}
+
+// +kubebuilder:webhook:path=/validate-workload-resources,mutating=false,failurePolicy=ignore,sideEffects=None,groups=apps;batch,resources=deployments;statefulsets;daemonsets;replicasets;jobs;cronjobs,verbs=update,versions=v1,name=vworkload-resources.kb.io,admissionReviewVersions=v1
+
+// ResourcesValidator protects the container resources managed by a VWA from manual changes.
+// Workloads updated by a VWA carry the updatedBy annotation; when any user other than the VWA controller
+// changes their container resources, the change is allowed with a warning naming the VWA.
+// In strict mode the change is denied unless the workload has the allowManualResources annotation.
+type ResourcesValidator struct {
+	Decoder            admission.Decoder
+	ControllerUsername string
+	Strict             bool
+}
+
+var _ admission.Handler = &ResourcesValidator{}
+
+// Handle implements admission.Handler
+func (v *ResourcesValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	oldObj, newObj, err := v.decode(req)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if oldObj == nil {
+		return admission.Allowed("")
+	}
+
+	vwaName := oldObj.GetAnnotations()[vwav1.UpdatedByAnnotation]
+	if vwaName == "" || req.UserInfo.Username == v.ControllerUsername {
+		return admission.Allowed("")
+	}
+
+	changed := changedContainers(podSpec(oldObj), podSpec(newObj))
+	if len(changed) == 0 {
+		return admission.Allowed("")
+	}
+
+	workloadlog.Info("manual change of VWA managed resources", "kind", req.Kind.Kind, "namespace", req.Namespace,
+		"name", req.Name, "user", req.UserInfo.Username, "vwa", vwaName, "containers", changed)
+	if v.Strict && newObj.GetAnnotations()[vwav1.AllowManualResourcesAnnotation] != "true" {
+		return admission.Denied(fmt.Sprintf("resources of containers %v are managed by VerticalWorkloadAutoscaler '%s'; "+
+			"set the %s=true annotation to change them manually", changed, vwaName, vwav1.AllowManualResourcesAnnotation))
+	}
+	return admission.Allowed("").WithWarnings(fmt.Sprintf("resources of containers %v are managed by VerticalWorkloadAutoscaler '%s' "+
+		"and will be overwritten on its next update", changed, vwaName))
+}
+
+// decode returns the old and the new workload of an update request; the old workload is nil for other operations
+func (v *ResourcesValidator) decode(req admission.Request) (client.Object, client.Object, error) {
+	if len(req.OldObject.Raw) == 0 {
+		return nil, nil, nil
+	}
+	oldObj, err := v.decodeRaw(req.Kind.Kind, req.OldObject)
+	if err != nil {
+		return nil, nil, err
+	}
+	newObj, err := v.decodeRaw(req.Kind.Kind, req.Object)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldObj, newObj, nil
+}
+
+// decodeRaw decodes the raw workload of the kind
+func (v *ResourcesValidator) decodeRaw(kind string, raw runtime.RawExtension) (client.Object, error) {
+	obj, err := newWorkload(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.Decoder.DecodeRaw(raw, obj); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", kind, err)
+	}
+	return obj, nil
+}
+
+// newWorkload returns an empty workload object of the kind
+func newWorkload(kind string) (client.Object, error) {
+	switch kind {
+	case "Deployment":
+		return &appsv1.Deployment{}, nil
+	case "StatefulSet":
+		return &appsv1.StatefulSet{}, nil
+	case "DaemonSet":
+		return &appsv1.DaemonSet{}, nil
+	case "ReplicaSet":
+		return &appsv1.ReplicaSet{}, nil
+	case "Job":
+		return &batchv1.Job{}, nil
+	case "CronJob":
+		return &batchv1.CronJob{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+}
+
+// podSpec returns the pod template spec of the workload
+func podSpec(obj runtime.Object) *corev1.PodSpec {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		return &workload.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		return &workload.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		return &workload.Spec.Template.Spec
+	case *appsv1.ReplicaSet:
+		return &workload.Spec.Template.Spec
+	case *batchv1.Job:
+		return &workload.Spec.Template.Spec
+	case *batchv1.CronJob:
+		return &workload.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return &corev1.PodSpec{}
+	}
+}
+
+// changedContainers returns the names of the containers present in both pod specs whose resources differ
+func changedContainers(oldSpec, newSpec *corev1.PodSpec) []string {
+	oldResources := make(map[string]corev1.ResourceRequirements, len(oldSpec.Containers))
+	for _, container := range oldSpec.Containers {
+		oldResources[container.Name] = container.Resources
+	}
+
+	var changed []string
+	for _, container := range newSpec.Containers {
+		if resources, ok := oldResources[container.Name]; ok && !equality.Semantic.DeepEqual(resources, container.Resources) {
+			changed = append(changed, container.Name)
+		}
+	}
+	return changed
+}
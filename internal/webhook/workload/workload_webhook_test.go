@@ -0,0 +1,226 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	vwav1 "github.com/alexei-led/vertical-workload-autoscaler/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const controllerUsername = "system:serviceaccount:vwa:vwa-controller-manager"
+
+func TestResourcesValidatorHandle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	deployment := func(cpu string, annotations map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: annotations},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name: "app",
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	managed := map[string]string{vwav1.UpdatedByAnnotation: "web-vwa"}
+	overridden := map[string]string{vwav1.UpdatedByAnnotation: "web-vwa", vwav1.AllowManualResourcesAnnotation: "true"}
+
+	tests := []struct {
+		name            string
+		strict          bool
+		username        string
+		oldObj          *appsv1.Deployment
+		newObj          *appsv1.Deployment
+		expectedAllowed bool
+		expectedWarning bool
+	}{
+		{
+			name:            "Unmanaged workload",
+			username:        "alice",
+			oldObj:          deployment("100m", nil),
+			newObj:          deployment("200m", nil),
+			expectedAllowed: true,
+		},
+		{
+			name:            "Controller change",
+			strict:          true,
+			username:        controllerUsername,
+			oldObj:          deployment("100m", managed),
+			newObj:          deployment("200m", managed),
+			expectedAllowed: true,
+		},
+		{
+			name:            "Resources not changed",
+			strict:          true,
+			username:        "alice",
+			oldObj:          deployment("100m", managed),
+			newObj:          deployment("100m", map[string]string{vwav1.UpdatedByAnnotation: "web-vwa", "team": "web"}),
+			expectedAllowed: true,
+		},
+		{
+			name:            "Semantically equal resources",
+			strict:          true,
+			username:        "alice",
+			oldObj:          deployment("1", managed),
+			newObj:          deployment("1000m", managed),
+			expectedAllowed: true,
+		},
+		{
+			name:            "Manual change warns",
+			username:        "alice",
+			oldObj:          deployment("100m", managed),
+			newObj:          deployment("200m", managed),
+			expectedAllowed: true,
+			expectedWarning: true,
+		},
+		{
+			name:     "Manual change denied in strict mode",
+			strict:   true,
+			username: "alice",
+			oldObj:   deployment("100m", managed),
+			newObj:   deployment("200m", managed),
+		},
+		{
+			name:            "Manual change with override annotation in strict mode",
+			strict:          true,
+			username:        "alice",
+			oldObj:          deployment("100m", managed),
+			newObj:          deployment("200m", overridden),
+			expectedAllowed: true,
+			expectedWarning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &ResourcesValidator{Decoder: admission.NewDecoder(scheme), ControllerUsername: controllerUsername, Strict: tt.strict}
+
+			resp := v.Handle(context.Background(), updateRequest(t, "Deployment", tt.username, tt.oldObj, tt.newObj))
+
+			assert.Equal(t, tt.expectedAllowed, resp.Allowed)
+			if tt.expectedWarning {
+				require.Len(t, resp.Warnings, 1)
+				assert.Contains(t, resp.Warnings[0], "VerticalWorkloadAutoscaler 'web-vwa'")
+				assert.Contains(t, resp.Warnings[0], "[app]")
+			} else {
+				assert.Empty(t, resp.Warnings)
+			}
+			if !tt.expectedAllowed {
+				assert.Contains(t, resp.Result.Message, vwav1.AllowManualResourcesAnnotation)
+			}
+		})
+	}
+}
+
+func TestResourcesValidatorHandleCreate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	v := &ResourcesValidator{Decoder: admission.NewDecoder(scheme), ControllerUsername: controllerUsername, Strict: true}
+
+	req := updateRequest(t, "Deployment", "alice", nil, &appsv1.Deployment{})
+	req.Operation = admissionv1.Create
+
+	resp := v.Handle(context.Background(), req)
+	assert.True(t, resp.Allowed)
+}
+
+func TestPodSpec(t *testing.T) {
+	containers := []corev1.Container{{Name: "app"}}
+	template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: containers}}
+
+	tests := []struct {
+		name string
+		obj  runtime.Object
+	}{
+		{name: "Deployment", obj: &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: template}}},
+		{name: "StatefulSet", obj: &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Template: template}}},
+		{name: "DaemonSet", obj: &appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{Template: template}}},
+		{name: "ReplicaSet", obj: &appsv1.ReplicaSet{Spec: appsv1.ReplicaSetSpec{Template: template}}},
+		{name: "Job", obj: &batchv1.Job{Spec: batchv1.JobSpec{Template: template}}},
+		{name: "CronJob", obj: &batchv1.CronJob{Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: template}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, containers, podSpec(tt.obj).Containers)
+		})
+	}
+}
+
+func TestChangedContainers(t *testing.T) {
+	requests := func(cpu string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}}
+	}
+	oldSpec := &corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "app", Resources: requests("100m")},
+		{Name: "sidecar", Resources: requests("50m")},
+	}}
+	newSpec := &corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "app", Resources: requests("100m")},
+		{Name: "sidecar", Resources: requests("60m")},
+		{Name: "debug", Resources: requests("10m")},
+	}}
+
+	assert.Equal(t, []string{"sidecar"}, changedContainers(oldSpec, newSpec))
+}
+
+// updateRequest returns an admission request updating the old workload to the new one
+func updateRequest(t *testing.T, kind, username string, oldObj, newObj runtime.Object) admission.Request {
+	t.Helper()
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Update,
+		Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: kind},
+		Namespace: "default",
+		Name:      "web",
+		UserInfo:  authenticationv1.UserInfo{Username: username},
+	}}
+	raw, err := json.Marshal(newObj)
+	require.NoError(t, err)
+	req.Object = runtime.RawExtension{Raw: raw}
+	if oldObj != nil {
+		raw, err = json.Marshal(oldObj)
+		require.NoError(t, err)
+		req.OldObject = runtime.RawExtension{Raw: raw}
+	}
+	return req
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 Alexei Ledenev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chart
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// actionLine matches the lines holding only a template action, e.g. the included labels
+	actionLine = regexp.MustCompile(`(?m)^\s*\{\{-[^\n]*\}\}\s*\n`)
+	// action matches the remaining template actions, which may span lines
+	action = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+)
+
+// render approximates the rendering of a chart template: the full name resolves to "release"
+// and the other template actions to null
+func render(t *testing.T, name string, obj interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "chart", "templates", name))
+	require.NoError(t, err)
+	data = actionLine.ReplaceAll(data, nil)
+	data = regexp.MustCompile(`\{\{ include "chart\.fullname" \. \}\}`).ReplaceAll(data, []byte("release"))
+	data = action.ReplaceAll(data, []byte("null"))
+	require.NoError(t, yaml.Unmarshal(data, obj))
+}
+
+func TestChartControllerUsername(t *testing.T) {
+	deployment := &appsv1.Deployment{}
+	render(t, "deployment.yaml", deployment)
+	serviceAccount := &corev1.ServiceAccount{}
+	render(t, "serviceaccount.yaml", serviceAccount)
+
+	// the workload webhook derives the controller username from the pod namespace and service account
+	assert.Equal(t, serviceAccount.Name, deployment.Spec.Template.Spec.ServiceAccountName)
+	require.Len(t, deployment.Spec.Template.Spec.Containers, 1)
+	fieldPaths := make(map[string]string)
+	for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+		if env.ValueFrom != nil && env.ValueFrom.FieldRef != nil {
+			fieldPaths[env.Name] = env.ValueFrom.FieldRef.FieldPath
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"POD_NAMESPACE":        "metadata.namespace",
+		"SERVICE_ACCOUNT_NAME": "spec.serviceAccountName",
+	}, fieldPaths)
+}